@@ -16,7 +16,10 @@ limitations under the License.
 
 package v1alpha1
 
-import corev1 "k8s.io/api/core/v1"
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 const (
 	// DefaultAddressName is the default name for the Addresses object.
@@ -55,6 +58,35 @@ type LoadBalancerEndpoints struct {
 	// +optional
 	// +kubebuilder:validation:MinItems=1
 	Ports []EndpointPort `json:"ports,omitempty" protobuf:"bytes,3,rep,name=ports"`
+
+	// Weight is the share of the connections of each port that is sent to these endpoints, relative to the weights of the other sets of
+	// endpoints with the same priority. Weights are not supported for UDP ports, their traffic is sent to the set of endpoints with the
+	// highest weight. Only used by LoadBalancers. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	// +optional
+	Weight *uint32 `json:"weight,omitempty" protobuf:"varint,4,opt,name=weight"`
+
+	// Priority of these endpoints, 0 is the highest priority. Connections are sent to the endpoints with the highest priority and fail
+	// over to the endpoints with the next priority as the endpoints become unhealthy. Only used by LoadBalancers. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Priority uint32 `json:"priority,omitempty" protobuf:"varint,5,opt,name=priority"`
+
+	// OverprovisioningFactor controls when connections fail over from these endpoints to the endpoints with the next priority, as a
+	// percentage. These endpoints receive all the connections as long as the percentage of healthy endpoints multiplied by the factor is
+	// at least 100%, the remaining share is sent to the next priority. For example with 140, the failover starts once less than about
+	// 71% of the endpoints are healthy, and with 100, as soon as one endpoint is unhealthy. Only used by LoadBalancers. Defaults to 140.
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	OverprovisioningFactor *uint32 `json:"overprovisioningFactor,omitempty" protobuf:"varint,6,opt,name=overprovisioningFactor"`
+
+	// DNS configures the resolution of the addresses that only have a hostname. It applies to the cluster of these endpoints, including
+	// the endpoints with a lower priority that it fails over to. The endpoints of a Route share the clusters of its services, the
+	// settings of the first set of endpoints are used for them.
+	// +optional
+	DNS *DNSSettings `json:"dns,omitempty" protobuf:"bytes,7,opt,name=dns"`
 }
 
 // EndpointPort is a tuple that describes a single port.
@@ -74,16 +106,24 @@ type EndpointPort struct {
 	Protocol corev1.Protocol `json:"protocol,omitempty" protobuf:"bytes,3,opt,name=protocol,casttype=Protocol"`
 }
 
-// EndpointAddress is a tuple that describes single IP address.
+// EndpointAddress is a tuple that describes single IP address or hostname.
+// +kubebuilder:validation:XValidation:rule="has(self.ip) || has(self.hostname)",message="either ip or hostname is required"
 type EndpointAddress struct {
-	// The IP of this endpoint.
-	// May not be loopback (127.0.0.0/8), link-local (169.254.0.0/16),
-	// or link-local multicast ((224.0.0.0/24).
-	// +kubebuilder:validation:MinLength=7
-	IP string `json:"ip" protobuf:"bytes,1,opt,name=ip"`
-	// The Hostname of this endpoint
+	// The IP of this endpoint, both IPv4 and IPv6 addresses are supported. IPv6 addresses are only checked for their characters and the
+	// number of their groups, the CEL IP library would require Kubernetes 1.31.
+	// +kubebuilder:validation:Pattern=`^(((25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])|[0-9a-fA-F]{0,4}(:[0-9a-fA-F]{0,4}){2,7})$`
+	// +optional
+	IP string `json:"ip,omitempty" protobuf:"bytes,1,opt,name=ip"`
+	// The Hostname of this endpoint. If no IP is specified, the hostname is resolved by Envoy with a DNS cluster, for example for the
+	// load balancers of cloud providers that only expose a hostname. Supported by both LoadBalancers and Routes.
 	// +optional
 	Hostname string `json:"hostname,omitempty" protobuf:"bytes,3,opt,name=hostname"`
+	// Zone is the zone of the node that serves this endpoint, from the topology.kubernetes.io/zone label.
+	// +optional
+	Zone string `json:"zone,omitempty" protobuf:"bytes,4,opt,name=zone"`
+	// Region is the region of the node that serves this endpoint, from the topology.kubernetes.io/region label.
+	// +optional
+	Region string `json:"region,omitempty" protobuf:"bytes,5,opt,name=region"`
 }
 
 type AnnotationSettings struct {
@@ -97,3 +137,518 @@ type AnnotationSettings struct {
 	// +optional
 	PropagateAllAnnotations *bool `json:"propagateAllAnnotations,omitempty"`
 }
+
+// LoadBalancingPolicyType is the algorithm that is used to pick an endpoint for a new connection or UDP session.
+// +kubebuilder:validation:Enum=RoundRobin;LeastRequest;Random;RingHash;Maglev
+type LoadBalancingPolicyType string
+
+const (
+	LoadBalancingPolicyRoundRobin   LoadBalancingPolicyType = "RoundRobin"
+	LoadBalancingPolicyLeastRequest LoadBalancingPolicyType = "LeastRequest"
+	LoadBalancingPolicyRandom       LoadBalancingPolicyType = "Random"
+	LoadBalancingPolicyRingHash     LoadBalancingPolicyType = "RingHash"
+	LoadBalancingPolicyMaglev       LoadBalancingPolicyType = "Maglev"
+)
+
+// LoadBalancingPolicy defines how connections are distributed across the endpoints of a load balancer.
+type LoadBalancingPolicy struct {
+	// Type is the load balancing algorithm. Valid values are RoundRobin, LeastRequest, Random, RingHash and Maglev.
+	// RingHash and Maglev use the source IP of the client as the hash key, this provides a consistent endpoint for a client.
+	// +kubebuilder:default=RoundRobin
+	// +optional
+	Type LoadBalancingPolicyType `json:"type,omitempty"`
+
+	// LeastRequest contains the configuration for the LeastRequest policy.
+	// +optional
+	LeastRequest *LeastRequestConfig `json:"leastRequest,omitempty"`
+
+	// RingHash contains the configuration for the RingHash policy.
+	// +optional
+	RingHash *RingHashConfig `json:"ringHash,omitempty"`
+
+	// Maglev contains the configuration for the Maglev policy.
+	// +optional
+	Maglev *MaglevConfig `json:"maglev,omitempty"`
+}
+
+// TCPSettings configures the timeouts and the keepalive of the TCP connections that are proxied by Envoy.
+type TCPSettings struct {
+	// IdleTimeout is the time after which a connection without traffic in either direction is closed. A value of 0s disables the
+	// idle timeout. Defaults to 1h.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+
+	// MaxConnectionDuration is the maximum lifetime of a connection, regardless of its activity. If not specified, the lifetime is
+	// unlimited.
+	// +optional
+	MaxConnectionDuration *metav1.Duration `json:"maxConnectionDuration,omitempty"`
+
+	// ConnectTimeout is the timeout for establishing the connection to an endpoint. Defaults to 5s.
+	// +optional
+	ConnectTimeout *metav1.Duration `json:"connectTimeout,omitempty"`
+
+	// Keepalive enables TCP keepalive on the connections to the endpoints.
+	// +optional
+	Keepalive *TCPKeepalive `json:"keepalive,omitempty"`
+}
+
+// TCPKeepalive configures the TCP keepalive probes. Unspecified values are inherited from the operating system.
+type TCPKeepalive struct {
+	// Probes is the number of unacknowledged probes after which the connection is considered dead.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Probes *uint32 `json:"probes,omitempty"`
+
+	// Time is the idle time of a connection before the first probe is sent. It's rounded down to full seconds.
+	// +optional
+	Time *metav1.Duration `json:"time,omitempty"`
+
+	// Interval is the time between the probes. It's rounded down to full seconds.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// DNSResolution defines how the hostnames of the endpoints are resolved.
+// +kubebuilder:validation:Enum=Strict;Logical
+type DNSResolution string
+
+const (
+	// DNSResolutionStrict uses all the addresses that a hostname resolves to as endpoints, the endpoints are updated when the records
+	// change. This suits hostnames that resolve to a small, stable set of addresses.
+	DNSResolutionStrict DNSResolution = "Strict"
+	// DNSResolutionLogical only uses the first address that a hostname resolves to for new connections, existing connections are kept
+	// when the records change. This suits large services with frequently rotating records such as the load balancers of cloud providers.
+	// It requires a single endpoint, Strict is used otherwise.
+	DNSResolutionLogical DNSResolution = "Logical"
+)
+
+// DNSLookupFamily defines the IP families of the addresses that the hostnames are resolved to.
+// +kubebuilder:validation:Enum=Auto;V4Only;V6Only;V4Preferred;All
+type DNSLookupFamily string
+
+const (
+	// DNSLookupFamilyAuto prefers IPv6 addresses and falls back to IPv4 addresses.
+	DNSLookupFamilyAuto DNSLookupFamily = "Auto"
+	// DNSLookupFamilyV4Only only uses IPv4 addresses.
+	DNSLookupFamilyV4Only DNSLookupFamily = "V4Only"
+	// DNSLookupFamilyV6Only only uses IPv6 addresses.
+	DNSLookupFamilyV6Only DNSLookupFamily = "V6Only"
+	// DNSLookupFamilyV4Preferred prefers IPv4 addresses and falls back to IPv6 addresses.
+	DNSLookupFamilyV4Preferred DNSLookupFamily = "V4Preferred"
+	// DNSLookupFamilyAll uses both IPv4 and IPv6 addresses.
+	DNSLookupFamilyAll DNSLookupFamily = "All"
+)
+
+// DNSSettings configures the resolution of the hostnames of the endpoints.
+// +kubebuilder:validation:XValidation:rule="!has(self.refreshRate) || duration(self.refreshRate) > duration('1ms')",message="refreshRate must be greater than 1ms"
+type DNSSettings struct {
+	// Resolution defines how the hostnames are resolved. Defaults to Strict.
+	// +optional
+	Resolution DNSResolution `json:"resolution,omitempty"`
+
+	// RefreshRate is the interval at which the hostnames are resolved again. Defaults to 5s.
+	// +optional
+	RefreshRate *metav1.Duration `json:"refreshRate,omitempty"`
+
+	// RespectDNSTTL uses the TTL of the DNS records as the refresh rate instead of RefreshRate. Defaults to false.
+	// +optional
+	RespectDNSTTL *bool `json:"respectDNSTTL,omitempty"`
+
+	// LookupFamily defines the IP families of the addresses that the hostnames are resolved to. Defaults to Auto.
+	// +optional
+	LookupFamily DNSLookupFamily `json:"lookupFamily,omitempty"`
+}
+
+// ConnectionRateLimit limits the rate of new connections with a token bucket. Each connection consumes a token and connections are closed
+// immediately if the bucket is empty.
+// +kubebuilder:validation:XValidation:rule="duration(self.fillInterval) >= duration('50ms')",message="fillInterval must be at least 50ms"
+type ConnectionRateLimit struct {
+	// MaxTokens is the size of the token bucket, i.e. the maximum burst of connections.
+	// +kubebuilder:validation:Minimum=1
+	MaxTokens uint32 `json:"maxTokens"`
+
+	// TokensPerFill is the number of tokens that are added to the bucket at each fill interval. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TokensPerFill *uint32 `json:"tokensPerFill,omitempty"`
+
+	// FillInterval is the interval at which tokens are added to the bucket.
+	FillInterval metav1.Duration `json:"fillInterval"`
+}
+
+// UDPLoadBalancingMode defines how the datagrams of a UDP session are distributed across the endpoints.
+// +kubebuilder:validation:Enum=Session;PerPacket
+type UDPLoadBalancingMode string
+
+const (
+	// UDPLoadBalancingModeSession sends all the datagrams of a session to the endpoint that was selected for the first datagram.
+	UDPLoadBalancingModeSession UDPLoadBalancingMode = "Session"
+	// UDPLoadBalancingModePerPacket selects an endpoint for each datagram. This suits stateless protocols such as DNS.
+	UDPLoadBalancingModePerPacket UDPLoadBalancingMode = "PerPacket"
+)
+
+// UDPSettings configures the UDP proxy. A session is identified by the source and destination addresses and ports of the datagrams.
+type UDPSettings struct {
+	// IdleTimeout is the time after which a session without datagrams is closed. Defaults to 60s.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+
+	// LoadBalancingMode defines whether an endpoint is selected once per session or for each datagram. Defaults to Session.
+	// +kubebuilder:default=Session
+	// +optional
+	LoadBalancingMode UDPLoadBalancingMode `json:"loadBalancingMode,omitempty"`
+
+	// HashSourceIP routes the sessions of a client to the same endpoint by using the source IP as the hash key. The Maglev policy is
+	// used for the port unless a policy that uses a hash key is configured.
+	// +optional
+	HashSourceIP bool `json:"hashSourceIP,omitempty"`
+
+	// MaxBufferedDatagrams is the number of datagrams that the listener buffers while the proxy is busy, further datagrams are dropped.
+	// The receive buffer of the listener socket is sized for datagrams of up to 1500 bytes and is capped by net.core.rmem_max on the node
+	// of the proxy. Defaults to 1024.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65536
+	// +optional
+	MaxBufferedDatagrams *uint32 `json:"maxBufferedDatagrams,omitempty"`
+}
+
+// SessionAffinity pins the connections of a client to the same endpoint based on the IP address of the client.
+type SessionAffinity struct {
+	// Type is the session affinity of the Service in the tenant cluster. With ClientIP, the source IP of the client is used as the hash
+	// key and the Maglev policy is used unless the RingHash policy is configured.
+	// +kubebuilder:validation:Enum=None;ClientIP
+	Type corev1.ServiceAffinity `json:"type"`
+
+	// TimeoutSeconds is propagated from sessionAffinityConfig.clientIP.timeoutSeconds of the Service in the tenant cluster. It doesn't
+	// affect the proxy since the clients are pinned by the hash of their source IP, which doesn't expire. Idle UDP sessions are closed after
+	// the idle timeout of the UDP settings of the port.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=86400
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+type LeastRequestConfig struct {
+	// ChoiceCount is the number of random healthy endpoints from which the endpoint with the fewest active connections is chosen.
+	// Defaults to 2.
+	// +kubebuilder:validation:Minimum=2
+	// +optional
+	ChoiceCount *uint32 `json:"choiceCount,omitempty"`
+}
+
+type RingHashConfig struct {
+	// MinimumRingSize is the minimum size of the hash ring. Defaults to 1024.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=8388608
+	// +optional
+	MinimumRingSize *uint64 `json:"minimumRingSize,omitempty"`
+
+	// MaximumRingSize is the maximum size of the hash ring. Defaults to 8388608.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=8388608
+	// +optional
+	MaximumRingSize *uint64 `json:"maximumRingSize,omitempty"`
+}
+
+type MaglevConfig struct {
+	// TableSize is the size of the Maglev lookup table. Envoy requires a prime number, the allowed values are primes that are roughly
+	// powers of two up to the maximum of 5000011. Defaults to 65537.
+	// +kubebuilder:validation:Enum=251;509;1021;2039;4093;8191;16381;32749;65537;131071;262139;524287;1048573;2097143;4194301;5000011
+	// +optional
+	TableSize *uint64 `json:"tableSize,omitempty"`
+}
+
+// HealthCheck defines the active health checking for the endpoints of a load balancer. By default, a TCP connect health check is used.
+// +kubebuilder:validation:XValidation:rule="!(has(self.http) && has(self.tcp))",message="http and tcp health checks are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.interval) || duration(self.interval) > duration('0s')",message="interval must be greater than 0"
+// +kubebuilder:validation:XValidation:rule="!has(self.timeout) || duration(self.timeout) > duration('0s')",message="timeout must be greater than 0"
+type HealthCheck struct {
+	// Disable turns off active health checking. This can be used for endpoints that cannot be probed, all the endpoints are then considered healthy.
+	// +optional
+	Disable bool `json:"disable,omitempty"`
+
+	// Interval is the time between two health checks. Defaults to 5s.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Timeout is the time to wait for a health check response. Defaults to 5s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// HealthyThreshold is the number of consecutive successful health checks required before an endpoint is marked healthy. Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	HealthyThreshold *uint32 `json:"healthyThreshold,omitempty"`
+
+	// UnhealthyThreshold is the number of consecutive failed health checks required before an endpoint is marked unhealthy. Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	UnhealthyThreshold *uint32 `json:"unhealthyThreshold,omitempty"`
+
+	// HTTP configures an HTTP health check against the endpoints.
+	// +optional
+	HTTP *HTTPHealthCheck `json:"http,omitempty"`
+
+	// TCP configures a TCP health check with an optional payload against the endpoints.
+	// +optional
+	TCP *TCPHealthCheck `json:"tcp,omitempty"`
+
+	// UDP configures the health checking strategy for UDP ports, since UDP endpoints cannot be probed directly. If not specified, the
+	// healthCheckNodePort of the LoadBalancer is used when available, otherwise active health checking is disabled for UDP ports.
+	// +optional
+	UDP *UDPHealthCheck `json:"udp,omitempty"`
+}
+
+type HTTPHealthCheck struct {
+	// Path is the HTTP path that is requested by the health check.
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Host is the value of the host header in the HTTP health check request. Defaults to the name of the cluster.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// ExpectedStatusCodes is the list of HTTP status codes that are considered healthy. Defaults to 200.
+	// +kubebuilder:validation:items:Minimum=100
+	// +kubebuilder:validation:items:Maximum=599
+	// +optional
+	ExpectedStatusCodes []int32 `json:"expectedStatusCodes,omitempty"`
+}
+
+// UDPHealthCheckMode is the strategy that is used to health check the endpoints of a UDP port.
+// +kubebuilder:validation:Enum=None;CompanionTCPPort;HealthCheckNodePort
+type UDPHealthCheckMode string
+
+const (
+	// UDPHealthCheckModeNone disables active health checking for the UDP port.
+	UDPHealthCheckModeNone UDPHealthCheckMode = "None"
+	// UDPHealthCheckModeCompanionTCPPort health checks a TCP port that is served by the same endpoints.
+	UDPHealthCheckModeCompanionTCPPort UDPHealthCheckMode = "CompanionTCPPort"
+	// UDPHealthCheckModeHealthCheckNodePort health checks the healthCheckNodePort of the Service, served by kube-proxy in the tenant cluster.
+	UDPHealthCheckModeHealthCheckNodePort UDPHealthCheckMode = "HealthCheckNodePort"
+)
+
+// +kubebuilder:validation:XValidation:rule="self.mode != 'CompanionTCPPort' || has(self.port)",message="port is required for the CompanionTCPPort mode"
+type UDPHealthCheck struct {
+	// Mode is the strategy that is used to health check the endpoints. Valid values are None, CompanionTCPPort and HealthCheckNodePort.
+	Mode UDPHealthCheckMode `json:"mode"`
+
+	// Port is the TCP port on the endpoints that is health checked when the mode is CompanionTCPPort. The HTTP and TCP settings of the
+	// health check are applied to this port.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+}
+
+// HealthCheckMode is the active health checking mode that is used for the endpoints of a port.
+type HealthCheckMode string
+
+const (
+	HealthCheckModeDisabled            HealthCheckMode = "Disabled"
+	HealthCheckModeTCP                 HealthCheckMode = "TCP"
+	HealthCheckModeHTTP                HealthCheckMode = "HTTP"
+	HealthCheckModeCompanionTCPPort    HealthCheckMode = "CompanionTCPPort"
+	HealthCheckModeHealthCheckNodePort HealthCheckMode = "HealthCheckNodePort"
+)
+
+type TCPHealthCheck struct {
+	// Send is the payload that is sent to the endpoint. If empty, a connect only health check is performed.
+	// +optional
+	Send string `json:"send,omitempty"`
+
+	// Receive is the list of payloads that are expected in the response. The check succeeds if all the payloads are found in the response, in order.
+	// +optional
+	Receive []string `json:"receive,omitempty"`
+}
+
+// ProxyProtocolVersion is the version of the PROXY protocol.
+// +kubebuilder:validation:Enum=v1;v2
+type ProxyProtocolVersion string
+
+const (
+	ProxyProtocolVersionV1 ProxyProtocolVersion = "v1"
+	ProxyProtocolVersionV2 ProxyProtocolVersion = "v2"
+)
+
+// ProxyProtocol configures the PROXY protocol for TCP ports. It is used to preserve the address of the client across the load balancer.
+type ProxyProtocol struct {
+	// Upstream is the version of the PROXY protocol header that is sent to the endpoints. If not specified, no header is sent.
+	// +optional
+	Upstream ProxyProtocolVersion `json:"upstream,omitempty"`
+
+	// Downstream enables accepting the PROXY protocol on the listeners. This is required when the load balancer runs behind another
+	// L4 load balancer that sends the PROXY protocol header. Both v1 and v2 are accepted. Set to false to opt out of a default that
+	// enables it. LoadBalancers sharing a TLS passthrough port must configure it the same.
+	// +optional
+	Downstream *bool `json:"downstream,omitempty"`
+}
+
+// AccessLogFormat is the format of the access log entries.
+// +kubebuilder:validation:Enum=Text;JSON
+type AccessLogFormat string
+
+const (
+	AccessLogFormatText AccessLogFormat = "Text"
+	AccessLogFormatJSON AccessLogFormat = "JSON"
+)
+
+// AccessLog configures the access logging for the L4 listeners. Access logs are written to the standard output of Envoy Proxy, one entry per
+// TCP connection or UDP session. Each entry contains the tenant, the load balancer and the origin of the load balancer.
+type AccessLog struct {
+	// Disable turns off access logging.
+	// +optional
+	Disable bool `json:"disable,omitempty"`
+
+	// Format is the format of the access log entries. Valid values are Text and JSON. Defaults to Text.
+	// +kubebuilder:default=Text
+	// +optional
+	Format AccessLogFormat `json:"format,omitempty"`
+
+	// Fields are additional fields that are added to each entry. The values can contain Envoy command operators e.g. "%BYTES_SENT%".
+	// +optional
+	Fields map[string]string `json:"fields,omitempty"`
+
+	// SamplingPercentage is the percentage of connections and sessions that are logged. Defaults to 100.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	SamplingPercentage *int32 `json:"samplingPercentage,omitempty"`
+}
+
+// ZoneAwareRouting keeps the traffic of the Envoy proxies within their own zone as long as the endpoints in that zone can handle their share
+// of the load, the rest is sent to the other zones. Both the endpoints and the Envoy proxy pods need the topology.kubernetes.io/zone and
+// topology.kubernetes.io/region labels; for the pods they are set by Kubernetes with the PodTopologyLabelsAdmission feature. Zone-aware
+// routing is not used with the RingHash and Maglev load balancing policies, which includes ClientIP session affinity.
+type ZoneAwareRouting struct {
+	// Enabled turns on zone-aware routing.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// MinClusterSize is the minimum number of endpoints that a load balancer needs for zone-aware routing to be used. Defaults to 6.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinClusterSize *uint64 `json:"minClusterSize,omitempty"`
+}
+
+// CircuitBreakers limit the connections that Envoy Proxy opens to the endpoints of a load balancer. Once a limit is reached, new connections
+// are rejected and the overflow is recorded in the statistics of the corresponding cluster e.g. upstream_cx_overflow.
+type CircuitBreakers struct {
+	// MaxConnections is the maximum number of connections to the endpoints. Defaults to 1024.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConnections *uint32 `json:"maxConnections,omitempty"`
+
+	// MaxPendingRequests is the maximum number of connections that are waiting for a connection to the endpoints. Defaults to 1024.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxPendingRequests *uint32 `json:"maxPendingRequests,omitempty"`
+
+	// MaxRetries is the maximum number of parallel retries to the endpoints. Defaults to 3.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRetries *uint32 `json:"maxRetries,omitempty"`
+}
+
+// OutlierDetection passively ejects the endpoints that fail to accept connections from the load balancing pool. Ejected endpoints are
+// added back to the pool once the ejection time has elapsed.
+type OutlierDetection struct {
+	// Disable turns off outlier detection.
+	// +optional
+	Disable bool `json:"disable,omitempty"`
+
+	// ConsecutiveConnectionFailures is the number of consecutive connection failures or timeouts before an endpoint is ejected. Defaults to 5.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ConsecutiveConnectionFailures *uint32 `json:"consecutiveConnectionFailures,omitempty"`
+
+	// Interval is the time between two ejection analysis sweeps. Defaults to 10s.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// BaseEjectionTime is the time an endpoint is ejected for. It's multiplied by the number of times the endpoint has been ejected. Defaults to 30s.
+	// +optional
+	BaseEjectionTime *metav1.Duration `json:"baseEjectionTime,omitempty"`
+
+	// MaxEjectionPercent is the maximum percentage of the endpoints that can be ejected at the same time. Defaults to 10.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	MaxEjectionPercent *uint32 `json:"maxEjectionPercent,omitempty"`
+}
+
+// TLSPassthrough routes TLS connections without terminating them, based on the server name indication (SNI) of the TLS client hello.
+type TLSPassthrough struct {
+	// Hostnames are the server names that are routed to the port. A wildcard prefix such as *.example.com matches all the subdomains.
+	// A hostname can only be claimed by a single LoadBalancer port per listener port, the oldest claim wins.
+	// +kubebuilder:validation:MinItems=1
+	Hostnames []string `json:"hostnames"`
+}
+
+// SecretReferenceKind is the kind of a resource that holds TLS certificates.
+// +kubebuilder:validation:Enum=Secret;SyncSecret
+type SecretReferenceKind string
+
+const (
+	SecretReferenceKindSecret     SecretReferenceKind = "Secret"
+	SecretReferenceKindSyncSecret SecretReferenceKind = "SyncSecret"
+)
+
+// SecretReference references a Secret or a SyncSecret in the namespace of the referencing resource.
+type SecretReference struct {
+	// Kind is the kind of the referenced resource. Defaults to Secret.
+	// +kubebuilder:default=Secret
+	// +optional
+	Kind SecretReferenceKind `json:"kind,omitempty"`
+
+	// Name is the name of the referenced resource.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ListenerTLS terminates TLS on the listener of the Envoy proxy. The certificates are served to Envoy over SDS, hence a rotated certificate
+// is picked up without restarting the listener.
+type ListenerTLS struct {
+	// CertificateRef references the Secret or SyncSecret that contains the certificate chain and the private key in the tls.crt and tls.key keys.
+	CertificateRef SecretReference `json:"certificateRef"`
+
+	// ClientValidation enables mutual TLS. Only clients that present a certificate signed by the CA bundle are accepted.
+	// +optional
+	ClientValidation *ClientValidation `json:"clientValidation,omitempty"`
+}
+
+// ClientValidation configures the validation of the client certificates.
+type ClientValidation struct {
+	// CACertificateRef references the Secret or SyncSecret that contains the CA bundle in the ca.crt key.
+	CACertificateRef SecretReference `json:"caCertificateRef"`
+}
+
+// UpstreamTLS originates TLS from the Envoy proxy to the endpoints in the tenant cluster. It's only applied to TCP ports. The
+// certificates are usually propagated from the tenant cluster with SyncSecrets. The certificates of the endpoints are verified against
+// CACertificateRef; skipping the verification must be requested explicitly with InsecureSkipVerify.
+// +kubebuilder:validation:XValidation:rule="has(self.caCertificateRef) != (has(self.insecureSkipVerify) && self.insecureSkipVerify)",message="exactly one of caCertificateRef or insecureSkipVerify is required"
+type UpstreamTLS struct {
+	// SNI is the server name that is sent to the endpoints during the TLS handshake.
+	// +optional
+	SNI string `json:"sni,omitempty"`
+
+	// CACertificateRef references the Secret or SyncSecret that contains the CA bundle in the ca.crt key that is used to verify the
+	// certificates of the endpoints. Required unless InsecureSkipVerify is set.
+	// +optional
+	CACertificateRef *SecretReference `json:"caCertificateRef,omitempty"`
+
+	// InsecureSkipVerify encrypts the connections to the endpoints without verifying their certificates. This doesn't protect against
+	// an attacker that intercepts the traffic and should only be used if the endpoints serve self-signed certificates without a CA.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// SubjectAltNames are the DNS names of which at least one must be present in the certificates of the endpoints. Only used if
+	// CACertificateRef is specified.
+	// +optional
+	SubjectAltNames []string `json:"subjectAltNames,omitempty"`
+
+	// ClientCertificateRef enables mutual TLS. It references the Secret or SyncSecret that contains the client certificate chain and the
+	// private key in the tls.crt and tls.key keys.
+	// +optional
+	ClientCertificateRef *SecretReference `json:"clientCertificateRef,omitempty"`
+}
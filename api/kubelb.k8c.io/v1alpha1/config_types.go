@@ -82,6 +82,19 @@ type EnvoyProxy struct {
 	// Affinity is used to schedule Envoy Proxy pods on nodes with matching affinity.
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// IPFamilies are the IP families of Envoy Proxy. The listeners bind to them and only the endpoints with addresses of these families are
+	// served, since the proxies can't reach the others. Set both IPv4 and IPv6 for dual-stack clusters. Defaults to IPv4.
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:XValidation:rule="self.all(f, f == 'IPv4' || f == 'IPv6')",message="only IPv4 and IPv6 are valid IP families"
+	// +optional
+	IPFamilies []corev1.IPFamily `json:"ipFamilies,omitempty"`
+
+	// ExposeClusterStatus exposes the status of the clusters of the admin interface on the stats port of Envoy Proxy, next to the
+	// Prometheus statistics. It's required to report the active priority of the load balancers, but it reveals the addresses and the
+	// health of the endpoints to everyone who can reach the Envoy Proxy pods.
+	// +optional
+	ExposeClusterStatus bool `json:"exposeClusterStatus,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -26,6 +26,27 @@ import (
 // To configure multiple different annotations, you can provide unique suffix e.g. "kubelb.k8c.io/propagate-annotation-1"
 var PropagateAnnotation = "kubelb.k8c.io/propagate-annotation"
 
+// LoadBalancingPolicyAnnotation can be set on a Service in the tenant cluster to configure the load balancing policy of the
+// corresponding LoadBalancer. Valid values are the same as for LoadBalancingPolicyType e.g. "RingHash". The annotation only sets the
+// type of the policy, the parameters of the policy are configured on the LoadBalancer in the LB cluster and are kept by the CCM.
+var LoadBalancingPolicyAnnotation = "kubelb.k8c.io/load-balancing-policy"
+
+// ProxyProtocolAnnotation can be set on a Service in the tenant cluster to send the PROXY protocol header to the endpoints of the
+// corresponding LoadBalancer. Valid values are "v1" and "v2".
+var ProxyProtocolAnnotation = "kubelb.k8c.io/proxy-protocol"
+
+// TLSPassthroughHostnamesAnnotation can be set on a Service in the tenant cluster to enable TLS passthrough for its TCP ports. The value is a
+// comma separated list of hostnames e.g. "app.example.com,*.apps.example.com".
+var TLSPassthroughHostnamesAnnotation = "kubelb.k8c.io/tls-passthrough-hostnames"
+
+// EnvoySnapshotVersionAnnotation can be set on a Tenant to pin its Envoy proxies to a snapshot version, e.g. to roll back to a version that
+// was served before. For the global Envoy proxy topology, it is set on the Config instead. The pin freezes the whole configuration,
+// including the endpoints that are served over EDS, so changes of the endpoints are not applied until the annotation is removed. Only the
+// last snapshots that were accepted by the Envoy proxies are kept, in memory by each replica of the control plane. If the version is
+// unknown, e.g. after a restart, the configuration of the Envoy proxies is not updated and a warning event is recorded on the Tenant or
+// the Config.
+var EnvoySnapshotVersionAnnotation = "kubelb.k8c.io/envoy-snapshot-version"
+
 // LoadBalancerStatus defines the observed state of LoadBalancer
 type LoadBalancerStatus struct {
 	// LoadBalancer contains the current status of the load-balancer,
@@ -36,6 +57,77 @@ type LoadBalancerStatus struct {
 	// Service contains the current status of the LB service.
 	// +optional
 	Service ServiceStatus `json:"service,omitempty" protobuf:"bytes,2,opt,name=service"`
+
+	// Proxy contains the state of the load balancer as observed by the Envoy proxies.
+	// +optional
+	Proxy *ProxyStatus `json:"proxy,omitempty"`
+
+	// Conditions contains the conditions of the LoadBalancer.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionHostnamesAccepted indicates whether all the hostnames that are claimed for TLS passthrough have been accepted.
+	ConditionHostnamesAccepted ConditionType = "HostnamesAccepted"
+	// ConditionProxyConfigRejected indicates whether the Envoy proxies rejected the listeners or clusters of a LoadBalancer or a Route.
+	ConditionProxyConfigRejected ConditionType = "ProxyConfigRejected"
+	// ConditionProxyConfigInvalid indicates that a LoadBalancer or a Route was left out of the Envoy proxy configuration since its
+	// resources could not be generated or are invalid. For TLS passthrough, only the routes of the LoadBalancer on the shared listeners
+	// are left out. If the addresses of the endpoints are unavailable, the configuration that is currently served for the LoadBalancer or
+	// the Route is kept instead, if any. The other LoadBalancers and Routes are not affected.
+	ConditionProxyConfigInvalid ConditionType = "ProxyConfigInvalid"
+)
+
+const (
+	ReasonHostnamesAccepted      = "Accepted"
+	ReasonHostnameConflict       = "HostnameConflict"
+	ReasonHostnameNotAllowed     = "HostnameNotAllowed"
+	ReasonInvalidTLSPassthrough  = "InvalidTLSPassthrough"
+	ReasonProxyConfigRejected    = "Rejected"
+	ReasonProxyConfigAccepted    = "Accepted"
+	ReasonInvalidSourceRanges    = "InvalidSourceRanges"
+	ReasonAddressesUnavailable   = "AddressesUnavailable"
+	ReasonInvalidResource        = "InvalidResource"
+	ReasonUpstreamTLSUnavailable = "UpstreamTLSUnavailable"
+)
+
+// ProxyStatus contains the statistics that are collected from the Envoy proxies for a load balancer.
+type ProxyStatus struct {
+	// EjectedEndpoints is the number of endpoints that are currently ejected by outlier detection, summed over the ports of the load balancer.
+	// With multiple Envoy proxy replicas, the highest number observed by a single replica is reported.
+	EjectedEndpoints int32 `json:"ejectedEndpoints"`
+
+	// RateLimitedConnections is the number of connections that were rejected by the connection rate limits of the ports since the Envoy
+	// proxies were started, summed over the ports and the Envoy proxy replicas.
+	// +optional
+	RateLimitedConnections int64 `json:"rateLimitedConnections,omitempty"`
+
+	// CircuitBreakerOverflows are the connections, pending requests and retries that were rejected by the circuit breakers since the
+	// Envoy proxies were started, summed over the ports and the Envoy proxy replicas.
+	// +optional
+	CircuitBreakerOverflows *CircuitBreakerOverflows `json:"circuitBreakerOverflows,omitempty"`
+
+	// ActivePriority is the lowest priority of the endpoints that receive connections, i.e. 0 while the endpoints with priority 0 can
+	// handle all the connections and a higher value once the connections fail over. The highest value over the ports and the Envoy proxy
+	// replicas is reported. Only set if the endpoints of the load balancer have more than one priority and the Config exposes the status
+	// of the clusters.
+	// +optional
+	ActivePriority *uint32 `json:"activePriority,omitempty"`
+}
+
+// CircuitBreakerOverflows are the overflow counters of the circuit breakers of a load balancer.
+type CircuitBreakerOverflows struct {
+	// Connections is the number of connections that were rejected because the maximum number of connections was reached.
+	Connections int64 `json:"connections"`
+
+	// PendingRequests is the number of requests that were rejected because the maximum number of pending requests was reached.
+	PendingRequests int64 `json:"pendingRequests"`
+
+	// Retries is the number of retries that were not attempted because the maximum number of retries was reached.
+	Retries int64 `json:"retries"`
 }
 
 type ServiceStatus struct {
@@ -46,9 +138,16 @@ type ServiceStatus struct {
 type ServicePort struct {
 	corev1.ServicePort `json:",inline"`
 	UpstreamTargetPort int32 `json:"upstreamTargetPort" protobuf:"bytes,4,opt,name=port"`
+
+	// HealthCheckMode is the active health checking mode that is used for the endpoints of this port.
+	// +optional
+	HealthCheckMode HealthCheckMode `json:"healthCheckMode,omitempty"`
 }
 
 // LoadBalancerPort contains information on service's port.
+// +kubebuilder:validation:XValidation:rule="!(has(self.tls) && has(self.tlsPassthrough))",message="tls and tlsPassthrough are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.udp) || (has(self.protocol) && self.protocol == 'UDP')",message="udp is only supported for UDP ports"
+// +kubebuilder:validation:XValidation:rule="!has(self.connectionRateLimit) || !has(self.protocol) || self.protocol == 'TCP'",message="connectionRateLimit is only supported for TCP ports"
 type LoadBalancerPort struct {
 	// The name of this port within the service. This must be a DNS_LABEL.
 	// All ports within a Spec must have unique names. When considering
@@ -64,6 +163,30 @@ type LoadBalancerPort struct {
 
 	// The port that will be exposed by the LoadBalancer.
 	Port int32 `json:"port" protobuf:"varint,3,opt,name=port"`
+
+	// HealthCheck configures the active health checking for this port. This has higher precedence than the health check specified for the LoadBalancer.
+	// +optional
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+
+	// TLSPassthrough shares the listener port of the Envoy proxy with the other LoadBalancers that use TLS passthrough on the same
+	// port. Connections are routed to this LoadBalancer based on the server name of the TLS client hello, and no dedicated listener
+	// port is allocated. Only supported for TCP ports.
+	// +optional
+	TLSPassthrough *TLSPassthrough `json:"tlsPassthrough,omitempty"`
+
+	// TLS terminates TLS on the listener of this port before the connections are proxied to the endpoints. Only supported for TCP ports.
+	// +optional
+	TLS *ListenerTLS `json:"tls,omitempty"`
+
+	// ConnectionRateLimit limits the rate of new connections to this port. If not specified, the default from the Tenant or the Config
+	// is used. The rate is capped to the maximum of both the Tenant and the Config. With multiple Envoy proxy replicas, each replica
+	// enforces the limit independently. Only supported for TCP ports.
+	// +optional
+	ConnectionRateLimit *ConnectionRateLimit `json:"connectionRateLimit,omitempty"`
+
+	// UDP configures the sessions of the UDP proxy for this port. Only supported for UDP ports.
+	// +optional
+	UDP *UDPSettings `json:"udp,omitempty"`
 }
 
 // LoadBalancerSpec defines the desired state of LoadBalancer
@@ -97,6 +220,58 @@ type LoadBalancerSpec struct {
 	// +optional
 	// +kubebuilder:default=ClusterIP
 	Type corev1.ServiceType `json:"type,omitempty" protobuf:"bytes,4,opt,name=type,casttype=ServiceType"`
+
+	// LoadBalancingPolicy defines how connections are distributed across the endpoints of the LoadBalancer.
+	// This has higher precedence than the value specified in the Tenant and Config. Defaults to RoundRobin.
+	// +optional
+	LoadBalancingPolicy *LoadBalancingPolicy `json:"loadBalancingPolicy,omitempty"`
+
+	// HealthCheck configures the active health checking for the endpoints of the LoadBalancer.
+	// +optional
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+
+	// HealthCheckNodePort is the healthCheckNodePort of the Service in the tenant cluster. It is used to health check the endpoints of
+	// UDP ports.
+	// +optional
+	HealthCheckNodePort int32 `json:"healthCheckNodePort,omitempty"`
+
+	// SourceRanges are the CIDRs of the clients that are allowed to connect to the LoadBalancer. It's propagated from the
+	// loadBalancerSourceRanges of the Service in the tenant cluster. If not specified, all the clients are allowed. The source ranges
+	// that are denied in the Tenant or the Config take precedence.
+	// +optional
+	SourceRanges []string `json:"sourceRanges,omitempty"`
+
+	// SessionAffinity pins the connections of a client to the same endpoint. It's propagated from the Service in the tenant cluster and
+	// takes precedence over load balancing policies that don't use a hash key.
+	// +optional
+	SessionAffinity *SessionAffinity `json:"sessionAffinity,omitempty"`
+
+	// ProxyProtocol configures the PROXY protocol for the TCP ports of the LoadBalancer.
+	// +optional
+	ProxyProtocol *ProxyProtocol `json:"proxyProtocol,omitempty"`
+
+	// IPFamilyPolicy is the IP family policy of the Service that is generated for the LoadBalancer. The IP families themselves are not
+	// propagated since they depend on the configuration of the cluster. Defaults to SingleStack.
+	// +optional
+	IPFamilyPolicy *corev1.IPFamilyPolicy `json:"ipFamilyPolicy,omitempty"`
+
+	// CircuitBreakers limit the connections to the endpoints of the LoadBalancer.
+	// +optional
+	CircuitBreakers *CircuitBreakers `json:"circuitBreakers,omitempty"`
+
+	// OutlierDetection configures the passive ejection of endpoints that fail to accept connections. If not specified, the default
+	// from the Tenant or the Config is used.
+	// +optional
+	OutlierDetection *OutlierDetection `json:"outlierDetection,omitempty"`
+
+	// TCP configures the timeouts and the keepalive for the TCP ports of the LoadBalancer. Values that are not specified are defaulted
+	// from the Tenant and then the Config.
+	// +optional
+	TCP *TCPSettings `json:"tcp,omitempty"`
+
+	// UpstreamTLS originates TLS from the Envoy proxy to the endpoints of the TCP ports of the LoadBalancer.
+	// +optional
+	UpstreamTLS *UpstreamTLS `json:"upstreamTLS,omitempty"`
 }
 
 // +kubebuilder:object:root=true
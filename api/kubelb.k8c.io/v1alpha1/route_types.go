@@ -34,6 +34,27 @@ type RouteSpec struct {
 	// Source contains the information about the source of the route. This is used when the route is created from external sources.
 	// +optional
 	Source RouteSource `json:"source,omitempty"`
+
+	// ServiceSettings contains the settings for the upstream clusters that are generated for the services of the route. The key is the
+	// service in the format `namespace/name`, as seen in the tenant cluster.
+	// This field is not managed by the KubeLB CCM and is preserved when the CCM updates the route.
+	// +optional
+	ServiceSettings map[string]RouteServiceSettings `json:"serviceSettings,omitempty"`
+}
+
+// RouteServiceSettings contains the settings for the upstream clusters of a service.
+type RouteServiceSettings struct {
+	// HealthCheck configures the active health checking for the endpoints of the service.
+	// +optional
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+
+	// CircuitBreakers limit the connections to the endpoints of the service.
+	// +optional
+	CircuitBreakers *CircuitBreakers `json:"circuitBreakers,omitempty"`
+
+	// UpstreamTLS originates TLS from the Envoy proxy to the endpoints of the TCP ports of the service.
+	// +optional
+	UpstreamTLS *UpstreamTLS `json:"upstreamTLS,omitempty"`
 }
 
 type RouteSource struct {
@@ -102,6 +123,12 @@ type Route struct {
 type RouteStatus struct {
 	// Resources contains the list of resources that are created/processed as a result of the Route.
 	Resources RouteResourcesStatus `json:"resources,omitempty"`
+
+	// Conditions contains the conditions of the Route.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 type RouteResourcesStatus struct {
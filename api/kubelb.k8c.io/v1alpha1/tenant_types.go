@@ -29,7 +29,14 @@ type TenantSpec struct {
 	GatewayAPI   GatewayAPISettings   `json:"gatewayAPI,omitempty"`
 }
 
-// LoadBalancerSettings defines the settings for the load balancers.
+// LoadBalancerSettings defines the settings for the load balancers. The settings of the Tenant are merged with the settings of the Config:
+//   - LoadBalancingPolicy, AccessLog, OutlierDetection, ZoneAwareRouting and AllowedTLSHostnames of the Tenant replace the values
+//     of the Config.
+//   - ProxyProtocol, CircuitBreakers, TCP, DNS and ConnectionRateLimit are defaults. They are resolved per field, a value of the
+//     LoadBalancer or the Route takes precedence over the Tenant, and the Tenant over the Config. ConnectionRateLimit is resolved as
+//     a whole.
+//   - CircuitBreakerLimits and MaxConnectionRateLimit are upper bounds, the bounds of both the Tenant and the Config are enforced.
+//   - DeniedSourceRanges of both the Tenant and the Config are denied.
 type LoadBalancerSettings struct {
 	// Class is the class of the load balancer to use.
 	// This has higher precedence than the value specified in the Config.
@@ -38,6 +45,69 @@ type LoadBalancerSettings struct {
 
 	// Disable is a flag that can be used to disable L4 load balancing for a tenant.
 	Disable bool `json:"disable,omitempty"`
+
+	// LoadBalancingPolicy is the default load balancing policy for the load balancers. It is only used if the LoadBalancer
+	// does not specify a policy.
+	// +optional
+	LoadBalancingPolicy *LoadBalancingPolicy `json:"loadBalancingPolicy,omitempty"`
+
+	// ProxyProtocol is the default PROXY protocol configuration for the load balancers. Each field is only used if the LoadBalancer
+	// does not specify it.
+	// +optional
+	ProxyProtocol *ProxyProtocol `json:"proxyProtocol,omitempty"`
+
+	// AccessLog configures the access logging for the L4 listeners of the load balancers.
+	// +optional
+	AccessLog *AccessLog `json:"accessLog,omitempty"`
+
+	// CircuitBreakers are the default circuit breakers for the load balancers and the services of the routes. They are only used for the values
+	// that are not specified on the LoadBalancer or the Route.
+	// +optional
+	CircuitBreakers *CircuitBreakers `json:"circuitBreakers,omitempty"`
+
+	// CircuitBreakerLimits are the upper bounds for the circuit breakers of the load balancers and the services of the routes. Higher values
+	// are capped to these limits. The limits of both the Tenant and the Config are enforced.
+	// +optional
+	CircuitBreakerLimits *CircuitBreakers `json:"circuitBreakerLimits,omitempty"`
+
+	// OutlierDetection is the default outlier detection for the load balancers.
+	// +optional
+	OutlierDetection *OutlierDetection `json:"outlierDetection,omitempty"`
+
+	// TCP are the default timeouts and keepalive for the TCP ports of the load balancers. They are only used for the values that are
+	// not specified on the LoadBalancer.
+	// +optional
+	TCP *TCPSettings `json:"tcp,omitempty"`
+
+	// ConnectionRateLimit is the default connection rate limit for the TCP ports of the load balancers. It is only used if the port does
+	// not specify a rate limit.
+	// +optional
+	ConnectionRateLimit *ConnectionRateLimit `json:"connectionRateLimit,omitempty"`
+
+	// MaxConnectionRateLimit is the upper bound for the connection rate limits of the TCP ports of the load balancers. Larger buckets are
+	// capped to its number of tokens, and faster rates, i.e. tokens per fill interval, to its rate. Ports without a rate limit are not
+	// limited.
+	// +optional
+	MaxConnectionRateLimit *ConnectionRateLimit `json:"maxConnectionRateLimit,omitempty"`
+
+	// ZoneAwareRouting configures zone-aware routing for the load balancers and the services of the routes.
+	// +optional
+	ZoneAwareRouting *ZoneAwareRouting `json:"zoneAwareRouting,omitempty"`
+
+	// DNS configures the default resolution of the endpoints of the load balancers and the routes that only have a hostname. It is only
+	// used for the values that are not specified on the endpoints of the LoadBalancer or the Route.
+	// +optional
+	DNS *DNSSettings `json:"dns,omitempty"`
+
+	// DeniedSourceRanges are the CIDRs of the clients that are never allowed to connect to the load balancers and the services of the
+	// routes, regardless of their source ranges. The denied source ranges of both the Tenant and the Config are enforced.
+	// +optional
+	DeniedSourceRanges []string `json:"deniedSourceRanges,omitempty"`
+
+	// AllowedTLSHostnames restricts the hostnames that the load balancers can claim for TLS passthrough. A wildcard prefix such as
+	// *.example.com allows all the subdomains. If not specified, all the hostnames are allowed.
+	// +optional
+	AllowedTLSHostnames []string `json:"allowedTLSHostnames,omitempty"`
 }
 
 // IngressSettings defines the settings for the ingress.
@@ -26,6 +26,33 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessLog) DeepCopyInto(out *AccessLog) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SamplingPercentage != nil {
+		in, out := &in.SamplingPercentage, &out.SamplingPercentage
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessLog.
+func (in *AccessLog) DeepCopy() *AccessLog {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessLog)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Addresses) DeepCopyInto(out *Addresses) {
 	*out = *in
@@ -151,6 +178,67 @@ func (in *AnnotationSettings) DeepCopy() *AnnotationSettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CircuitBreakerOverflows) DeepCopyInto(out *CircuitBreakerOverflows) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CircuitBreakerOverflows.
+func (in *CircuitBreakerOverflows) DeepCopy() *CircuitBreakerOverflows {
+	if in == nil {
+		return nil
+	}
+	out := new(CircuitBreakerOverflows)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CircuitBreakers) DeepCopyInto(out *CircuitBreakers) {
+	*out = *in
+	if in.MaxConnections != nil {
+		in, out := &in.MaxConnections, &out.MaxConnections
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.MaxPendingRequests != nil {
+		in, out := &in.MaxPendingRequests, &out.MaxPendingRequests
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(uint32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CircuitBreakers.
+func (in *CircuitBreakers) DeepCopy() *CircuitBreakers {
+	if in == nil {
+		return nil
+	}
+	out := new(CircuitBreakers)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientValidation) DeepCopyInto(out *ClientValidation) {
+	*out = *in
+	out.CACertificateRef = in.CACertificateRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientValidation.
+func (in *ClientValidation) DeepCopy() *ClientValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Config) DeepCopyInto(out *Config) {
 	*out = *in
@@ -229,6 +317,52 @@ func (in *ConfigSpec) DeepCopy() *ConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionRateLimit) DeepCopyInto(out *ConnectionRateLimit) {
+	*out = *in
+	if in.TokensPerFill != nil {
+		in, out := &in.TokensPerFill, &out.TokensPerFill
+		*out = new(uint32)
+		**out = **in
+	}
+	out.FillInterval = in.FillInterval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionRateLimit.
+func (in *ConnectionRateLimit) DeepCopy() *ConnectionRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSSettings) DeepCopyInto(out *DNSSettings) {
+	*out = *in
+	if in.RefreshRate != nil {
+		in, out := &in.RefreshRate, &out.RefreshRate
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RespectDNSTTL != nil {
+		in, out := &in.RespectDNSTTL, &out.RespectDNSTTL
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSSettings.
+func (in *DNSSettings) DeepCopy() *DNSSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EndpointAddress) DeepCopyInto(out *EndpointAddress) {
 	*out = *in
@@ -286,6 +420,11 @@ func (in *EnvoyProxy) DeepCopyInto(out *EnvoyProxy) {
 		*out = new(v1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]v1.IPFamily, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvoyProxy.
@@ -318,6 +457,76 @@ func (in *GatewayAPISettings) DeepCopy() *GatewayAPISettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPHealthCheck) DeepCopyInto(out *HTTPHealthCheck) {
+	*out = *in
+	if in.ExpectedStatusCodes != nil {
+		in, out := &in.ExpectedStatusCodes, &out.ExpectedStatusCodes
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHealthCheck.
+func (in *HTTPHealthCheck) DeepCopy() *HTTPHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HealthyThreshold != nil {
+		in, out := &in.HealthyThreshold, &out.HealthyThreshold
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.UnhealthyThreshold != nil {
+		in, out := &in.UnhealthyThreshold, &out.UnhealthyThreshold
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TCP != nil {
+		in, out := &in.TCP, &out.TCP
+		*out = new(TCPHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UDP != nil {
+		in, out := &in.UDP, &out.UDP
+		*out = new(UDPHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheck.
+func (in *HealthCheck) DeepCopy() *HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressSettings) DeepCopyInto(out *IngressSettings) {
 	*out = *in
@@ -361,6 +570,47 @@ func (in *KubernetesSource) DeepCopy() *KubernetesSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeastRequestConfig) DeepCopyInto(out *LeastRequestConfig) {
+	*out = *in
+	if in.ChoiceCount != nil {
+		in, out := &in.ChoiceCount, &out.ChoiceCount
+		*out = new(uint32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeastRequestConfig.
+func (in *LeastRequestConfig) DeepCopy() *LeastRequestConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LeastRequestConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ListenerTLS) DeepCopyInto(out *ListenerTLS) {
+	*out = *in
+	out.CertificateRef = in.CertificateRef
+	if in.ClientValidation != nil {
+		in, out := &in.ClientValidation, &out.ClientValidation
+		*out = new(ClientValidation)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ListenerTLS.
+func (in *ListenerTLS) DeepCopy() *ListenerTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(ListenerTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LoadBalancer) DeepCopyInto(out *LoadBalancer) {
 	*out = *in
@@ -406,6 +656,21 @@ func (in *LoadBalancerEndpoints) DeepCopyInto(out *LoadBalancerEndpoints) {
 		*out = make([]EndpointPort, len(*in))
 		copy(*out, *in)
 	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.OverprovisioningFactor != nil {
+		in, out := &in.OverprovisioningFactor, &out.OverprovisioningFactor
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(DNSSettings)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerEndpoints.
@@ -453,6 +718,31 @@ func (in *LoadBalancerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LoadBalancerPort) DeepCopyInto(out *LoadBalancerPort) {
 	*out = *in
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(HealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSPassthrough != nil {
+		in, out := &in.TLSPassthrough, &out.TLSPassthrough
+		*out = new(TLSPassthrough)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ListenerTLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectionRateLimit != nil {
+		in, out := &in.ConnectionRateLimit, &out.ConnectionRateLimit
+		*out = new(ConnectionRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UDP != nil {
+		in, out := &in.UDP, &out.UDP
+		*out = new(UDPSettings)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerPort.
@@ -473,6 +763,71 @@ func (in *LoadBalancerSettings) DeepCopyInto(out *LoadBalancerSettings) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.LoadBalancingPolicy != nil {
+		in, out := &in.LoadBalancingPolicy, &out.LoadBalancingPolicy
+		*out = new(LoadBalancingPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProxyProtocol != nil {
+		in, out := &in.ProxyProtocol, &out.ProxyProtocol
+		*out = new(ProxyProtocol)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AccessLog != nil {
+		in, out := &in.AccessLog, &out.AccessLog
+		*out = new(AccessLog)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CircuitBreakers != nil {
+		in, out := &in.CircuitBreakers, &out.CircuitBreakers
+		*out = new(CircuitBreakers)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CircuitBreakerLimits != nil {
+		in, out := &in.CircuitBreakerLimits, &out.CircuitBreakerLimits
+		*out = new(CircuitBreakers)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OutlierDetection != nil {
+		in, out := &in.OutlierDetection, &out.OutlierDetection
+		*out = new(OutlierDetection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TCP != nil {
+		in, out := &in.TCP, &out.TCP
+		*out = new(TCPSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectionRateLimit != nil {
+		in, out := &in.ConnectionRateLimit, &out.ConnectionRateLimit
+		*out = new(ConnectionRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxConnectionRateLimit != nil {
+		in, out := &in.MaxConnectionRateLimit, &out.MaxConnectionRateLimit
+		*out = new(ConnectionRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ZoneAwareRouting != nil {
+		in, out := &in.ZoneAwareRouting, &out.ZoneAwareRouting
+		*out = new(ZoneAwareRouting)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(DNSSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeniedSourceRanges != nil {
+		in, out := &in.DeniedSourceRanges, &out.DeniedSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedTLSHostnames != nil {
+		in, out := &in.AllowedTLSHostnames, &out.AllowedTLSHostnames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerSettings.
@@ -498,8 +853,60 @@ func (in *LoadBalancerSpec) DeepCopyInto(out *LoadBalancerSpec) {
 	if in.Ports != nil {
 		in, out := &in.Ports, &out.Ports
 		*out = make([]LoadBalancerPort, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LoadBalancingPolicy != nil {
+		in, out := &in.LoadBalancingPolicy, &out.LoadBalancingPolicy
+		*out = new(LoadBalancingPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(HealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SourceRanges != nil {
+		in, out := &in.SourceRanges, &out.SourceRanges
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.SessionAffinity != nil {
+		in, out := &in.SessionAffinity, &out.SessionAffinity
+		*out = new(SessionAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProxyProtocol != nil {
+		in, out := &in.ProxyProtocol, &out.ProxyProtocol
+		*out = new(ProxyProtocol)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IPFamilyPolicy != nil {
+		in, out := &in.IPFamilyPolicy, &out.IPFamilyPolicy
+		*out = new(v1.IPFamilyPolicy)
+		**out = **in
+	}
+	if in.CircuitBreakers != nil {
+		in, out := &in.CircuitBreakers, &out.CircuitBreakers
+		*out = new(CircuitBreakers)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OutlierDetection != nil {
+		in, out := &in.OutlierDetection, &out.OutlierDetection
+		*out = new(OutlierDetection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TCP != nil {
+		in, out := &in.TCP, &out.TCP
+		*out = new(TCPSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpstreamTLS != nil {
+		in, out := &in.UpstreamTLS, &out.UpstreamTLS
+		*out = new(UpstreamTLS)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerSpec.
@@ -517,6 +924,18 @@ func (in *LoadBalancerStatus) DeepCopyInto(out *LoadBalancerStatus) {
 	*out = *in
 	in.LoadBalancer.DeepCopyInto(&out.LoadBalancer)
 	in.Service.DeepCopyInto(&out.Service)
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerStatus.
@@ -529,6 +948,136 @@ func (in *LoadBalancerStatus) DeepCopy() *LoadBalancerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancingPolicy) DeepCopyInto(out *LoadBalancingPolicy) {
+	*out = *in
+	if in.LeastRequest != nil {
+		in, out := &in.LeastRequest, &out.LeastRequest
+		*out = new(LeastRequestConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RingHash != nil {
+		in, out := &in.RingHash, &out.RingHash
+		*out = new(RingHashConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Maglev != nil {
+		in, out := &in.Maglev, &out.Maglev
+		*out = new(MaglevConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancingPolicy.
+func (in *LoadBalancingPolicy) DeepCopy() *LoadBalancingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaglevConfig) DeepCopyInto(out *MaglevConfig) {
+	*out = *in
+	if in.TableSize != nil {
+		in, out := &in.TableSize, &out.TableSize
+		*out = new(uint64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaglevConfig.
+func (in *MaglevConfig) DeepCopy() *MaglevConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(MaglevConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutlierDetection) DeepCopyInto(out *OutlierDetection) {
+	*out = *in
+	if in.ConsecutiveConnectionFailures != nil {
+		in, out := &in.ConsecutiveConnectionFailures, &out.ConsecutiveConnectionFailures
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BaseEjectionTime != nil {
+		in, out := &in.BaseEjectionTime, &out.BaseEjectionTime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxEjectionPercent != nil {
+		in, out := &in.MaxEjectionPercent, &out.MaxEjectionPercent
+		*out = new(uint32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutlierDetection.
+func (in *OutlierDetection) DeepCopy() *OutlierDetection {
+	if in == nil {
+		return nil
+	}
+	out := new(OutlierDetection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyProtocol) DeepCopyInto(out *ProxyProtocol) {
+	*out = *in
+	if in.Downstream != nil {
+		in, out := &in.Downstream, &out.Downstream
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyProtocol.
+func (in *ProxyProtocol) DeepCopy() *ProxyProtocol {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyProtocol)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyStatus) DeepCopyInto(out *ProxyStatus) {
+	*out = *in
+	if in.CircuitBreakerOverflows != nil {
+		in, out := &in.CircuitBreakerOverflows, &out.CircuitBreakerOverflows
+		*out = new(CircuitBreakerOverflows)
+		**out = **in
+	}
+	if in.ActivePriority != nil {
+		in, out := &in.ActivePriority, &out.ActivePriority
+		*out = new(uint32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyStatus.
+func (in *ProxyStatus) DeepCopy() *ProxyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceState) DeepCopyInto(out *ResourceState) {
 	*out = *in
@@ -552,6 +1101,31 @@ func (in *ResourceState) DeepCopy() *ResourceState {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RingHashConfig) DeepCopyInto(out *RingHashConfig) {
+	*out = *in
+	if in.MinimumRingSize != nil {
+		in, out := &in.MinimumRingSize, &out.MinimumRingSize
+		*out = new(uint64)
+		**out = **in
+	}
+	if in.MaximumRingSize != nil {
+		in, out := &in.MaximumRingSize, &out.MaximumRingSize
+		*out = new(uint64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RingHashConfig.
+func (in *RingHashConfig) DeepCopy() *RingHashConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RingHashConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route) DeepCopyInto(out *Route) {
 	*out = *in
@@ -634,6 +1208,36 @@ func (in *RouteResourcesStatus) DeepCopy() *RouteResourcesStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteServiceSettings) DeepCopyInto(out *RouteServiceSettings) {
+	*out = *in
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(HealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CircuitBreakers != nil {
+		in, out := &in.CircuitBreakers, &out.CircuitBreakers
+		*out = new(CircuitBreakers)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpstreamTLS != nil {
+		in, out := &in.UpstreamTLS, &out.UpstreamTLS
+		*out = new(UpstreamTLS)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteServiceSettings.
+func (in *RouteServiceSettings) DeepCopy() *RouteServiceSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteServiceSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RouteServiceStatus) DeepCopyInto(out *RouteServiceStatus) {
 	*out = *in
@@ -688,6 +1292,13 @@ func (in *RouteSpec) DeepCopyInto(out *RouteSpec) {
 		}
 	}
 	in.Source.DeepCopyInto(&out.Source)
+	if in.ServiceSettings != nil {
+		in, out := &in.ServiceSettings, &out.ServiceSettings
+		*out = make(map[string]RouteServiceSettings, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteSpec.
@@ -704,6 +1315,13 @@ func (in *RouteSpec) DeepCopy() *RouteSpec {
 func (in *RouteStatus) DeepCopyInto(out *RouteStatus) {
 	*out = *in
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteStatus.
@@ -716,6 +1334,21 @@ func (in *RouteStatus) DeepCopy() *RouteStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServicePort) DeepCopyInto(out *ServicePort) {
 	*out = *in
@@ -754,6 +1387,26 @@ func (in *ServiceStatus) DeepCopy() *ServiceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionAffinity) DeepCopyInto(out *SessionAffinity) {
+	*out = *in
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionAffinity.
+func (in *SessionAffinity) DeepCopy() *SessionAffinity {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionAffinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SyncSecret) DeepCopyInto(out *SyncSecret) {
 	*out = *in
@@ -839,6 +1492,111 @@ func (in *SyncSecretList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPHealthCheck) DeepCopyInto(out *TCPHealthCheck) {
+	*out = *in
+	if in.Receive != nil {
+		in, out := &in.Receive, &out.Receive
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPHealthCheck.
+func (in *TCPHealthCheck) DeepCopy() *TCPHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPKeepalive) DeepCopyInto(out *TCPKeepalive) {
+	*out = *in
+	if in.Probes != nil {
+		in, out := &in.Probes, &out.Probes
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPKeepalive.
+func (in *TCPKeepalive) DeepCopy() *TCPKeepalive {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPKeepalive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPSettings) DeepCopyInto(out *TCPSettings) {
+	*out = *in
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxConnectionDuration != nil {
+		in, out := &in.MaxConnectionDuration, &out.MaxConnectionDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ConnectTimeout != nil {
+		in, out := &in.ConnectTimeout, &out.ConnectTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Keepalive != nil {
+		in, out := &in.Keepalive, &out.Keepalive
+		*out = new(TCPKeepalive)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPSettings.
+func (in *TCPSettings) DeepCopy() *TCPSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSPassthrough) DeepCopyInto(out *TLSPassthrough) {
+	*out = *in
+	if in.Hostnames != nil {
+		in, out := &in.Hostnames, &out.Hostnames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSPassthrough.
+func (in *TLSPassthrough) DeepCopy() *TLSPassthrough {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSPassthrough)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Tenant) DeepCopyInto(out *Tenant) {
 	*out = *in
@@ -932,6 +1690,51 @@ func (in *TenantStatus) DeepCopy() *TenantStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UDPHealthCheck) DeepCopyInto(out *UDPHealthCheck) {
+	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UDPHealthCheck.
+func (in *UDPHealthCheck) DeepCopy() *UDPHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(UDPHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UDPSettings) DeepCopyInto(out *UDPSettings) {
+	*out = *in
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxBufferedDatagrams != nil {
+		in, out := &in.MaxBufferedDatagrams, &out.MaxBufferedDatagrams
+		*out = new(uint32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UDPSettings.
+func (in *UDPSettings) DeepCopy() *UDPSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(UDPSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamReferenceGrant) DeepCopyInto(out *UpstreamReferenceGrant) {
 	*out = *in
@@ -963,3 +1766,53 @@ func (in *UpstreamService) DeepCopy() *UpstreamService {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamTLS) DeepCopyInto(out *UpstreamTLS) {
+	*out = *in
+	if in.CACertificateRef != nil {
+		in, out := &in.CACertificateRef, &out.CACertificateRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.SubjectAltNames != nil {
+		in, out := &in.SubjectAltNames, &out.SubjectAltNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClientCertificateRef != nil {
+		in, out := &in.ClientCertificateRef, &out.ClientCertificateRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamTLS.
+func (in *UpstreamTLS) DeepCopy() *UpstreamTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneAwareRouting) DeepCopyInto(out *ZoneAwareRouting) {
+	*out = *in
+	if in.MinClusterSize != nil {
+		in, out := &in.MinClusterSize, &out.MinClusterSize
+		*out = new(uint64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneAwareRouting.
+func (in *ZoneAwareRouting) DeepCopy() *ZoneAwareRouting {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneAwareRouting)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -221,6 +221,7 @@ func main() {
 		KubeLBManager:        kubeLBMgr,
 		Log:                  ctrl.Log.WithName("kubelb.service.reconciler"),
 		Scheme:               mgr.GetScheme(),
+		Recorder:             mgr.GetEventRecorderFor(ccm.ServiceControllerName),
 		CloudController:      enableCloudController,
 		UseLoadbalancerClass: useLoadbalancerClass,
 		ClusterName:          clusterName,
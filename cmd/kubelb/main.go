@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 
@@ -26,13 +27,18 @@ import (
 	"k8c.io/kubelb/internal/config"
 	"k8c.io/kubelb/internal/controllers/kubelb"
 	"k8c.io/kubelb/internal/envoy"
+	kubelbutil "k8c.io/kubelb/internal/kubelb"
 	portlookup "k8c.io/kubelb/internal/port-lookup"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -42,7 +48,10 @@ import (
 type options struct {
 	metricsAddr                     string
 	envoyCPMetricsAddr              string
+	envoyDebugAddr                  string
 	envoyListenAddress              string
+	envoyXDSMode                    string
+	envoyStatsInterval              time.Duration
 	enableLeaderElection            bool
 	probeAddr                       string
 	kubeconfig                      string
@@ -66,8 +75,11 @@ func init() {
 func main() {
 	opt := &options{}
 	flag.StringVar(&opt.envoyListenAddress, "listen-address", ":8001", "Address to serve envoy control-plane on")
+	flag.StringVar(&opt.envoyXDSMode, "xds-mode", string(envoy.XDSModeDeltaADS), "The xDS protocol used by the envoy proxies to fetch their configuration. Valid values are delta-ads, ads and sotw.")
+	flag.DurationVar(&opt.envoyStatsInterval, "envoy-stats-interval", kubelb.DefaultEnvoyStatsInterval, "The interval at which the statistics of the envoy proxies are collected and reported in the status of the LoadBalancers.")
 	flag.StringVar(&opt.metricsAddr, "metrics-addr", ":9443", "The address the metric endpoint for the default controller manager binds to.")
 	flag.StringVar(&opt.envoyCPMetricsAddr, "envoy-cp-metrics-addr", ":9444", "The address the metric endpoint for the envoy control-plane manager binds to.")
+	flag.StringVar(&opt.envoyDebugAddr, "envoy-debug-addr", "0", "The address the authenticated debug endpoint for the snapshots of the envoy proxies binds to. Set to 0 to disable it.")
 	flag.StringVar(&opt.probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&opt.enableLeaderElection, "enable-leader-election", true,
 		"Enable leader election for controller kubelb. Enabling this will ensure there is only one active controller kubelb.")
@@ -124,13 +136,21 @@ func main() {
 		Scheme:         scheme,
 		Metrics:        metricsserver.Options{BindAddress: opt.envoyCPMetricsAddr},
 		LeaderElection: false,
+		Cache: cache.Options{
+			// Only the Envoy proxy pods are watched, to track their zones.
+			ByObject: map[ctrlruntimeclient.Object]cache.ByObject{
+				&corev1.Pod{}: {
+					Label: labels.SelectorFromSet(labels.Set{kubelbutil.LabelManagedBy: kubelbutil.LabelControllerName}),
+				},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start kubelb envoy cache manager")
 		os.Exit(1)
 	}
 
-	envoyServer, err := envoy.NewServer(opt.envoyListenAddress, opt.enableDebugMode)
+	envoyServer, err := envoy.NewServer(opt.envoyListenAddress, envoy.XDSMode(opt.envoyXDSMode), opt.enableDebugMode)
 	if err != nil {
 		setupLog.Error(err, "unable to create envoy server")
 		os.Exit(1)
@@ -178,19 +198,73 @@ func main() {
 		os.Exit(1)
 	}
 
+	snapshotHistory := envoy.NewSnapshotHistory()
+	envoyServer.Tracker.OnAck(snapshotHistory.Ack)
 	if err = (&kubelb.EnvoyCPReconciler{
 		Client:             envoyMgr.GetClient(),
 		EnvoyCache:         envoyServer.Cache,
 		EnvoyProxyTopology: kubelb.EnvoyProxyTopology(conf.GetEnvoyProxyTopology()),
 		PortAllocator:      portAllocator,
 		Namespace:          opt.namespace,
-		EnvoyBootstrap:     envoyServer.GenerateBootstrap(),
+		EnvoyBootstrap:     envoyServer.GenerateBootstrap(conf.Spec.EnvoyProxy.IPFamilies, conf.Spec.EnvoyProxy.ExposeClusterStatus),
 		DisableGatewayAPI:  disableGatewayAPI,
+		SnapshotHistory:    snapshotHistory,
+		Recorder:           envoyMgr.GetEventRecorderFor(kubelb.EnvoyCPControllerName),
 	}).SetupWithManager(ctx, envoyMgr); err != nil {
 		setupLog.Error(err, "unable to create envoy control-plane controller", "controller", "LoadBalancer")
 		os.Exit(1)
 	}
 
+	// The publisher runs next to the xDS server since it only knows the Envoy proxies that are connected to this replica.
+	replica, err := os.Hostname()
+	if err != nil {
+		setupLog.Error(err, "unable to determine the name of the replica")
+		os.Exit(1)
+	}
+	if err := envoyMgr.Add(&kubelb.EnvoyConfigStatusPublisher{
+		Client:    envoyMgr.GetClient(),
+		APIReader: envoyMgr.GetAPIReader(),
+		Tracker:   envoyServer.Tracker,
+		Namespace: opt.namespace,
+		Replica:   replica,
+	}); err != nil {
+		setupLog.Error(err, "unable to create envoy config status publisher")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(&kubelb.EnvoyConfigStatusReporter{
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Recorder:  mgr.GetEventRecorderFor(kubelb.EnvoyConfigStatusReporterName),
+		Namespace: opt.namespace,
+	}); err != nil {
+		setupLog.Error(err, "unable to create envoy config status reporter")
+		os.Exit(1)
+	}
+
+	if opt.envoyDebugAddr != "0" {
+		debugServer, err := kubelb.NewEnvoyDebugServer(envoyMgr, opt.envoyDebugAddr,
+			envoy.NewDebugHandler(envoyMgr.GetClient(), envoyServer.Cache, envoyServer.Tracker, snapshotHistory, portAllocator))
+		if err != nil {
+			setupLog.Error(err, "unable to create envoy debug server")
+			os.Exit(1)
+		}
+		if err := envoyMgr.Add(debugServer); err != nil {
+			setupLog.Error(err, "failed to register envoy debug server")
+			os.Exit(1)
+		}
+	}
+
+	if err := mgr.Add(&kubelb.EnvoyStatsCollector{
+		Client:              mgr.GetClient(),
+		APIReader:           mgr.GetAPIReader(),
+		Interval:            opt.envoyStatsInterval,
+		ScrapeClusterStatus: conf.Spec.EnvoyProxy.ExposeClusterStatus,
+	}); err != nil {
+		setupLog.Error(err, "unable to create envoy stats collector")
+		os.Exit(1)
+	}
+
 	if err = (&kubelb.RouteReconciler{
 		Client:             mgr.GetClient(),
 		Scheme:             mgr.GetScheme(),
@@ -92,35 +92,31 @@ func (r *KubeLBNodeReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 }
 
 func (r *KubeLBNodeReconciler) GenerateAddresses(nodes *corev1.NodeList) *kubelbiov1alpha1.Addresses {
-	endpoints := r.getEndpoints(nodes)
-	var addresses []kubelbiov1alpha1.EndpointAddress
-	for _, endpoint := range endpoints {
-		addresses = append(addresses, kubelbiov1alpha1.EndpointAddress{
-			IP: endpoint,
-		})
-	}
-
 	return &kubelbiov1alpha1.Addresses{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      kubelbiov1alpha1.DefaultAddressName,
 			Namespace: r.ClusterName,
 		},
 		Spec: kubelbiov1alpha1.AddressesSpec{
-			Addresses: addresses,
+			Addresses: r.getEndpoints(nodes),
 		},
 	}
 }
 
-func (r *KubeLBNodeReconciler) getEndpoints(nodes *corev1.NodeList) []string {
-	var clusterEndpoints []string
+// getEndpoints returns the addresses of the nodes along with their zone and region, these are used by the Envoy proxies for zone-aware routing.
+func (r *KubeLBNodeReconciler) getEndpoints(nodes *corev1.NodeList) []kubelbiov1alpha1.EndpointAddress {
+	var clusterEndpoints []kubelbiov1alpha1.EndpointAddress
 	for _, node := range nodes.Items {
-		var internalIP string
+		// Dual-stack nodes report one address per IP family.
 		for _, address := range node.Status.Addresses {
-			if address.Type == r.EndpointAddressType {
-				internalIP = address.Address
+			if address.Type == r.EndpointAddressType && address.Address != "" {
+				clusterEndpoints = append(clusterEndpoints, kubelbiov1alpha1.EndpointAddress{
+					IP:     address.Address,
+					Zone:   node.Labels[corev1.LabelTopologyZone],
+					Region: node.Labels[corev1.LabelTopologyRegion],
+				})
 			}
 		}
-		clusterEndpoints = append(clusterEndpoints, internalIP)
 	}
 	return clusterEndpoints
 }
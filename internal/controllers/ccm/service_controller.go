@@ -32,6 +32,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -44,6 +45,7 @@ import (
 const (
 	LBFinalizerName       = "kubelb.k8c.io/lb-finalizer"
 	LoadBalancerClassName = "kubelb"
+	ServiceControllerName = "service-controller"
 )
 
 // KubeLBServiceReconciler reconciles a Service object
@@ -53,6 +55,7 @@ type KubeLBServiceReconciler struct {
 	KubeLBManager        ctrl.Manager
 	Log                  logr.Logger
 	Scheme               *runtime.Scheme
+	Recorder             record.EventRecorder
 	ClusterName          string
 	CloudController      bool
 	UseLoadbalancerClass bool
@@ -60,6 +63,7 @@ type KubeLBServiceReconciler struct {
 
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups="",resources=services/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *KubeLBServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("name", req.Name, "namespace", req.Namespace)
@@ -108,6 +112,10 @@ func (r *KubeLBServiceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	clusterEndpoints, useAddressesReference := r.getEndpoints(&service)
 	log.V(5).Info("proceeding with", "endpoints", clusterEndpoints)
 
+	for _, err := range kubelb.ValidateLoadBalancerAnnotations(&service) {
+		r.Recorder.Event(&service, corev1.EventTypeWarning, "InvalidAnnotation", err.Error())
+	}
+
 	desiredLB := kubelb.MapLoadBalancer(&service, clusterEndpoints, useAddressesReference, r.ClusterName)
 	log.V(6).Info("desired", "LoadBalancer", desiredLB)
 
@@ -127,6 +135,9 @@ func (r *KubeLBServiceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, kubelbClient.Create(ctx, desiredLB)
 	}
 
+	// The settings that are managed in the LB cluster would otherwise be reset with every update.
+	kubelb.PreserveLoadBalancerSettings(&actualLB, desiredLB)
+
 	log.V(6).Info("load balancer status", "LoadBalancer", actualLB.Status.LoadBalancer.Ingress, "service", service.Status.LoadBalancer.Ingress)
 
 	if service.Spec.Type == corev1.ServiceTypeLoadBalancer && !reflect.DeepEqual(actualLB.Status.LoadBalancer.Ingress, service.Status.LoadBalancer.Ingress) {
@@ -226,16 +237,17 @@ func (r *KubeLBServiceReconciler) enqueueLoadBalancer() handler.TypedMapFunc[*ku
 	})
 }
 
-func (r *KubeLBServiceReconciler) getEndpoints(service *corev1.Service) ([]string, bool) {
-	var clusterEndpoints []string
+func (r *KubeLBServiceReconciler) getEndpoints(service *corev1.Service) ([]kubelbv1alpha1.EndpointAddress, bool) {
+	var clusterEndpoints []kubelbv1alpha1.EndpointAddress
 
 	// Use LB Endpoint if there is any non KubeLb load balancer implementation
 	if service.Spec.Type == corev1.ServiceTypeLoadBalancer && !r.CloudController {
 		for _, lbIngress := range service.Status.LoadBalancer.Ingress {
+			// Some load balancers, such as AWS ELB, only expose a hostname that is resolved by Envoy.
 			if lbIngress.IP != "" {
-				clusterEndpoints = append(clusterEndpoints, lbIngress.IP)
-			} else {
-				clusterEndpoints = append(clusterEndpoints, lbIngress.Hostname)
+				clusterEndpoints = append(clusterEndpoints, kubelbv1alpha1.EndpointAddress{IP: lbIngress.IP})
+			} else if lbIngress.Hostname != "" {
+				clusterEndpoints = append(clusterEndpoints, kubelbv1alpha1.EndpointAddress{Hostname: lbIngress.Hostname})
 			}
 		}
 	} else {
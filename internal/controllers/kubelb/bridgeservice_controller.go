@@ -19,6 +19,7 @@ package kubelb
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/go-logr/logr"
 
@@ -93,29 +94,6 @@ func (r *BridgeServiceReconciler) reconcile(ctx context.Context, _ logr.Logger,
 		return fmt.Errorf("failed to get original service: %w", err)
 	}
 
-	endpointSlice := &discoveryv1.EndpointSlice{
-		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
-	}
-	endpointSlice.Labels = make(map[string]string)
-	endpointSlice.Labels = kubelb.AddKubeLBLabels(endpointSlice.Labels, name, namespace, "Service")
-	endpointSlice.Labels[discoveryv1.LabelServiceName] = name
-	endpointSlice.AddressType = discoveryv1.AddressTypeIPv4
-	for _, port := range originalService.Spec.Ports {
-		endpointSlice.Ports = append(endpointSlice.Ports, discoveryv1.EndpointPort{
-			Name:     ptr.To(port.Name),
-			Port:     ptr.To(port.TargetPort.IntVal),
-			Protocol: ptr.To(port.Protocol),
-		})
-	}
-	endpointSlice.Endpoints = []discoveryv1.Endpoint{
-		{
-			Addresses: object.Spec.ClusterIPs,
-			Conditions: discoveryv1.EndpointConditions{
-				Ready: ptr.To(true),
-			},
-		},
-	}
-
 	ownerReference := metav1.OwnerReference{
 		APIVersion: originalService.APIVersion,
 		Kind:       originalService.Kind,
@@ -124,10 +102,72 @@ func (r *BridgeServiceReconciler) reconcile(ctx context.Context, _ logr.Logger,
 		Controller: ptr.To(true),
 	}
 
-	// Set owner reference for the resource.
-	endpointSlice.SetOwnerReferences([]metav1.OwnerReference{ownerReference})
+	// An EndpointSlice can only contain addresses of a single IP family, so one EndpointSlice is generated per cluster IP of the bridge
+	// service. The cluster IPs are ordered in the same way as the IP families.
+	for i, clusterIP := range object.Spec.ClusterIPs {
+		if i >= len(object.Spec.IPFamilies) {
+			break
+		}
+		family := object.Spec.IPFamilies[i]
+
+		endpointSlice := &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{Name: getBridgeEndpointSliceName(name, i, family), Namespace: namespace},
+		}
+		endpointSlice.Labels = make(map[string]string)
+		endpointSlice.Labels = kubelb.AddKubeLBLabels(endpointSlice.Labels, name, namespace, "Service")
+		endpointSlice.Labels[discoveryv1.LabelServiceName] = name
+		endpointSlice.AddressType = discoveryv1.AddressTypeIPv4
+		if family == corev1.IPv6Protocol {
+			endpointSlice.AddressType = discoveryv1.AddressTypeIPv6
+		}
+		for _, port := range originalService.Spec.Ports {
+			endpointSlice.Ports = append(endpointSlice.Ports, discoveryv1.EndpointPort{
+				Name:     ptr.To(port.Name),
+				Port:     ptr.To(port.TargetPort.IntVal),
+				Protocol: ptr.To(port.Protocol),
+			})
+		}
+		endpointSlice.Endpoints = []discoveryv1.Endpoint{
+			{
+				Addresses: []string{clusterIP},
+				Conditions: discoveryv1.EndpointConditions{
+					Ready: ptr.To(true),
+				},
+			},
+		}
+
+		// Set owner reference for the resource.
+		endpointSlice.SetOwnerReferences([]metav1.OwnerReference{ownerReference})
+
+		if err := CreateOrUpdateEndpointSlice(ctx, r.Client, endpointSlice); err != nil {
+			return err
+		}
+	}
+
+	// Remove the EndpointSlice for the secondary IP family if the bridge service is no longer dual-stack.
+	if len(object.Spec.IPFamilies) == 1 {
+		for _, family := range []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol} {
+			if family == object.Spec.IPFamilies[0] {
+				continue
+			}
+			endpointSlice := &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{Name: getBridgeEndpointSliceName(name, 1, family), Namespace: namespace},
+			}
+			if err := r.Delete(ctx, endpointSlice); err != nil && !kerrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete EndpointSlice: %w", err)
+			}
+		}
+	}
+	return nil
+}
 
-	return CreateOrUpdateEndpointSlice(ctx, r.Client, endpointSlice)
+// getBridgeEndpointSliceName returns the name of the EndpointSlice for an IP family of the bridge service. The EndpointSlice for the primary
+// IP family uses the name of the service.
+func getBridgeEndpointSliceName(name string, index int, family corev1.IPFamily) string {
+	if index == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", name, strings.ToLower(string(family)))
 }
 
 func CreateOrUpdateEndpointSlice(ctx context.Context, client ctrlclient.Client, obj *discoveryv1.EndpointSlice) error {
@@ -0,0 +1,354 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+	envoycp "k8c.io/kubelb/internal/envoy"
+	"k8c.io/kubelb/internal/kubelb"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	EnvoyConfigStatusReporterName  = "envoy-config-status-reporter"
+	EnvoyConfigStatusPublisherName = "envoy-config-status-publisher"
+	envoyConfigStatusPublishPeriod = 30 * time.Second
+	// envoyConfigStatusReportPeriod is shorter than the publish period since the reporter only learns about the changes of the other
+	// replicas by polling.
+	envoyConfigStatusReportPeriod = 10 * time.Second
+	// envoyConfigStatusExpiration is the age after which the states of a replica are discarded, the replica is assumed to be gone.
+	envoyConfigStatusExpiration = 3 * envoyConfigStatusPublishPeriod
+	// envoyConfigStatusPrefix is the prefix of the ConfigMaps that contain the states of the Envoy proxies of each replica.
+	envoyConfigStatusPrefix = "envoy-config-status-"
+	// LabelEnvoyConfigStatus marks the ConfigMaps that contain the states of the Envoy proxies of a replica.
+	LabelEnvoyConfigStatus = "kubelb.k8c.io/envoy-config-status"
+	envoyConfigStatesKey   = "states"
+	envoyConfigUpdatedKey  = "updatedAt"
+	// maxProxyConfigRejectedMessageLength limits the length of the condition message, the error details of Envoy can be large if many
+	// resources were rejected.
+	maxProxyConfigRejectedMessageLength = 1024
+)
+
+// EnvoyConfigStatusPublisher publishes the configuration states of the Envoy proxies that are connected to this replica of the control
+// plane in a ConfigMap, so that the leader can report them. It runs on every replica since the Envoy proxies can be connected to any
+// replica.
+type EnvoyConfigStatusPublisher struct {
+	ctrlruntimeclient.Client
+	// APIReader is used to get the ConfigMap of the replica, this avoids caching all the ConfigMaps of the cluster.
+	APIReader ctrlruntimeclient.Reader
+	Tracker   *envoycp.ConfigTracker
+	Namespace string
+	// Replica is the unique name of this replica, e.g. the name of the pod.
+	Replica string
+}
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
+
+func (p *EnvoyConfigStatusPublisher) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName(EnvoyConfigStatusPublisherName)
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	// The ConfigMap is also updated periodically, the leader discards the states of replicas that stopped updating it.
+	ticker := time.NewTicker(envoyConfigStatusPublishPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := p.publish(ctx); err != nil {
+			log.Error(err, "failed to publish Envoy proxy configuration states")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-p.Tracker.Changes():
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection is false since the Envoy proxies can be connected to any replica.
+func (p *EnvoyConfigStatusPublisher) NeedLeaderElection() bool {
+	return false
+}
+
+func (p *EnvoyConfigStatusPublisher) publish(ctx context.Context) error {
+	states, err := json.Marshal(p.Tracker.States())
+	if err != nil {
+		return fmt.Errorf("failed to marshal states: %w", err)
+	}
+	data := map[string]string{
+		envoyConfigStatesKey:  string(states),
+		envoyConfigUpdatedKey: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = p.APIReader.Get(ctx, types.NamespacedName{Namespace: p.Namespace, Name: envoyConfigStatusPrefix + p.Replica}, configMap)
+	if kerrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      envoyConfigStatusPrefix + p.Replica,
+				Namespace: p.Namespace,
+				Labels:    map[string]string{LabelEnvoyConfigStatus: "true"},
+			},
+			Data: data,
+		}
+		return p.Create(ctx, configMap)
+	}
+	if err != nil {
+		return err
+	}
+	configMap.Data = data
+	return p.Update(ctx, configMap)
+}
+
+// EnvoyConfigStatusReporter reports the configurations that were rejected by the Envoy proxies in the conditions of the LoadBalancers and
+// the Routes. The rejected listeners and clusters are matched by their names, which are parsed from the error details of Envoy.
+//
+// Only the leader updates the conditions, it combines the states that were published by the EnvoyConfigStatusPublisher of each replica.
+type EnvoyConfigStatusReporter struct {
+	ctrlruntimeclient.Client
+	// APIReader is used to list the ConfigMaps of the replicas, this avoids caching all the ConfigMaps of the cluster.
+	APIReader ctrlruntimeclient.Reader
+	Recorder  record.EventRecorder
+	Namespace string
+
+	// rejected contains the LoadBalancers and Routes whose configuration was rejected by the Envoy proxies.
+	rejected map[rejectedObjectKey]bool
+}
+
+type rejectedObjectKey struct {
+	kind string
+	ctrlruntimeclient.ObjectKey
+}
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *EnvoyConfigStatusReporter) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName(EnvoyConfigStatusReporterName)
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.report(ctx); err != nil {
+			log.Error(err, "failed to report rejected Envoy proxy configurations")
+		}
+	}, envoyConfigStatusReportPeriod)
+	return nil
+}
+
+// NeedLeaderElection ensures that the conditions are only updated by a single replica.
+func (r *EnvoyConfigStatusReporter) NeedLeaderElection() bool {
+	return true
+}
+
+// getStates returns the configuration states that were published by the replicas. The ConfigMaps of the replicas that stopped publishing
+// are deleted.
+func (r *EnvoyConfigStatusReporter) getStates(ctx context.Context) ([]envoycp.ConfigState, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.APIReader.List(ctx, configMaps, ctrlruntimeclient.InNamespace(r.Namespace), ctrlruntimeclient.MatchingLabels{LabelEnvoyConfigStatus: "true"}); err != nil {
+		return nil, fmt.Errorf("failed to list ConfigMaps: %w", err)
+	}
+
+	var states []envoycp.ConfigState
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		updated, err := time.Parse(time.RFC3339, configMap.Data[envoyConfigUpdatedKey])
+		if err != nil || time.Since(updated) > envoyConfigStatusExpiration {
+			if err := r.Delete(ctx, configMap); ctrlruntimeclient.IgnoreNotFound(err) != nil {
+				log.Error(err, "failed to delete expired Envoy proxy configuration states", "name", configMap.Name)
+			}
+			continue
+		}
+
+		var replicaStates []envoycp.ConfigState
+		if err := json.Unmarshal([]byte(configMap.Data[envoyConfigStatesKey]), &replicaStates); err != nil {
+			log.Error(err, "failed to unmarshal Envoy proxy configuration states", "name", configMap.Name)
+			continue
+		}
+		states = append(states, replicaStates...)
+	}
+	return states, nil
+}
+
+func (r *EnvoyConfigStatusReporter) report(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	states, err := r.getStates(ctx)
+	if err != nil {
+		return err
+	}
+	var rejections []envoycp.ConfigState
+	for _, state := range states {
+		if state.Rejection != nil {
+			rejections = append(rejections, state)
+		}
+	}
+
+	lbs := &kubelbv1alpha1.LoadBalancerList{}
+	if err := r.List(ctx, lbs); err != nil {
+		return fmt.Errorf("failed to list LoadBalancers: %w", err)
+	}
+	routes := &kubelbv1alpha1.RouteList{}
+	if err := r.List(ctx, routes); err != nil {
+		return fmt.Errorf("failed to list Routes: %w", err)
+	}
+
+	// Conditions that were set before a restart are cleared unless the rejection is observed again.
+	initialize := r.rejected == nil
+	if initialize {
+		r.rejected = make(map[rejectedObjectKey]bool)
+	}
+
+	for i := range lbs.Items {
+		lb := &lbs.Items[i]
+		if err := r.reportObject(ctx, lb, &lb.Status.Conditions, getLoadBalancerResourceNames(lb), rejections, initialize); err != nil {
+			log.Error(err, "failed to update LoadBalancer conditions", "namespace", lb.Namespace, "name", lb.Name)
+		}
+	}
+	for i := range routes.Items {
+		route := &routes.Items[i]
+		if err := r.reportObject(ctx, route, &route.Status.Conditions, envoycp.RouteResourceNames(route), rejections, initialize); err != nil {
+			log.Error(err, "failed to update Route conditions", "namespace", route.Namespace, "name", route.Name)
+		}
+	}
+	return nil
+}
+
+// reportObject updates the ProxyConfigRejected condition of a LoadBalancer or a Route. An event is recorded whenever the configuration
+// is rejected or accepted again.
+func (r *EnvoyConfigStatusReporter) reportObject(ctx context.Context, obj ctrlruntimeclient.Object, conditions *[]metav1.Condition, resourceNames []string,
+	rejections []envoycp.ConfigState, initialize bool) error {
+	key := rejectedObjectKey{kind: fmt.Sprintf("%T", obj), ObjectKey: ctrlruntimeclient.ObjectKeyFromObject(obj)}
+	existing := meta.FindStatusCondition(*conditions, kubelbv1alpha1.ConditionProxyConfigRejected.String())
+	if initialize && existing != nil && existing.Status == metav1.ConditionTrue {
+		r.rejected[key] = true
+	}
+
+	message := getProxyConfigRejectedMessage(resourceNames, rejections)
+	if message != "" {
+		condition := metav1.Condition{
+			Type:               kubelbv1alpha1.ConditionProxyConfigRejected.String(),
+			Status:             metav1.ConditionTrue,
+			Reason:             kubelbv1alpha1.ReasonProxyConfigRejected,
+			Message:            message,
+			ObservedGeneration: obj.GetGeneration(),
+		}
+		if err := r.setCondition(ctx, obj, condition); err != nil {
+			return err
+		}
+		if !r.rejected[key] {
+			r.Recorder.Event(obj, corev1.EventTypeWarning, kubelbv1alpha1.ConditionProxyConfigRejected.String(), message)
+			r.rejected[key] = true
+		}
+		return nil
+	}
+
+	if !r.rejected[key] {
+		return nil
+	}
+	condition := metav1.Condition{
+		Type:               kubelbv1alpha1.ConditionProxyConfigRejected.String(),
+		Status:             metav1.ConditionFalse,
+		Reason:             kubelbv1alpha1.ReasonProxyConfigAccepted,
+		Message:            "The configuration has been accepted by the Envoy proxies",
+		ObservedGeneration: obj.GetGeneration(),
+	}
+	if err := r.setCondition(ctx, obj, condition); err != nil {
+		return err
+	}
+	r.Recorder.Event(obj, corev1.EventTypeNormal, "ProxyConfigAccepted", condition.Message)
+	delete(r.rejected, key)
+	return nil
+}
+
+func (r *EnvoyConfigStatusReporter) setCondition(ctx context.Context, obj ctrlruntimeclient.Object, condition metav1.Condition) error {
+	return PatchStatusConditions(ctx, r.Client, obj, func(conditions *[]metav1.Condition) bool {
+		return meta.SetStatusCondition(conditions, condition)
+	})
+}
+
+// getProxyConfigRejectedMessage returns the error details of the rejections that refer to any of the resources, or an empty string if
+// none of the resources were rejected. The rejections can be reported by several replicas for the same node.
+func getProxyConfigRejectedMessage(resourceNames []string, rejections []envoycp.ConfigState) string {
+	names := sets.New(resourceNames...)
+	// The number of rejected proxies is summed across the replicas per type, an Envoy proxy that rejected several types is counted once.
+	proxies := make(map[string]map[string]int)
+	messages := make(map[string]bool)
+	for _, rejection := range rejections {
+		if !names.HasAny(rejection.Rejection.Resources...) {
+			continue
+		}
+		if proxies[rejection.Node] == nil {
+			proxies[rejection.Node] = make(map[string]int)
+		}
+		proxies[rejection.Node][rejection.TypeURL] += rejection.RejectedProxies
+		messages[rejection.Rejection.Message] = true
+	}
+	if len(messages) == 0 {
+		return ""
+	}
+
+	rejected := 0
+	for _, counts := range proxies {
+		nodeRejected := 0
+		for _, count := range counts {
+			nodeRejected = max(nodeRejected, count)
+		}
+		rejected += nodeRejected
+	}
+
+	details := make([]string, 0, len(messages))
+	for detail := range messages {
+		details = append(details, detail)
+	}
+	sort.Strings(details)
+
+	message := fmt.Sprintf("The configuration was rejected by %d Envoy proxies: %s", rejected, strings.Join(details, "; "))
+	if len(message) > maxProxyConfigRejectedMessageLength {
+		message = message[:maxProxyConfigRejectedMessageLength-3] + "..."
+	}
+	return message
+}
+
+// getLoadBalancerResourceNames returns the names of the listeners and clusters of a LoadBalancer. The listeners of the ports with TLS
+// passthrough are shared with other LoadBalancers.
+func getLoadBalancerResourceNames(lb *kubelbv1alpha1.LoadBalancer) []string {
+	names := envoycp.LoadBalancerResourceNames(lb)
+	for p, port := range lb.Spec.Ports {
+		if kubelb.UsesTLSPassthrough(lb, p) {
+			names = append(names, fmt.Sprintf(kubelb.EnvoyTLSPassthroughListenerPattern, port.Port))
+		}
+	}
+	return names
+}
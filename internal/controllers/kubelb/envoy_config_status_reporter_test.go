@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"testing"
+
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+
+	envoycp "k8c.io/kubelb/internal/envoy"
+)
+
+func newConfigRejection(node, typeURL string, rejectedProxies int, resources ...string) envoycp.ConfigState {
+	return envoycp.ConfigState{
+		Node:            node,
+		TypeURL:         typeURL,
+		RejectedProxies: rejectedProxies,
+		Rejection:       &envoycp.ConfigRejection{Message: "rejected " + typeURL, Resources: resources},
+	}
+}
+
+func TestGetProxyConfigRejectedMessage(t *testing.T) {
+	resourceNames := []string{"tenant-test-test-ep-0-port-80-TCP", "tls-passthrough-443"}
+
+	testCases := []struct {
+		name       string
+		rejections []envoycp.ConfigState
+		expected   string
+	}{
+		{
+			name: "no rejections",
+		},
+		{
+			name:       "resource with the same prefix doesn't match",
+			rejections: []envoycp.ConfigState{newConfigRejection("tenant-test", resource.ListenerType, 1, "tenant-test-test-ep-0-port-8080-TCP")},
+		},
+		{
+			name:       "shared TLS passthrough listener",
+			rejections: []envoycp.ConfigState{newConfigRejection("tenant-test", resource.ListenerType, 1, "tls-passthrough-443")},
+			expected:   "The configuration was rejected by 1 Envoy proxies: rejected " + resource.ListenerType,
+		},
+		{
+			name: "rejections of several replicas are summed",
+			rejections: []envoycp.ConfigState{
+				newConfigRejection("tenant-test", resource.ListenerType, 1, "tenant-test-test-ep-0-port-80-TCP"),
+				newConfigRejection("tenant-test", resource.ListenerType, 2, "tenant-test-test-ep-0-port-80-TCP"),
+			},
+			expected: "The configuration was rejected by 3 Envoy proxies: rejected " + resource.ListenerType,
+		},
+		{
+			name: "proxies that rejected several types are counted once",
+			rejections: []envoycp.ConfigState{
+				newConfigRejection("tenant-test", resource.ClusterType, 2, "tenant-test-test-ep-0-port-80-TCP"),
+				newConfigRejection("tenant-test", resource.ListenerType, 2, "tenant-test-test-ep-0-port-80-TCP"),
+			},
+			expected: "The configuration was rejected by 2 Envoy proxies: rejected " + resource.ClusterType + "; rejected " + resource.ListenerType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if message := getProxyConfigRejectedMessage(resourceNames, tc.rejections); message != tc.expected {
+				t.Errorf("expected message %q, got %q", tc.expected, message)
+			}
+		})
+	}
+}
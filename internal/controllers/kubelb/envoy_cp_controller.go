@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 
 	envoycachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	envoyresource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
@@ -33,22 +34,26 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
-	RequeueAllResources = "requeue-all-for-route"
+	EnvoyCPControllerName = "envoy-cp-controller"
+	RequeueAllResources   = "requeue-all-for-route"
 )
 
 type EnvoyCPReconciler struct {
@@ -60,10 +65,18 @@ type EnvoyCPReconciler struct {
 	EnvoyBootstrap     string
 	DisableGatewayAPI  bool
 	Config             *kubelbv1alpha1.Config
+	// SnapshotHistory keeps the last good snapshots, they can be served again by pinning a snapshot version.
+	SnapshotHistory *envoycp.SnapshotHistory
+	Recorder        record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=kubelb.k8c.io,resources=loadbalancers,verbs=get;list;watch
-// +kubebuilder:rbac:groups=kubelb.k8c.io,resources=loadbalancers/status,verbs=get
+// +kubebuilder:rbac:groups=kubelb.k8c.io,resources=loadbalancers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kubelb.k8c.io,resources=routes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kubelb.k8c.io,resources=syncsecrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
 func (r *EnvoyCPReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 
@@ -94,6 +107,7 @@ func (r *EnvoyCPReconciler) reconcile(ctx context.Context, req ctrl.Request) err
 
 	if len(lbs) == 0 && len(routes) == 0 {
 		r.EnvoyCache.ClearSnapshot(snapshotName)
+		r.SnapshotHistory.Remove(snapshotName)
 		return r.cleanupEnvoyProxy(ctx, appName, namespace)
 	}
 
@@ -115,40 +129,235 @@ func (r *EnvoyCPReconciler) reconcile(ctx context.Context, req ctrl.Request) err
 		return err
 	}
 
-	return r.updateCache(ctx, snapshotName, lbs, routes)
+	tenants, err := r.getTenants(ctx, lbs, routes)
+	if err != nil {
+		return fmt.Errorf("failed to get tenants: %w", err)
+	}
+	r.applyDefaults(lbs, routes, tenants)
+
+	proxies, err := r.getEnvoyProxies(ctx, namespace, appName)
+	if err != nil {
+		return fmt.Errorf("failed to list Envoy proxy pods: %w", err)
+	}
+
+	pinnedVersion, pinnedBy := r.getPinnedSnapshotVersion(req.Namespace, tenants)
+	return r.updateCache(ctx, snapshotName, lbs, routes, tenants, proxies, pinnedVersion, pinnedBy)
+}
+
+// getEnvoyProxies returns the addresses of the running Envoy proxy pods along with their zone and region. They are used by the proxies
+// to compare their own zone with the zones of the endpoints for zone-aware routing.
+func (r *EnvoyCPReconciler) getEnvoyProxies(ctx context.Context, namespace, appName string) ([]kubelbv1alpha1.EndpointAddress, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels{kubelb.LabelAppKubernetesName: appName}); err != nil {
+		return nil, err
+	}
+
+	// The order of the pods is not stable, the snapshot would change with every reconciliation.
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+
+	var proxies []kubelbv1alpha1.EndpointAddress
+	for _, pod := range pods.Items {
+		if !isRunningEnvoyProxyPod(&pod) {
+			continue
+		}
+		proxies = append(proxies, kubelbv1alpha1.EndpointAddress{
+			IP:     pod.Status.PodIP,
+			Zone:   pod.Labels[corev1.LabelTopologyZone],
+			Region: pod.Labels[corev1.LabelTopologyRegion],
+		})
+	}
+	return proxies, nil
+}
+
+func isRunningEnvoyProxyPod(pod *corev1.Pod) bool {
+	return pod.DeletionTimestamp.IsZero() && pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != ""
+}
+
+// getTenants returns the Tenants of the LoadBalancers and Routes keyed by the namespace of the tenant. Tenant level settings are optional,
+// an empty Tenant is returned if the Tenant doesn't exist.
+func (r *EnvoyCPReconciler) getTenants(ctx context.Context, lbs []kubelbv1alpha1.LoadBalancer, routes []kubelbv1alpha1.Route) (map[string]*kubelbv1alpha1.Tenant, error) {
+	var namespaces []string
+	for _, lb := range lbs {
+		namespaces = append(namespaces, lb.Namespace)
+	}
+	for _, route := range routes {
+		namespaces = append(namespaces, route.Namespace)
+	}
+
+	tenants := make(map[string]*kubelbv1alpha1.Tenant)
+	for _, namespace := range namespaces {
+		if _, ok := tenants[namespace]; ok {
+			continue
+		}
+		tenant, err := GetTenant(ctx, r.Client, RemoveTenantPrefix(namespace))
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			tenant = &kubelbv1alpha1.Tenant{}
+		}
+		tenants[namespace] = tenant
+	}
+	return tenants, nil
+}
+
+// applyDefaults resolves the defaults configured at the Tenant and Config level into the LoadBalancers and Routes. Values specified on the
+// LoadBalancer or Route have the highest precedence, followed by the Tenant and then the Config.
+func (r *EnvoyCPReconciler) applyDefaults(lbs []kubelbv1alpha1.LoadBalancer, routes []kubelbv1alpha1.Route, tenants map[string]*kubelbv1alpha1.Tenant) {
+	for i := range lbs {
+		tenant := tenants[lbs[i].Namespace]
+		if lbs[i].Spec.LoadBalancingPolicy == nil {
+			lbs[i].Spec.LoadBalancingPolicy = GetLoadBalancingPolicy(tenant, r.Config)
+		}
+		lbs[i].Spec.ProxyProtocol = GetProxyProtocol(lbs[i].Spec.ProxyProtocol, tenant, r.Config)
+		if lbs[i].Spec.OutlierDetection == nil {
+			lbs[i].Spec.OutlierDetection = GetOutlierDetection(tenant, r.Config)
+		}
+		lbs[i].Spec.CircuitBreakers = GetCircuitBreakers(lbs[i].Spec.CircuitBreakers, tenant, r.Config)
+		lbs[i].Spec.TCP = GetTCPSettings(lbs[i].Spec.TCP, tenant, r.Config)
+		for j := range lbs[i].Spec.Endpoints {
+			lbs[i].Spec.Endpoints[j].DNS = GetDNSSettings(lbs[i].Spec.Endpoints[j].DNS, tenant, r.Config)
+		}
+		for p := range lbs[i].Spec.Ports {
+			if lbs[i].Spec.Ports[p].Protocol != corev1.ProtocolUDP {
+				lbs[i].Spec.Ports[p].ConnectionRateLimit = GetConnectionRateLimit(lbs[i].Spec.Ports[p].ConnectionRateLimit, tenant, r.Config)
+			}
+		}
+	}
+
+	for i := range routes {
+		if routes[i].Spec.Source.Kubernetes == nil {
+			continue
+		}
+		tenant := tenants[routes[i].Namespace]
+		for j := range routes[i].Spec.Endpoints {
+			routes[i].Spec.Endpoints[j].DNS = GetDNSSettings(routes[i].Spec.Endpoints[j].DNS, tenant, r.Config)
+		}
+		for _, svc := range routes[i].Spec.Source.Kubernetes.Services {
+			key := fmt.Sprintf(kubelb.RouteServiceMapKey, kubelb.GetNamespace(&svc.Service), kubelb.GetName(&svc.Service))
+			settings := routes[i].Spec.ServiceSettings[key]
+			settings.CircuitBreakers = GetCircuitBreakers(settings.CircuitBreakers, tenant, r.Config)
+			if settings.CircuitBreakers == nil {
+				continue
+			}
+			if routes[i].Spec.ServiceSettings == nil {
+				routes[i].Spec.ServiceSettings = make(map[string]kubelbv1alpha1.RouteServiceSettings)
+			}
+			routes[i].Spec.ServiceSettings[key] = settings
+		}
+	}
 }
 
-func (r *EnvoyCPReconciler) updateCache(ctx context.Context, snapshotName string, lbs []kubelbv1alpha1.LoadBalancer, routes []kubelbv1alpha1.Route) error {
+func (r *EnvoyCPReconciler) updateCache(ctx context.Context, snapshotName string, lbs []kubelbv1alpha1.LoadBalancer, routes []kubelbv1alpha1.Route,
+	tenants map[string]*kubelbv1alpha1.Tenant, proxies []kubelbv1alpha1.EndpointAddress, pinnedVersion string, pinnedBy ctrlruntimeclient.Object) error {
 	log := ctrl.LoggerFrom(ctx)
-	desiredSnapshot, err := envoycp.MapSnapshot(ctx, r.Client, lbs, routes, r.PortAllocator, r.EnvoyProxyTopology == EnvoyProxyTopologyGlobal)
+	// The current snapshot is missing until the first snapshot is set.
+	currentSnapshot, _ := r.EnvoyCache.GetSnapshot(snapshotName)
+	settings := envoycp.SnapshotSettings{
+		Config:   r.Config,
+		Tenants:  tenants,
+		Proxies:  proxies,
+		Previous: currentSnapshot,
+	}
+	desiredSnapshot, skipped, err := envoycp.MapSnapshot(ctx, r.Client, lbs, routes, r.PortAllocator, r.EnvoyProxyTopology == EnvoyProxyTopologyGlobal, settings)
 	if err != nil {
+		// The last good snapshot is kept until the configuration can be generated again.
 		return err
 	}
+	r.reportSkippedObjects(ctx, lbs, routes, skipped)
 
-	currentSnapshot, err := r.EnvoyCache.GetSnapshot(snapshotName)
-	if err != nil {
-		log.Info("init snapshot", "service-node", snapshotName, "version", desiredSnapshot.GetVersion(envoyresource.ClusterType))
-		return r.EnvoyCache.SetSnapshot(ctx, snapshotName, desiredSnapshot)
+	if err := envoycp.CheckSnapshotConsistency(desiredSnapshot); err != nil {
+		return fmt.Errorf("new Envoy config snapshot is not consistent: %w", err)
+	}
+
+	r.SnapshotHistory.SetPinnedVersion(snapshotName, pinnedVersion)
+	if pinnedVersion != "" {
+		pinnedSnapshot, ok := r.SnapshotHistory.Get(snapshotName, pinnedVersion)
+		if !ok {
+			// Serving another version than the pinned one would defeat the pin, the Envoy proxies keep their current configuration.
+			versions := r.SnapshotHistory.Versions(snapshotName)
+			log.Error(fmt.Errorf("unknown snapshot version %q", pinnedVersion), "not updating the snapshot", "service-node", snapshotName, "available-versions", versions)
+			r.Recorder.Eventf(pinnedBy, corev1.EventTypeWarning, "UnknownSnapshotVersion",
+				"Snapshot version %q of the Envoy proxies %q is unknown, their configuration is not updated until the version is known or the annotation %s is removed. Available versions: %v",
+				pinnedVersion, snapshotName, kubelbv1alpha1.EnvoySnapshotVersionAnnotation, versions)
+			return nil
+		}
+		desiredSnapshot = pinnedSnapshot
 	}
 
-	lastUsedVersion := currentSnapshot.GetVersion(envoyresource.ClusterType)
-	desiredVersion := desiredSnapshot.GetVersion(envoyresource.ClusterType)
-	if lastUsedVersion == desiredVersion {
+	switch {
+	case currentSnapshot == nil:
+		log.Info("init snapshot", "service-node", snapshotName, "version", desiredSnapshot.GetVersion(envoyresource.ClusterType), "snapshot-version", envoycp.SnapshotVersion(desiredSnapshot))
+		if err := r.EnvoyCache.SetSnapshot(ctx, snapshotName, desiredSnapshot); err != nil {
+			return err
+		}
+	case envoycp.SnapshotIsEqual(currentSnapshot, desiredSnapshot):
 		log.V(2).Info("snapshot is in desired state")
-		return nil
+	default:
+		log.Info("updating snapshot", "service-node", snapshotName, "version", desiredSnapshot.GetVersion(envoyresource.ClusterType), "endpoints-version", desiredSnapshot.GetVersion(envoyresource.EndpointType),
+			"snapshot-version", envoycp.SnapshotVersion(desiredSnapshot))
+		if err := r.EnvoyCache.SetSnapshot(ctx, snapshotName, desiredSnapshot); err != nil {
+			return fmt.Errorf("failed to set a new Envoy cache snapshot: %w", err)
+		}
 	}
 
-	if err := desiredSnapshot.Consistent(); err != nil {
-		return fmt.Errorf("new Envoy config snapshot is not consistent: %w", err)
+	// The snapshot is added to the history once the Envoy proxies accepted it, the pinned snapshot is already part of it.
+	if pinnedVersion == "" {
+		r.SnapshotHistory.SetPending(snapshotName, desiredSnapshot)
+	}
+	return nil
+}
+
+// getPinnedSnapshotVersion returns the snapshot version that the Envoy proxies of a snapshot are pinned to, if any, along with the Config
+// or Tenant that pins it.
+func (r *EnvoyCPReconciler) getPinnedSnapshotVersion(namespace string, tenants map[string]*kubelbv1alpha1.Tenant) (string, ctrlruntimeclient.Object) {
+	if r.EnvoyProxyTopology.IsGlobalTopology() {
+		return r.Config.GetAnnotations()[kubelbv1alpha1.EnvoySnapshotVersionAnnotation], r.Config
+	}
+	if tenant, ok := tenants[namespace]; ok {
+		return tenant.GetAnnotations()[kubelbv1alpha1.EnvoySnapshotVersionAnnotation], tenant
 	}
+	return "", nil
+}
+
+// reportSkippedObjects sets the ProxyConfigInvalid condition on the LoadBalancers and Routes that were left out of the snapshot, and
+// removes it from the ones that are part of the snapshot again.
+func (r *EnvoyCPReconciler) reportSkippedObjects(ctx context.Context, lbs []kubelbv1alpha1.LoadBalancer, routes []kubelbv1alpha1.Route, skipped []envoycp.SkippedObject) {
+	log := ctrl.LoggerFrom(ctx)
 
-	log.Info("updating snapshot", "service-node", snapshotName, "version", desiredSnapshot.GetVersion(envoyresource.ClusterType))
+	skippedObjects := make(map[string]envoycp.SkippedObject)
+	for _, s := range skipped {
+		skippedObjects[fmt.Sprintf("%T/%s", s.Object, client.ObjectKeyFromObject(s.Object))] = s
+	}
 
-	if err := r.EnvoyCache.SetSnapshot(ctx, snapshotName, desiredSnapshot); err != nil {
-		return fmt.Errorf("failed to set a new Envoy cache snapshot: %w", err)
+	objects := make([]ctrlruntimeclient.Object, 0, len(lbs)+len(routes))
+	for i := range lbs {
+		objects = append(objects, &lbs[i])
+	}
+	for i := range routes {
+		objects = append(objects, &routes[i])
 	}
 
-	return nil
+	for _, obj := range objects {
+		s, isSkipped := skippedObjects[fmt.Sprintf("%T/%s", obj, client.ObjectKeyFromObject(obj))]
+		err := PatchStatusConditions(ctx, r.Client, obj, func(conditions *[]metav1.Condition) bool {
+			if !isSkipped {
+				return meta.RemoveStatusCondition(conditions, kubelbv1alpha1.ConditionProxyConfigInvalid.String())
+			}
+			return meta.SetStatusCondition(conditions, metav1.Condition{
+				Type:               kubelbv1alpha1.ConditionProxyConfigInvalid.String(),
+				Status:             metav1.ConditionTrue,
+				Reason:             s.Reason,
+				Message:            s.Err.Error(),
+				ObservedGeneration: obj.GetGeneration(),
+			})
+		})
+		if err != nil {
+			log.Error(err, "failed to update ProxyConfigInvalid condition", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		}
+	}
 }
 
 func (r *EnvoyCPReconciler) ListLoadBalancersAndRoutes(ctx context.Context, req ctrl.Request) ([]kubelbv1alpha1.LoadBalancer, []kubelbv1alpha1.Route, error) {
@@ -293,7 +502,10 @@ func (r *EnvoyCPReconciler) getEnvoyProxyPodSpec(namespace, appName, snapshotNam
 		ObjectMeta: v1.ObjectMeta{
 			Name:      appName,
 			Namespace: namespace,
-			Labels:    map[string]string{kubelb.LabelAppKubernetesName: appName},
+			Labels: map[string]string{
+				kubelb.LabelAppKubernetesName: appName,
+				kubelb.LabelManagedBy:         kubelb.LabelControllerName,
+			},
 		},
 		Spec: corev1.PodSpec{
 			Containers: []corev1.Container{
@@ -305,6 +517,18 @@ func (r *EnvoyCPReconciler) getEnvoyProxyPodSpec(namespace, appName, snapshotNam
 						"--service-node", snapshotName,
 						"--service-cluster", namespace,
 					},
+					// The topology labels are set on the pod by Kubernetes with the PodTopologyLabelsAdmission feature.
+					Env: []corev1.EnvVar{
+						makeLabelEnvVar(envoycp.ProxyZoneEnvVar, corev1.LabelTopologyZone),
+						makeLabelEnvVar(envoycp.ProxyRegionEnvVar, corev1.LabelTopologyRegion),
+					},
+					Ports: []corev1.ContainerPort{
+						{
+							Name:          envoyProxyMetricsPortName,
+							ContainerPort: envoycp.StatsPort,
+							Protocol:      corev1.ProtocolTCP,
+						},
+					},
 				},
 			},
 		},
@@ -341,6 +565,18 @@ func (r *EnvoyCPReconciler) getEnvoyProxyPodSpec(namespace, appName, snapshotNam
 	return template
 }
 
+// makeLabelEnvVar generates an environment variable with the value of a label of the pod, the value is empty if the label doesn't exist.
+func makeLabelEnvVar(name, label string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				FieldPath: fmt.Sprintf("metadata.labels['%s']", label),
+			},
+		},
+	}
+}
+
 func envoySnapshotAndAppName(topology EnvoyProxyTopology, req ctrl.Request) (string, string) {
 	switch topology {
 	case EnvoyProxyTopologyShared, EnvoyProxyTopologyDedicated:
@@ -368,24 +604,155 @@ func (r *EnvoyCPReconciler) enqueueLoadBalancers() handler.MapFunc {
 	}
 }
 
+// enqueueLoadBalancersForTenant is a handler.MapFunc to be used to enqeue requests for reconciliation
+// for the snapshot of a tenant if some change is made to the Tenant.
+func (r *EnvoyCPReconciler) enqueueLoadBalancersForTenant() handler.MapFunc {
+	return func(_ context.Context, o ctrlruntimeclient.Object) []ctrl.Request {
+		return []reconcile.Request{
+			{
+				NamespacedName: types.NamespacedName{
+					Name:      RequeueAllResources,
+					Namespace: fmt.Sprintf(tenantNamespacePattern, o.GetName()),
+				},
+			},
+		}
+	}
+}
+
+// enqueueLoadBalancersForConfig is a handler.MapFunc to be used to enqeue requests for reconciliation
+// for all the snapshots if some change is made to the Config.
+func (r *EnvoyCPReconciler) enqueueLoadBalancersForConfig() handler.MapFunc {
+	return func(ctx context.Context, o ctrlruntimeclient.Object) []ctrl.Request {
+		result := []reconcile.Request{}
+		if o.GetNamespace() != r.Namespace {
+			return result
+		}
+
+		namespaces := make(map[string]bool)
+		loadBalancers := &kubelbv1alpha1.LoadBalancerList{}
+		if err := r.List(ctx, loadBalancers); err != nil {
+			return result
+		}
+		for _, lb := range loadBalancers.Items {
+			namespaces[lb.Namespace] = true
+		}
+
+		routes := &kubelbv1alpha1.RouteList{}
+		if err := r.List(ctx, routes); err != nil {
+			return result
+		}
+		for _, route := range routes.Items {
+			namespaces[route.Namespace] = true
+		}
+
+		for namespace := range namespaces {
+			result = append(result, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      RequeueAllResources,
+					Namespace: namespace,
+				},
+			})
+		}
+		return result
+	}
+}
+
 func (r *EnvoyCPReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
 	// 1. Watch for changes in LoadBalancer resources.
 	// 2. Resource must exist in a tenant namespace.
 	// 3. Watch for changes in Route resources and enqueue LoadBalancer resources. TODO: we need to
 	// find an alternative for this since it is more of a "hack".
+	// 4. Watch for changes in Secret and SyncSecret resources since they contain the certificates for the listeners.
+	// 5. Watch for changes in Tenant and Config resources since they contain the defaults for the LoadBalancers and the pinned snapshot
+	// versions.
+	// 6. Watch for changes in the Envoy proxy pods since their zones are used for zone-aware routing.
+	namespaceFilter := utils.ByLabelExistsOnNamespace(ctx, mgr.GetClient())
+	// The proxies of the Global topology run in the namespace of the controller, which is not a tenant namespace.
+	envoyProxyPodPredicates := []predicate.Predicate{predicate.NewPredicateFuncs(r.isEnvoyProxyPod), envoyProxyPodChanged()}
+	if !r.EnvoyProxyTopology.IsGlobalTopology() {
+		envoyProxyPodPredicates = append(envoyProxyPodPredicates, namespaceFilter)
+	}
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&kubelbv1alpha1.LoadBalancer{}).
+		For(&kubelbv1alpha1.LoadBalancer{}, builder.WithPredicates(namespaceFilter)).
 		// Disable concurrency to ensure that only one snapshot is created at a time.
 		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
-		WithEventFilter(utils.ByLabelExistsOnNamespace(ctx, mgr.GetClient())).
 		Watches(
 			&kubelbv1alpha1.Route{},
 			handler.EnqueueRequestsFromMapFunc(r.enqueueLoadBalancers()),
-			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+			builder.WithPredicates(namespaceFilter, predicate.GenerationChangedPredicate{}),
 		).
 		Watches(
 			&kubelbv1alpha1.Addresses{},
 			handler.EnqueueRequestsFromMapFunc(r.enqueueLoadBalancers()),
+			builder.WithPredicates(namespaceFilter),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueLoadBalancers()),
+			builder.WithPredicates(namespaceFilter),
+		).
+		Watches(
+			&kubelbv1alpha1.SyncSecret{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueLoadBalancers()),
+			builder.WithPredicates(namespaceFilter),
+		).
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueLoadBalancers()),
+			builder.WithPredicates(envoyProxyPodPredicates...),
+		).
+		Watches(
+			&kubelbv1alpha1.Tenant{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueLoadBalancersForTenant()),
+			builder.WithPredicates(predicate.Or[client.Object](predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})),
+		).
+		Watches(
+			&kubelbv1alpha1.Config{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueLoadBalancersForConfig()),
+			builder.WithPredicates(predicate.Or[client.Object](predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})),
 		).
 		Complete(r)
 }
+
+// isEnvoyProxyPod filters the pods that are not Envoy proxies deployed by the controller for the configured topology.
+func (r *EnvoyCPReconciler) isEnvoyProxyPod(o ctrlruntimeclient.Object) bool {
+	if o.GetLabels()[kubelb.LabelManagedBy] != kubelb.LabelControllerName {
+		return false
+	}
+	if r.EnvoyProxyTopology.IsGlobalTopology() {
+		return o.GetNamespace() == r.Namespace && o.GetLabels()[kubelb.LabelAppKubernetesName] == EnvoyGlobalCache
+	}
+	return o.GetLabels()[kubelb.LabelAppKubernetesName] == o.GetNamespace()
+}
+
+// envoyProxyPodChanged filters the events of the Envoy proxy pods that don't affect the local cluster of the snapshot. Only pods that are
+// running or had an address can be part of the local cluster.
+func envoyProxyPodChanged() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			pod, ok := e.Object.(*corev1.Pod)
+			return ok && isRunningEnvoyProxyPod(pod)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod, ok := e.ObjectOld.(*corev1.Pod)
+			if !ok {
+				return false
+			}
+			newPod, ok := e.ObjectNew.(*corev1.Pod)
+			if !ok {
+				return false
+			}
+			return isRunningEnvoyProxyPod(oldPod) != isRunningEnvoyProxyPod(newPod) ||
+				oldPod.Status.PodIP != newPod.Status.PodIP ||
+				oldPod.Labels[corev1.LabelTopologyZone] != newPod.Labels[corev1.LabelTopologyZone] ||
+				oldPod.Labels[corev1.LabelTopologyRegion] != newPod.Labels[corev1.LabelTopologyRegion]
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			pod, ok := e.Object.(*corev1.Pod)
+			return ok && pod.Status.PodIP != ""
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"testing"
+
+	"k8c.io/kubelb/internal/kubelb"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsEnvoyProxyPod(t *testing.T) {
+	newPod := func(namespace, appName string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "envoy",
+			Namespace: namespace,
+			Labels:    map[string]string{kubelb.LabelAppKubernetesName: appName, kubelb.LabelManagedBy: kubelb.LabelControllerName},
+		}}
+	}
+
+	testCases := []struct {
+		name     string
+		topology EnvoyProxyTopology
+		pod      *corev1.Pod
+		expected bool
+	}{
+		{
+			name:     "proxy of a tenant",
+			topology: EnvoyProxyTopologyShared,
+			pod:      newPod("tenant-test", "tenant-test"),
+			expected: true,
+		},
+		{
+			name:     "pod of a tenant that is not managed by the controller",
+			topology: EnvoyProxyTopologyShared,
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-test", Labels: map[string]string{kubelb.LabelAppKubernetesName: "tenant-test"}}},
+		},
+		{
+			name:     "proxy of the Global topology",
+			topology: EnvoyProxyTopologyGlobal,
+			pod:      newPod("kubelb", EnvoyGlobalCache),
+			expected: true,
+		},
+		{
+			name:     "proxy of the Global topology in another namespace",
+			topology: EnvoyProxyTopologyGlobal,
+			pod:      newPod("tenant-test", EnvoyGlobalCache),
+		},
+		{
+			name:     "proxy of a tenant with the Global topology",
+			topology: EnvoyProxyTopologyGlobal,
+			pod:      newPod("tenant-test", "tenant-test"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &EnvoyCPReconciler{EnvoyProxyTopology: tc.topology, Namespace: "kubelb"}
+			if isProxy := r.isEnvoyProxyPod(tc.pod); isProxy != tc.expected {
+				t.Errorf("expected the pod to be an Envoy proxy: %t, got %t", tc.expected, isProxy)
+			}
+		})
+	}
+}
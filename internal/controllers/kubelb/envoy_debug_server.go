@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"net/http"
+
+	envoycp "k8c.io/kubelb/internal/envoy"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// NewEnvoyDebugServer returns a server that serves the debug handler of the Envoy control plane over HTTPS. The clients are authenticated
+// with their bearer token and need to be allowed to get the non-resource URL /debug/snapshots/*. It uses a self-signed certificate, and runs
+// on all the replicas since each replica serves its own Envoy proxies.
+//
+// The server is based on the metrics server of controller-runtime, the metrics of the manager are served on it as well.
+func NewEnvoyDebugServer(mgr ctrl.Manager, address string, handler http.Handler) (metricsserver.Server, error) {
+	return metricsserver.NewServer(metricsserver.Options{
+		BindAddress:    address,
+		SecureServing:  true,
+		FilterProvider: filters.WithAuthenticationAndAuthorization,
+		ExtraHandlers: map[string]http.Handler{
+			envoycp.DebugSnapshotsPath:       handler,
+			envoycp.DebugSnapshotsPath + "/": handler,
+		},
+	}, mgr.GetConfig(), mgr.GetHTTPClient())
+}
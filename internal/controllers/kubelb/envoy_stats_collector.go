@@ -0,0 +1,200 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+	envoycp "k8c.io/kubelb/internal/envoy"
+	"k8c.io/kubelb/internal/kubelb"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	EnvoyStatsCollectorName   = "envoy-stats-collector"
+	DefaultEnvoyStatsInterval = 30 * time.Second
+	envoyStatsScrapeTimeout   = 5 * time.Second
+)
+
+// EnvoyStatsCollector periodically scrapes the statistics of the Envoy proxies and reports them in the status of the LoadBalancers.
+type EnvoyStatsCollector struct {
+	ctrlruntimeclient.Client
+	// APIReader is used to list the Envoy proxy pods, this avoids caching all the pods of the cluster.
+	APIReader ctrlruntimeclient.Reader
+	Interval  time.Duration
+	// ScrapeClusterStatus enables scraping the status of the clusters, it must only be enabled if the Envoy proxies expose it.
+	ScrapeClusterStatus bool
+
+	httpClient *http.Client
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+
+func (c *EnvoyStatsCollector) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName(EnvoyStatsCollectorName)
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	c.httpClient = &http.Client{Timeout: envoyStatsScrapeTimeout}
+	interval := c.Interval
+	if interval == 0 {
+		interval = DefaultEnvoyStatsInterval
+	}
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.collect(ctx); err != nil {
+			log.Error(err, "failed to collect Envoy proxy statistics")
+		}
+	}, interval)
+	return nil
+}
+
+// NeedLeaderElection ensures that only the leader updates the status of the LoadBalancers.
+func (c *EnvoyStatsCollector) NeedLeaderElection() bool {
+	return true
+}
+
+func (c *EnvoyStatsCollector) collect(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	pods := &corev1.PodList{}
+	if err := c.APIReader.List(ctx, pods, ctrlruntimeclient.MatchingLabels{kubelb.LabelManagedBy: kubelb.LabelControllerName}); err != nil {
+		return fmt.Errorf("failed to list Envoy proxy pods: %w", err)
+	}
+
+	stats := envoycp.NewStats()
+	for _, pod := range pods.Items {
+		if !isEnvoyProxyPod(&pod) || pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		address := net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(envoycp.StatsPort))
+		podStats, err := envoycp.ScrapeStats(ctx, c.httpClient, address, c.ScrapeClusterStatus)
+		if err != nil {
+			log.V(2).Info("failed to scrape Envoy proxy statistics", "pod", ctrlruntimeclient.ObjectKeyFromObject(&pod), "error", err.Error())
+			continue
+		}
+		stats.Add(podStats)
+	}
+
+	lbs := &kubelbv1alpha1.LoadBalancerList{}
+	if err := c.List(ctx, lbs); err != nil {
+		return fmt.Errorf("failed to list LoadBalancers: %w", err)
+	}
+
+	for _, lb := range lbs.Items {
+		proxyStatus, found := getProxyStatus(&lb, stats)
+		if !found || reflect.DeepEqual(lb.Status.Proxy, proxyStatus) {
+			continue
+		}
+		if err := c.updateProxyStatus(ctx, types.NamespacedName{Namespace: lb.Namespace, Name: lb.Name}, proxyStatus); err != nil {
+			log.Error(err, "failed to update LoadBalancer proxy status", "namespace", lb.Namespace, "name", lb.Name)
+		}
+	}
+	return nil
+}
+
+func (c *EnvoyStatsCollector) updateProxyStatus(ctx context.Context, key types.NamespacedName, proxyStatus *kubelbv1alpha1.ProxyStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lb := &kubelbv1alpha1.LoadBalancer{}
+		if err := c.Get(ctx, key, lb); err != nil {
+			return ctrlruntimeclient.IgnoreNotFound(err)
+		}
+		original := lb.DeepCopy()
+		lb.Status.Proxy = proxyStatus
+		return c.Status().Patch(ctx, lb, ctrlruntimeclient.MergeFrom(original))
+	})
+}
+
+// getProxyStatus aggregates the statistics of the clusters and the ports of a LoadBalancer. False is returned if none of them were observed.
+func getProxyStatus(lb *kubelbv1alpha1.LoadBalancer, stats envoycp.Stats) (*kubelbv1alpha1.ProxyStatus, bool) {
+	found := false
+	proxyStatus := &kubelbv1alpha1.ProxyStatus{}
+	for i, endpoints := range lb.Spec.Endpoints {
+		for _, port := range endpoints.Ports {
+			clusterStats, ok := stats.Clusters[fmt.Sprintf(kubelb.EnvoyResourceIdentifierPattern, lb.Namespace, lb.Name, i, port.Port, port.Protocol)]
+			if !ok {
+				continue
+			}
+			found = true
+			proxyStatus.EjectedEndpoints += int32(clusterStats.EjectedEndpoints)
+			if proxyStatus.CircuitBreakerOverflows == nil {
+				proxyStatus.CircuitBreakerOverflows = &kubelbv1alpha1.CircuitBreakerOverflows{}
+			}
+			proxyStatus.CircuitBreakerOverflows.Connections += int64(clusterStats.ConnectionOverflows)
+			proxyStatus.CircuitBreakerOverflows.PendingRequests += int64(clusterStats.PendingRequestOverflows)
+			proxyStatus.CircuitBreakerOverflows.Retries += int64(clusterStats.RetryOverflows)
+		}
+	}
+	for p := range lb.Spec.Ports {
+		// The listener of a port sends the connections to the sets of endpoints with the highest priority, their clusters fail over to the
+		// sets with the lower priorities.
+		levels := kubelb.GetPriorityLevels(lb, p)
+		if len(levels) < 2 {
+			continue
+		}
+		for _, i := range levels[0] {
+			endpointPort := lb.Spec.Endpoints[i].Ports[p]
+			clusterStats, ok := stats.Clusters[fmt.Sprintf(kubelb.EnvoyResourceIdentifierPattern, lb.Namespace, lb.Name, i, endpointPort.Port, endpointPort.Protocol)]
+			if !ok {
+				continue
+			}
+			overprovisioningFactor := uint32(envoycp.DefaultOverprovisioningFactor)
+			if lb.Spec.Endpoints[i].OverprovisioningFactor != nil {
+				overprovisioningFactor = *lb.Spec.Endpoints[i].OverprovisioningFactor
+			}
+			level := clusterStats.ActivePriority(overprovisioningFactor)
+			if level >= len(levels) {
+				continue
+			}
+			found = true
+			activePriority := lb.Spec.Endpoints[levels[level][0]].Priority
+			if proxyStatus.ActivePriority == nil || activePriority > *proxyStatus.ActivePriority {
+				proxyStatus.ActivePriority = &activePriority
+			}
+		}
+	}
+	for p := range lb.Spec.Ports {
+		rateLimited, ok := stats.RateLimitedConnections[fmt.Sprintf(kubelb.EnvoyConnectionRateLimitPattern, lb.Namespace, lb.Name, p)]
+		if !ok {
+			continue
+		}
+		found = true
+		proxyStatus.RateLimitedConnections += int64(rateLimited)
+	}
+	return proxyStatus, found
+}
+
+func isEnvoyProxyPod(pod *corev1.Pod) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == envoyProxyContainerName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"reflect"
+	"testing"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+	envoycp "k8c.io/kubelb/internal/envoy"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetProxyStatus(t *testing.T) {
+	lb := &kubelbv1alpha1.LoadBalancer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "tenant-test"},
+		Spec: kubelbv1alpha1.LoadBalancerSpec{
+			Ports: []kubelbv1alpha1.LoadBalancerPort{{Port: 80, Protocol: corev1.ProtocolTCP}, {Port: 443, Protocol: corev1.ProtocolTCP}},
+			Endpoints: []kubelbv1alpha1.LoadBalancerEndpoints{{
+				Ports: []kubelbv1alpha1.EndpointPort{{Port: 30080, Protocol: corev1.ProtocolTCP}, {Port: 30443, Protocol: corev1.ProtocolTCP}},
+			}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		clusters map[string]envoycp.ClusterStats
+		expected *kubelbv1alpha1.ProxyStatus
+	}{
+		{
+			name: "clusters are not observed",
+		},
+		{
+			name: "circuit breaker overflows are summed over the ports",
+			clusters: map[string]envoycp.ClusterStats{
+				"tenant-test-test-ep-0-port-30080-TCP":  {EjectedEndpoints: 1, ConnectionOverflows: 2, RetryOverflows: 1},
+				"tenant-test-test-ep-0-port-30443-TCP":  {ConnectionOverflows: 3, PendingRequestOverflows: 4},
+				"tenant-test-other-ep-0-port-30080-TCP": {ConnectionOverflows: 100},
+			},
+			expected: &kubelbv1alpha1.ProxyStatus{
+				EjectedEndpoints:        1,
+				CircuitBreakerOverflows: &kubelbv1alpha1.CircuitBreakerOverflows{Connections: 5, PendingRequests: 4, Retries: 1},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats := envoycp.NewStats()
+			for name, clusterStats := range tc.clusters {
+				stats.Clusters[name] = clusterStats
+			}
+
+			proxyStatus, found := getProxyStatus(lb, stats)
+			if found != (tc.expected != nil) {
+				t.Fatalf("expected the statistics to be found: %t, got %t", tc.expected != nil, found)
+			}
+			if found && !reflect.DeepEqual(proxyStatus, tc.expected) {
+				t.Errorf("expected proxy status %+v, got %+v", tc.expected, proxyStatus)
+			}
+		})
+	}
+}
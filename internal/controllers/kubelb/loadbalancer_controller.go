@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 
 	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
 	utils "k8c.io/kubelb/internal/controllers"
@@ -28,6 +29,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -48,6 +50,7 @@ import (
 const (
 	envoyImage                        = "envoyproxy/envoy:distroless-v1.31.0"
 	envoyProxyContainerName           = "envoy-proxy"
+	envoyProxyMetricsPortName         = "metrics"
 	envoyResourcePattern              = "envoy-%s"
 	envoyGlobalTopologyServicePattern = "envoy-%s-%s"
 	envoyProxyCleanupFinalizer        = "kubelb.k8c.io/cleanup-envoy-proxy"
@@ -206,6 +209,11 @@ func (r *LoadBalancerReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
+	if err := r.reconcileTLSPassthroughStatus(ctx, &loadBalancer, loadBalancers.Items, config); err != nil {
+		log.Error(err, "Unable to update TLS passthrough status")
+		return ctrl.Result{}, err
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -257,7 +265,10 @@ func (r *LoadBalancerReconciler) reconcileService(ctx context.Context, loadBalan
 			var allocatedPort corev1.ServicePort
 			targetPort := loadBalancer.Spec.Endpoints[0].Ports[currentLbPort].Port
 
-			if r.EnvoyProxyTopology == EnvoyProxyTopologyGlobal {
+			if kubelb.UsesTLSPassthrough(loadBalancer, currentLbPort) {
+				// The listener port is shared with the other LoadBalancers that use TLS passthrough on the same port.
+				targetPort = lbServicePort.Port
+			} else if r.EnvoyProxyTopology == EnvoyProxyTopologyGlobal {
 				endpointKey := fmt.Sprintf(kubelb.EnvoyEndpointPattern, loadBalancer.Namespace, loadBalancer.Name, 0)
 				portKey := fmt.Sprintf(kubelb.EnvoyListenerPattern, targetPort, lbServicePort.Protocol)
 				if value, exists := portAllocator.Lookup(endpointKey, portKey); exists {
@@ -292,6 +303,21 @@ func (r *LoadBalancerReconciler) reconcileService(ctx context.Context, loadBalan
 		service.Spec.Selector = map[string]string{kubelb.LabelAppKubernetesName: appName}
 		service.Spec.Type = loadBalancer.Spec.Type
 
+		ipFamilyPolicy := corev1.IPFamilyPolicySingleStack
+		if loadBalancer.Spec.IPFamilyPolicy != nil {
+			ipFamilyPolicy = *loadBalancer.Spec.IPFamilyPolicy
+		}
+		// The policy is taken from the tenant cluster, the Service can't be created if the LB cluster is single-stack.
+		if ipFamilyPolicy == corev1.IPFamilyPolicyRequireDualStack {
+			ipFamilyPolicy = corev1.IPFamilyPolicyPreferDualStack
+		}
+		service.Spec.IPFamilyPolicy = &ipFamilyPolicy
+		// Downgrading a dual-stack Service requires removing the secondary IP family.
+		if ipFamilyPolicy == corev1.IPFamilyPolicySingleStack && len(service.Spec.IPFamilies) > 1 {
+			service.Spec.IPFamilies = service.Spec.IPFamilies[:1]
+			service.Spec.ClusterIPs = service.Spec.ClusterIPs[:1]
+		}
+
 		// Set the LoadBalancerClassName if it is specified in the configuration.
 		if className != nil {
 			service.Spec.LoadBalancerClass = className
@@ -314,10 +340,12 @@ func (r *LoadBalancerReconciler) reconcileService(ctx context.Context, loadBalan
 	updatedPorts := []kubelbv1alpha1.ServicePort{}
 	for i, port := range service.Spec.Ports {
 		targetPort := loadBalancer.Spec.Endpoints[0].Ports[i].Port
+		healthCheckMode, _ := kubelb.GetHealthCheckMode(kubelb.GetHealthCheck(loadBalancer, i), port.Protocol, loadBalancer.Spec.HealthCheckNodePort)
 		updatedPorts = append(updatedPorts, kubelbv1alpha1.ServicePort{
 			ServicePort: port,
 			// In case of global topology, this will be different from the targetPort. Otherwise it will be the same.
 			UpstreamTargetPort: targetPort,
+			HealthCheckMode:    healthCheckMode,
 		})
 	}
 
@@ -353,7 +381,9 @@ func (r *LoadBalancerReconciler) reconcileService(ctx context.Context, loadBalan
 			return err
 		}
 		original := lb.DeepCopy()
-		lb.Status = updatedLoadBalanacerStatus
+		// The proxy status is reported by the EnvoyStatsCollector and is left untouched.
+		lb.Status.Service = updatedLoadBalanacerStatus.Service
+		lb.Status.LoadBalancer = updatedLoadBalanacerStatus.LoadBalancer
 		if reflect.DeepEqual(original.Status, lb.Status) {
 			return nil
 		}
@@ -362,6 +392,81 @@ func (r *LoadBalancerReconciler) reconcileService(ctx context.Context, loadBalan
 	})
 }
 
+// reconcileTLSPassthroughStatus reports whether the hostnames that the LoadBalancer claims for TLS passthrough have been accepted. The claims
+// of all the LoadBalancers that share the Envoy proxy are resolved in the same way as for the Envoy snapshot.
+func (r *LoadBalancerReconciler) reconcileTLSPassthroughStatus(ctx context.Context, loadBalancer *kubelbv1alpha1.LoadBalancer, loadBalancers []kubelbv1alpha1.LoadBalancer,
+	config *kubelbv1alpha1.Config) error {
+	var condition *v1.Condition
+	if usesTLSPassthrough(loadBalancer) {
+		var lbs []kubelbv1alpha1.LoadBalancer
+		allowedHostnames := make(map[string][]string)
+		for _, lb := range loadBalancers {
+			if !lb.DeletionTimestamp.IsZero() || !usesTLSPassthrough(&lb) {
+				continue
+			}
+			lbs = append(lbs, lb)
+			if _, ok := allowedHostnames[lb.Namespace]; ok {
+				continue
+			}
+			tenant, err := GetTenant(ctx, r.Client, RemoveTenantPrefix(lb.Namespace))
+			if err != nil {
+				if !apierrors.IsNotFound(err) {
+					return err
+				}
+				tenant = &kubelbv1alpha1.Tenant{}
+			}
+			allowedHostnames[lb.Namespace] = GetAllowedTLSHostnames(tenant, config)
+		}
+
+		claims := kubelb.ResolveTLSPassthroughClaims(lbs, func(namespace string) []string {
+			return allowedHostnames[namespace]
+		})
+
+		condition = &v1.Condition{
+			Type:               kubelbv1alpha1.ConditionHostnamesAccepted.String(),
+			Status:             v1.ConditionTrue,
+			Reason:             kubelbv1alpha1.ReasonHostnamesAccepted,
+			Message:            "All hostnames have been accepted",
+			ObservedGeneration: loadBalancer.Generation,
+		}
+		if rejections := claims.Rejections[types.NamespacedName{Namespace: loadBalancer.Namespace, Name: loadBalancer.Name}]; len(rejections) > 0 {
+			var messages []string
+			for _, rejection := range rejections {
+				messages = append(messages, rejection.Message)
+			}
+			condition.Status = v1.ConditionFalse
+			condition.Reason = rejections[0].Reason
+			condition.Message = strings.Join(messages, "; ")
+		}
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lb := &kubelbv1alpha1.LoadBalancer{}
+		if err := r.Get(ctx, types.NamespacedName{Name: loadBalancer.Name, Namespace: loadBalancer.Namespace}, lb); err != nil {
+			return err
+		}
+		original := lb.DeepCopy()
+		if condition != nil {
+			meta.SetStatusCondition(&lb.Status.Conditions, *condition)
+		} else {
+			meta.RemoveStatusCondition(&lb.Status.Conditions, kubelbv1alpha1.ConditionHostnamesAccepted.String())
+		}
+		if reflect.DeepEqual(original.Status, lb.Status) {
+			return nil
+		}
+		return r.Status().Patch(ctx, lb, ctrlruntimeclient.MergeFrom(original))
+	})
+}
+
+func usesTLSPassthrough(lb *kubelbv1alpha1.LoadBalancer) bool {
+	for p := range lb.Spec.Ports {
+		if kubelb.UsesTLSPassthrough(lb, p) {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *LoadBalancerReconciler) cleanup(ctx context.Context, lb kubelbv1alpha1.LoadBalancer, resourceNamespace string) error {
 	log := ctrl.LoggerFrom(ctx).WithValues("cleanup", "LoadBalancer")
 	log.V(2).Info("Cleaning up LoadBalancer", "name", lb.Name, "namespace", lb.Namespace)
@@ -434,9 +539,49 @@ func (r *LoadBalancerReconciler) SetupWithManager(ctx context.Context, mgr ctrl.
 			&corev1.Service{},
 			handler.EnqueueRequestsFromMapFunc(r.enqueueLoadBalancers()),
 		).
+		Watches(
+			&kubelbv1alpha1.LoadBalancer{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueLoadBalancersForTLSPassthrough()),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
 		Complete(r)
 }
 
+// enqueueLoadBalancersForTLSPassthrough is a handler.MapFunc to be used to enqeue requests for reconciliation
+// for LoadBalancers that share a listener port for TLS passthrough, since a change to one claim can resolve a conflict of another.
+func (r *LoadBalancerReconciler) enqueueLoadBalancersForTLSPassthrough() handler.MapFunc {
+	return func(ctx context.Context, o ctrlruntimeclient.Object) []ctrl.Request {
+		result := []reconcile.Request{}
+
+		lb, ok := o.(*kubelbv1alpha1.LoadBalancer)
+		if !ok || !usesTLSPassthrough(lb) {
+			return result
+		}
+
+		var listOpts []ctrlruntimeclient.ListOption
+		if !r.EnvoyProxyTopology.IsGlobalTopology() {
+			listOpts = append(listOpts, ctrlruntimeclient.InNamespace(lb.Namespace))
+		}
+		loadBalancers := &kubelbv1alpha1.LoadBalancerList{}
+		if err := r.List(ctx, loadBalancers, listOpts...); err != nil {
+			return result
+		}
+
+		for _, item := range loadBalancers.Items {
+			if (item.Namespace == lb.Namespace && item.Name == lb.Name) || !usesTLSPassthrough(&item) {
+				continue
+			}
+			result = append(result, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      item.Name,
+					Namespace: item.Namespace,
+				},
+			})
+		}
+		return result
+	}
+}
+
 // enqueueLoadBalancers is a handler.MapFunc to be used to enqeue requests for reconciliation
 // for LoadBalancers against the corresponding service.
 func (r *LoadBalancerReconciler) enqueueLoadBalancers() handler.MapFunc {
@@ -104,7 +104,7 @@ var _ = Describe("Lb deployment and service creation", func() {
 					return k8sClient.Get(ctx, deploymentLookupKey, createdDeployment)
 				}, timeout, interval).Should(Succeed())
 
-				Expect(createdDeployment.Spec.Template.Spec.Containers[0].Args[1]).Should(Equal(envoyServer.GenerateBootstrap()))
+				Expect(createdDeployment.Spec.Template.Spec.Containers[0].Args[1]).Should(Equal(envoyServer.GenerateBootstrap(nil, false)))
 
 				By("creating a corresponding service")
 
@@ -121,7 +121,7 @@ var _ = Describe("Lb deployment and service creation", func() {
 				snapshot, err := envoyServer.Cache.GetSnapshot(snapshotName)
 				Expect(err).ToNot(HaveOccurred())
 
-				testSnapshot, err := envoycp.MapSnapshot(ctx, k8sClient, getLoadBalancerList(*lb), nil, ecpr.PortAllocator, t.topology == EnvoyProxyTopologyGlobal)
+				testSnapshot, _, err := envoycp.MapSnapshot(ctx, k8sClient, getLoadBalancerList(*lb), nil, ecpr.PortAllocator, t.topology == EnvoyProxyTopologyGlobal, getSnapshotSettings(ctx, *lb))
 				Expect(err).ToNot(HaveOccurred())
 				diff := deep.Equal(snapshot, testSnapshot)
 				if len(diff) > 0 {
@@ -185,7 +185,7 @@ var _ = Describe("Lb deployment and service creation", func() {
 				snapshot, err := envoyServer.Cache.GetSnapshot(snapshotName)
 				Expect(err).ToNot(HaveOccurred())
 
-				testSnapshot, err := envoycp.MapSnapshot(ctx, k8sClient, getLoadBalancerList(*existingLb), nil, ecpr.PortAllocator, t.topology == EnvoyProxyTopologyGlobal)
+				testSnapshot, _, err := envoycp.MapSnapshot(ctx, k8sClient, getLoadBalancerList(*existingLb), nil, ecpr.PortAllocator, t.topology == EnvoyProxyTopologyGlobal, getSnapshotSettings(ctx, *existingLb))
 				Expect(err).ToNot(HaveOccurred())
 				diff := deep.Equal(snapshot, testSnapshot)
 				if len(diff) > 0 {
@@ -223,3 +223,9 @@ var _ = Describe("Lb deployment and service creation", func() {
 func getLoadBalancerList(lb kubelbv1alpha1.LoadBalancer) []kubelbv1alpha1.LoadBalancer {
 	return []kubelbv1alpha1.LoadBalancer{lb}
 }
+
+func getSnapshotSettings(ctx context.Context, lb kubelbv1alpha1.LoadBalancer) envoycp.SnapshotSettings {
+	tenants, err := ecpr.getTenants(ctx, getLoadBalancerList(lb), nil)
+	Expect(err).ToNot(HaveOccurred())
+	return envoycp.SnapshotSettings{Config: ecpr.Config, Tenants: tenants}
+}
@@ -317,6 +317,8 @@ func (r *RouteReconciler) UpdateRouteStatus(ctx context.Context, route *kubelbv1
 
 		// Update the status
 		original := route.DeepCopy()
+		// The conditions are reported by the Envoy config status reporter.
+		status.Conditions = route.Status.Conditions
 		route.Status = status
 
 		// If the status has not changed, no need to update.
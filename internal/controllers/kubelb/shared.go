@@ -18,10 +18,16 @@ package kubelb
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 
 	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
 	configpkg "k8c.io/kubelb/internal/config"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -74,3 +80,235 @@ func GetAnnotations(tenant *kubelbv1alpha1.Tenant, config *kubelbv1alpha1.Config
 	}
 	return annotations
 }
+
+// GetLoadBalancingPolicy returns the default load balancing policy for the LoadBalancers of a tenant. Tenant has higher precedence than the Config.
+func GetLoadBalancingPolicy(tenant *kubelbv1alpha1.Tenant, config *kubelbv1alpha1.Config) *kubelbv1alpha1.LoadBalancingPolicy {
+	if tenant.Spec.LoadBalancer.LoadBalancingPolicy != nil {
+		return tenant.Spec.LoadBalancer.LoadBalancingPolicy.DeepCopy()
+	} else if config.Spec.LoadBalancer.LoadBalancingPolicy != nil {
+		return config.Spec.LoadBalancer.LoadBalancingPolicy.DeepCopy()
+	}
+	return nil
+}
+
+// GetOutlierDetection returns the default outlier detection for the LoadBalancers of a tenant. Tenant has higher precedence than the Config.
+func GetOutlierDetection(tenant *kubelbv1alpha1.Tenant, config *kubelbv1alpha1.Config) *kubelbv1alpha1.OutlierDetection {
+	if tenant.Spec.LoadBalancer.OutlierDetection != nil {
+		return tenant.Spec.LoadBalancer.OutlierDetection.DeepCopy()
+	} else if config.Spec.LoadBalancer.OutlierDetection != nil {
+		return config.Spec.LoadBalancer.OutlierDetection.DeepCopy()
+	}
+	return nil
+}
+
+// GetAllowedTLSHostnames returns the hostnames that the LoadBalancers of a tenant can claim for TLS passthrough. Tenant has higher precedence
+// than the Config. Nil is returned if all the hostnames are allowed.
+func GetAllowedTLSHostnames(tenant *kubelbv1alpha1.Tenant, config *kubelbv1alpha1.Config) []string {
+	if len(tenant.Spec.LoadBalancer.AllowedTLSHostnames) > 0 {
+		return tenant.Spec.LoadBalancer.AllowedTLSHostnames
+	}
+	return config.Spec.LoadBalancer.AllowedTLSHostnames
+}
+
+// GetProxyProtocol returns the PROXY protocol configuration for a LoadBalancer. Both fields are resolved with the precedence
+// LoadBalancer > Tenant > Config, so a LoadBalancer can also opt out of a default.
+func GetProxyProtocol(proxyProtocol *kubelbv1alpha1.ProxyProtocol, tenant *kubelbv1alpha1.Tenant, config *kubelbv1alpha1.Config) *kubelbv1alpha1.ProxyProtocol {
+	var resolved *kubelbv1alpha1.ProxyProtocol
+	for _, p := range []*kubelbv1alpha1.ProxyProtocol{config.Spec.LoadBalancer.ProxyProtocol, tenant.Spec.LoadBalancer.ProxyProtocol, proxyProtocol} {
+		if p == nil {
+			continue
+		}
+		if resolved == nil {
+			resolved = &kubelbv1alpha1.ProxyProtocol{}
+		}
+		if p.Upstream != "" {
+			resolved.Upstream = p.Upstream
+		}
+		if p.Downstream != nil {
+			resolved.Downstream = ptr.To(*p.Downstream)
+		}
+	}
+	return resolved
+}
+
+// GetCircuitBreakers returns the circuit breakers for a LoadBalancer or a Route service. Values that are not specified are defaulted from the
+// Tenant and then the Config. Afterwards, the values are capped to the limits of both the Tenant and the Config; a missing value is set to the limit.
+func GetCircuitBreakers(circuitBreakers *kubelbv1alpha1.CircuitBreakers, tenant *kubelbv1alpha1.Tenant, config *kubelbv1alpha1.Config) *kubelbv1alpha1.CircuitBreakers {
+	resolved := &kubelbv1alpha1.CircuitBreakers{}
+	if circuitBreakers != nil {
+		resolved = circuitBreakers.DeepCopy()
+	}
+
+	for _, defaults := range []*kubelbv1alpha1.CircuitBreakers{tenant.Spec.LoadBalancer.CircuitBreakers, config.Spec.LoadBalancer.CircuitBreakers} {
+		if defaults == nil {
+			continue
+		}
+		resolved.MaxConnections = defaultLimit(resolved.MaxConnections, defaults.MaxConnections)
+		resolved.MaxPendingRequests = defaultLimit(resolved.MaxPendingRequests, defaults.MaxPendingRequests)
+		resolved.MaxRetries = defaultLimit(resolved.MaxRetries, defaults.MaxRetries)
+	}
+
+	for _, limits := range []*kubelbv1alpha1.CircuitBreakers{tenant.Spec.LoadBalancer.CircuitBreakerLimits, config.Spec.LoadBalancer.CircuitBreakerLimits} {
+		if limits == nil {
+			continue
+		}
+		resolved.MaxConnections = capLimit(resolved.MaxConnections, limits.MaxConnections)
+		resolved.MaxPendingRequests = capLimit(resolved.MaxPendingRequests, limits.MaxPendingRequests)
+		resolved.MaxRetries = capLimit(resolved.MaxRetries, limits.MaxRetries)
+	}
+
+	if *resolved == (kubelbv1alpha1.CircuitBreakers{}) {
+		return nil
+	}
+	return resolved
+}
+
+// GetTCPSettings returns the TCP settings for a LoadBalancer. Values that are not specified are defaulted from the Tenant and then the Config.
+func GetTCPSettings(tcp *kubelbv1alpha1.TCPSettings, tenant *kubelbv1alpha1.Tenant, config *kubelbv1alpha1.Config) *kubelbv1alpha1.TCPSettings {
+	resolved := &kubelbv1alpha1.TCPSettings{}
+	if tcp != nil {
+		resolved = tcp.DeepCopy()
+	}
+
+	for _, defaults := range []*kubelbv1alpha1.TCPSettings{tenant.Spec.LoadBalancer.TCP, config.Spec.LoadBalancer.TCP} {
+		if defaults == nil {
+			continue
+		}
+		if resolved.IdleTimeout == nil {
+			resolved.IdleTimeout = defaults.IdleTimeout
+		}
+		if resolved.MaxConnectionDuration == nil {
+			resolved.MaxConnectionDuration = defaults.MaxConnectionDuration
+		}
+		if resolved.ConnectTimeout == nil {
+			resolved.ConnectTimeout = defaults.ConnectTimeout
+		}
+		if resolved.Keepalive == nil && defaults.Keepalive != nil {
+			resolved.Keepalive = &kubelbv1alpha1.TCPKeepalive{}
+		}
+		if resolved.Keepalive != nil && defaults.Keepalive != nil {
+			resolved.Keepalive.Probes = defaultLimit(resolved.Keepalive.Probes, defaults.Keepalive.Probes)
+			if resolved.Keepalive.Time == nil {
+				resolved.Keepalive.Time = defaults.Keepalive.Time
+			}
+			if resolved.Keepalive.Interval == nil {
+				resolved.Keepalive.Interval = defaults.Keepalive.Interval
+			}
+		}
+	}
+
+	if reflect.DeepEqual(resolved, &kubelbv1alpha1.TCPSettings{}) {
+		return nil
+	}
+	return resolved
+}
+
+// GetDNSSettings returns the DNS settings for a set of endpoints of a LoadBalancer or a Route. Values that are not specified are defaulted from the
+// Tenant and then the Config.
+func GetDNSSettings(dns *kubelbv1alpha1.DNSSettings, tenant *kubelbv1alpha1.Tenant, config *kubelbv1alpha1.Config) *kubelbv1alpha1.DNSSettings {
+	resolved := &kubelbv1alpha1.DNSSettings{}
+	if dns != nil {
+		resolved = dns.DeepCopy()
+	}
+
+	for _, defaults := range []*kubelbv1alpha1.DNSSettings{tenant.Spec.LoadBalancer.DNS, config.Spec.LoadBalancer.DNS} {
+		if defaults == nil {
+			continue
+		}
+		if resolved.Resolution == "" {
+			resolved.Resolution = defaults.Resolution
+		}
+		if resolved.RefreshRate == nil {
+			resolved.RefreshRate = defaults.RefreshRate
+		}
+		if resolved.RespectDNSTTL == nil {
+			resolved.RespectDNSTTL = defaults.RespectDNSTTL
+		}
+		if resolved.LookupFamily == "" {
+			resolved.LookupFamily = defaults.LookupFamily
+		}
+	}
+
+	if reflect.DeepEqual(resolved, &kubelbv1alpha1.DNSSettings{}) {
+		return nil
+	}
+	return resolved
+}
+
+// GetConnectionRateLimit returns the connection rate limit for a port of a LoadBalancer. If not specified, the default from the Tenant and then
+// the Config is used. Afterwards, the rate limit is capped to the maximum of both the Tenant and the Config: the bucket to the number of
+// tokens of the maximum, and the rate at which the bucket is filled to the rate of the maximum. Ports without a rate limit are not limited.
+func GetConnectionRateLimit(rateLimit *kubelbv1alpha1.ConnectionRateLimit, tenant *kubelbv1alpha1.Tenant, config *kubelbv1alpha1.Config) *kubelbv1alpha1.ConnectionRateLimit {
+	var resolved *kubelbv1alpha1.ConnectionRateLimit
+	for _, defaults := range []*kubelbv1alpha1.ConnectionRateLimit{rateLimit, tenant.Spec.LoadBalancer.ConnectionRateLimit, config.Spec.LoadBalancer.ConnectionRateLimit} {
+		if defaults != nil {
+			resolved = defaults.DeepCopy()
+			break
+		}
+	}
+	if resolved == nil {
+		return nil
+	}
+
+	for _, limit := range []*kubelbv1alpha1.ConnectionRateLimit{tenant.Spec.LoadBalancer.MaxConnectionRateLimit, config.Spec.LoadBalancer.MaxConnectionRateLimit} {
+		if limit == nil {
+			continue
+		}
+		resolved.MaxTokens = min(resolved.MaxTokens, limit.MaxTokens)
+		if getConnectionRate(resolved) > getConnectionRate(limit) {
+			resolved.TokensPerFill = ptr.To(ptr.Deref(limit.TokensPerFill, 1))
+			resolved.FillInterval = limit.FillInterval
+		}
+	}
+	return resolved
+}
+
+// getConnectionRate returns the number of connections per second that a rate limit allows once its bucket is empty.
+func getConnectionRate(rateLimit *kubelbv1alpha1.ConnectionRateLimit) float64 {
+	return float64(ptr.Deref(rateLimit.TokensPerFill, 1)) / rateLimit.FillInterval.Seconds()
+}
+
+func defaultLimit(value, defaultValue *uint32) *uint32 {
+	if value != nil || defaultValue == nil {
+		return value
+	}
+	return ptr.To(*defaultValue)
+}
+
+func capLimit(value, limit *uint32) *uint32 {
+	if limit == nil {
+		return value
+	}
+	if value == nil || *value > *limit {
+		return ptr.To(*limit)
+	}
+	return value
+}
+
+// PatchStatusConditions applies mutate to the conditions of the latest version of a LoadBalancer or a Route. The status is only patched if
+// mutate reports a change.
+func PatchStatusConditions(ctx context.Context, client ctrlclient.Client, obj ctrlclient.Object, mutate func(conditions *[]metav1.Condition) bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var current ctrlclient.Object
+		var conditions *[]metav1.Condition
+		switch obj.(type) {
+		case *kubelbv1alpha1.LoadBalancer:
+			lb := &kubelbv1alpha1.LoadBalancer{}
+			current, conditions = lb, &lb.Status.Conditions
+		case *kubelbv1alpha1.Route:
+			route := &kubelbv1alpha1.Route{}
+			current, conditions = route, &route.Status.Conditions
+		default:
+			return fmt.Errorf("unsupported object %T", obj)
+		}
+
+		if err := client.Get(ctx, ctrlclient.ObjectKeyFromObject(obj), current); err != nil {
+			return ctrlclient.IgnoreNotFound(err)
+		}
+		original := current.DeepCopyObject().(ctrlclient.Object)
+		if !mutate(conditions) {
+			return nil
+		}
+		return client.Status().Patch(ctx, current, ctrlclient.MergeFrom(original))
+	})
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestGetProxyProtocol(t *testing.T) {
+	testCases := []struct {
+		name          string
+		proxyProtocol *kubelbv1alpha1.ProxyProtocol
+		tenant        *kubelbv1alpha1.ProxyProtocol
+		config        *kubelbv1alpha1.ProxyProtocol
+		expected      *kubelbv1alpha1.ProxyProtocol
+	}{
+		{
+			name: "no PROXY protocol",
+		},
+		{
+			name:     "fields are merged with the precedence LoadBalancer > Tenant > Config",
+			tenant:   &kubelbv1alpha1.ProxyProtocol{Upstream: kubelbv1alpha1.ProxyProtocolVersionV2},
+			config:   &kubelbv1alpha1.ProxyProtocol{Upstream: kubelbv1alpha1.ProxyProtocolVersionV1, Downstream: ptr.To(true)},
+			expected: &kubelbv1alpha1.ProxyProtocol{Upstream: kubelbv1alpha1.ProxyProtocolVersionV2, Downstream: ptr.To(true)},
+		},
+		{
+			name:          "LoadBalancer opts out of downstream PROXY protocol",
+			proxyProtocol: &kubelbv1alpha1.ProxyProtocol{Downstream: ptr.To(false)},
+			tenant:        &kubelbv1alpha1.ProxyProtocol{Downstream: ptr.To(true)},
+			expected:      &kubelbv1alpha1.ProxyProtocol{Downstream: ptr.To(false)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tenant := &kubelbv1alpha1.Tenant{}
+			tenant.Spec.LoadBalancer.ProxyProtocol = tc.tenant
+			config := &kubelbv1alpha1.Config{}
+			config.Spec.LoadBalancer.ProxyProtocol = tc.config
+
+			if proxyProtocol := GetProxyProtocol(tc.proxyProtocol, tenant, config); !reflect.DeepEqual(proxyProtocol, tc.expected) {
+				t.Errorf("expected PROXY protocol %v, got %v", tc.expected, proxyProtocol)
+			}
+		})
+	}
+}
+
+func TestGetCircuitBreakers(t *testing.T) {
+	testCases := []struct {
+		name            string
+		circuitBreakers *kubelbv1alpha1.CircuitBreakers
+		tenantDefault   *kubelbv1alpha1.CircuitBreakers
+		configDefault   *kubelbv1alpha1.CircuitBreakers
+		tenantLimits    *kubelbv1alpha1.CircuitBreakers
+		configLimits    *kubelbv1alpha1.CircuitBreakers
+		expected        *kubelbv1alpha1.CircuitBreakers
+	}{
+		{
+			name: "no circuit breakers",
+		},
+		{
+			name:          "missing values are defaulted from the Tenant and then the Config",
+			tenantDefault: &kubelbv1alpha1.CircuitBreakers{MaxConnections: ptr.To[uint32](100)},
+			configDefault: &kubelbv1alpha1.CircuitBreakers{MaxConnections: ptr.To[uint32](10), MaxRetries: ptr.To[uint32](3)},
+			expected:      &kubelbv1alpha1.CircuitBreakers{MaxConnections: ptr.To[uint32](100), MaxRetries: ptr.To[uint32](3)},
+		},
+		{
+			name:            "values are capped to the lowest limit and missing values are set to it",
+			circuitBreakers: &kubelbv1alpha1.CircuitBreakers{MaxConnections: ptr.To[uint32](1000), MaxRetries: ptr.To[uint32](1)},
+			tenantLimits:    &kubelbv1alpha1.CircuitBreakers{MaxConnections: ptr.To[uint32](500)},
+			configLimits:    &kubelbv1alpha1.CircuitBreakers{MaxConnections: ptr.To[uint32](200), MaxPendingRequests: ptr.To[uint32](50), MaxRetries: ptr.To[uint32](5)},
+			expected:        &kubelbv1alpha1.CircuitBreakers{MaxConnections: ptr.To[uint32](200), MaxPendingRequests: ptr.To[uint32](50), MaxRetries: ptr.To[uint32](1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tenant := &kubelbv1alpha1.Tenant{}
+			tenant.Spec.LoadBalancer.CircuitBreakers = tc.tenantDefault
+			tenant.Spec.LoadBalancer.CircuitBreakerLimits = tc.tenantLimits
+			config := &kubelbv1alpha1.Config{}
+			config.Spec.LoadBalancer.CircuitBreakers = tc.configDefault
+			config.Spec.LoadBalancer.CircuitBreakerLimits = tc.configLimits
+
+			if circuitBreakers := GetCircuitBreakers(tc.circuitBreakers, tenant, config); !reflect.DeepEqual(circuitBreakers, tc.expected) {
+				t.Errorf("expected circuit breakers %v, got %v", tc.expected, circuitBreakers)
+			}
+		})
+	}
+}
+
+func TestGetTCPSettings(t *testing.T) {
+	second := &metav1.Duration{Duration: time.Second}
+	minute := &metav1.Duration{Duration: time.Minute}
+
+	testCases := []struct {
+		name     string
+		tcp      *kubelbv1alpha1.TCPSettings
+		tenant   *kubelbv1alpha1.TCPSettings
+		config   *kubelbv1alpha1.TCPSettings
+		expected *kubelbv1alpha1.TCPSettings
+	}{
+		{
+			name: "no TCP settings",
+		},
+		{
+			name:     "missing values are defaulted from the Tenant and then the Config",
+			tcp:      &kubelbv1alpha1.TCPSettings{IdleTimeout: minute},
+			tenant:   &kubelbv1alpha1.TCPSettings{IdleTimeout: second, ConnectTimeout: second},
+			config:   &kubelbv1alpha1.TCPSettings{ConnectTimeout: minute, MaxConnectionDuration: minute},
+			expected: &kubelbv1alpha1.TCPSettings{IdleTimeout: minute, ConnectTimeout: second, MaxConnectionDuration: minute},
+		},
+		{
+			name:     "keepalive is merged field by field",
+			tcp:      &kubelbv1alpha1.TCPSettings{Keepalive: &kubelbv1alpha1.TCPKeepalive{Time: minute}},
+			tenant:   &kubelbv1alpha1.TCPSettings{Keepalive: &kubelbv1alpha1.TCPKeepalive{Probes: ptr.To[uint32](3)}},
+			config:   &kubelbv1alpha1.TCPSettings{Keepalive: &kubelbv1alpha1.TCPKeepalive{Time: second, Interval: second}},
+			expected: &kubelbv1alpha1.TCPSettings{Keepalive: &kubelbv1alpha1.TCPKeepalive{Probes: ptr.To[uint32](3), Time: minute, Interval: second}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tenant := &kubelbv1alpha1.Tenant{}
+			tenant.Spec.LoadBalancer.TCP = tc.tenant
+			config := &kubelbv1alpha1.Config{}
+			config.Spec.LoadBalancer.TCP = tc.config
+
+			if tcp := GetTCPSettings(tc.tcp, tenant, config); !reflect.DeepEqual(tcp, tc.expected) {
+				t.Errorf("expected TCP settings %v, got %v", tc.expected, tcp)
+			}
+		})
+	}
+}
+
+func TestGetDNSSettings(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dns      *kubelbv1alpha1.DNSSettings
+		tenant   *kubelbv1alpha1.DNSSettings
+		config   *kubelbv1alpha1.DNSSettings
+		expected *kubelbv1alpha1.DNSSettings
+	}{
+		{
+			name: "no DNS settings",
+		},
+		{
+			name:   "missing values are defaulted from the Tenant and then the Config",
+			dns:    &kubelbv1alpha1.DNSSettings{LookupFamily: kubelbv1alpha1.DNSLookupFamilyV6Only},
+			tenant: &kubelbv1alpha1.DNSSettings{Resolution: kubelbv1alpha1.DNSResolutionStrict, LookupFamily: kubelbv1alpha1.DNSLookupFamilyV4Only},
+			config: &kubelbv1alpha1.DNSSettings{Resolution: kubelbv1alpha1.DNSResolutionLogical, RespectDNSTTL: ptr.To(true)},
+			expected: &kubelbv1alpha1.DNSSettings{
+				Resolution:    kubelbv1alpha1.DNSResolutionStrict,
+				LookupFamily:  kubelbv1alpha1.DNSLookupFamilyV6Only,
+				RespectDNSTTL: ptr.To(true),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tenant := &kubelbv1alpha1.Tenant{}
+			tenant.Spec.LoadBalancer.DNS = tc.tenant
+			config := &kubelbv1alpha1.Config{}
+			config.Spec.LoadBalancer.DNS = tc.config
+
+			if dns := GetDNSSettings(tc.dns, tenant, config); !reflect.DeepEqual(dns, tc.expected) {
+				t.Errorf("expected DNS settings %v, got %v", tc.expected, dns)
+			}
+		})
+	}
+}
+
+func newConnectionRateLimit(maxTokens, tokensPerFill uint32, fillInterval time.Duration) *kubelbv1alpha1.ConnectionRateLimit {
+	return &kubelbv1alpha1.ConnectionRateLimit{
+		MaxTokens:     maxTokens,
+		TokensPerFill: ptr.To(tokensPerFill),
+		FillInterval:  metav1.Duration{Duration: fillInterval},
+	}
+}
+
+func TestGetConnectionRateLimit(t *testing.T) {
+	testCases := []struct {
+		name          string
+		rateLimit     *kubelbv1alpha1.ConnectionRateLimit
+		tenantDefault *kubelbv1alpha1.ConnectionRateLimit
+		tenantMax     *kubelbv1alpha1.ConnectionRateLimit
+		configMax     *kubelbv1alpha1.ConnectionRateLimit
+		expected      *kubelbv1alpha1.ConnectionRateLimit
+	}{
+		{
+			name: "no rate limit",
+		},
+		{
+			name:      "maximum alone doesn't limit the port",
+			configMax: newConnectionRateLimit(10, 1, time.Second),
+		},
+		{
+			name:          "default of the Tenant",
+			tenantDefault: newConnectionRateLimit(100, 10, time.Second),
+			expected:      newConnectionRateLimit(100, 10, time.Second),
+		},
+		{
+			name:      "rate limit within the maximum",
+			rateLimit: newConnectionRateLimit(100, 1, time.Second),
+			configMax: newConnectionRateLimit(200, 10, time.Second),
+			expected:  newConnectionRateLimit(100, 1, time.Second),
+		},
+		{
+			name:      "faster rate is clamped to the rate of the maximum",
+			rateLimit: newConnectionRateLimit(100, 1, 100*time.Millisecond),
+			configMax: newConnectionRateLimit(200, 5, time.Second),
+			expected:  newConnectionRateLimit(100, 5, time.Second),
+		},
+		{
+			name:      "slower rate with more tokens per fill is kept",
+			rateLimit: newConnectionRateLimit(100, 10, 10*time.Second),
+			configMax: newConnectionRateLimit(200, 5, time.Second),
+			expected:  newConnectionRateLimit(100, 10, 10*time.Second),
+		},
+		{
+			name:      "both maximums are enforced",
+			rateLimit: newConnectionRateLimit(100, 10, time.Second),
+			tenantMax: newConnectionRateLimit(50, 20, time.Second),
+			configMax: newConnectionRateLimit(200, 5, time.Second),
+			expected:  newConnectionRateLimit(50, 5, time.Second),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tenant := &kubelbv1alpha1.Tenant{}
+			tenant.Spec.LoadBalancer.ConnectionRateLimit = tc.tenantDefault
+			tenant.Spec.LoadBalancer.MaxConnectionRateLimit = tc.tenantMax
+			config := &kubelbv1alpha1.Config{}
+			config.Spec.LoadBalancer.MaxConnectionRateLimit = tc.configMax
+
+			if rateLimit := GetConnectionRateLimit(tc.rateLimit, tenant, config); !reflect.DeepEqual(rateLimit, tc.expected) {
+				t.Errorf("expected rate limit %v, got %v", tc.expected, rateLimit)
+			}
+		})
+	}
+}
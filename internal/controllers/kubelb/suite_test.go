@@ -87,7 +87,7 @@ var _ = BeforeSuite(func() {
 	sigCtx := ctrl.SetupSignalHandler()
 	ctx, cancel = context.WithCancel(sigCtx)
 
-	envoyServer, err = envoy.NewServer(":8001", true)
+	envoyServer, err = envoy.NewServer(":8001", envoy.XDSModeDeltaADS, true)
 
 	Expect(err).ToNot(HaveOccurred())
 
@@ -147,9 +147,11 @@ var _ = BeforeSuite(func() {
 		Client:             k8sManager.GetClient(),
 		EnvoyCache:         envoyServer.Cache,
 		EnvoyProxyTopology: EnvoyProxyTopologyShared,
-		EnvoyBootstrap:     envoyServer.GenerateBootstrap(),
+		EnvoyBootstrap:     envoyServer.GenerateBootstrap(nil, false),
 		Namespace:          LBNamespace,
 		PortAllocator:      portAllocator,
+		SnapshotHistory:    envoy.NewSnapshotHistory(),
+		Recorder:           k8sManager.GetEventRecorderFor(EnvoyCPControllerName),
 	}
 	err = ecpr.SetupWithManager(ctx, k8sManager)
 	Expect(err).ToNot(HaveOccurred())
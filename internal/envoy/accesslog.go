@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	envoyAccessLog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoyFileAccessLog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
+	envoytypev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+)
+
+const (
+	accessLogPath = "/dev/stdout"
+	// accessLogSamplingRuntimeKey can be used to override the sampling percentage at runtime.
+	accessLogSamplingRuntimeKey = "kubelb.access_log.sampling"
+)
+
+// defaultAccessLogFields are the fields that are logged for each TCP connection or UDP session.
+var defaultAccessLogFields = []struct {
+	name  string
+	value string
+}{
+	{"start_time", "%START_TIME%"},
+	{"downstream_remote_address", "%DOWNSTREAM_REMOTE_ADDRESS%"},
+	{"downstream_local_address", "%DOWNSTREAM_LOCAL_ADDRESS%"},
+	{"upstream_host", "%UPSTREAM_HOST%"},
+	{"upstream_cluster", "%UPSTREAM_CLUSTER%"},
+	{"bytes_received", "%BYTES_RECEIVED%"},
+	{"bytes_sent", "%BYTES_SENT%"},
+	{"duration", "%DURATION%"},
+	{"response_flags", "%RESPONSE_FLAGS%"},
+}
+
+// accessLogLabels identify the tenant and the load balancer that an access log entry belongs to.
+type accessLogLabels struct {
+	tenant          string
+	loadBalancer    string
+	originNamespace string
+	originName      string
+}
+
+func (l accessLogLabels) fields() map[string]string {
+	return map[string]string{
+		"tenant":           l.tenant,
+		"load_balancer":    l.loadBalancer,
+		"origin_namespace": l.originNamespace,
+		"origin_name":      l.originName,
+	}
+}
+
+// makeAccessLogs generates the access logs for a listener. Access logging is enabled with the text format by default.
+func makeAccessLogs(accessLog *kubelbv1alpha1.AccessLog, labels accessLogLabels) []*envoyAccessLog.AccessLog {
+	if accessLog == nil {
+		accessLog = &kubelbv1alpha1.AccessLog{}
+	}
+	if accessLog.Disable {
+		return nil
+	}
+
+	fileAccessLog := &envoyFileAccessLog.FileAccessLog{
+		Path: accessLogPath,
+	}
+	if accessLog.Format == kubelbv1alpha1.AccessLogFormatJSON {
+		fileAccessLog.AccessLogFormat = &envoyFileAccessLog.FileAccessLog_LogFormat{
+			LogFormat: makeJSONLogFormat(accessLog.Fields, labels),
+		}
+	} else {
+		fileAccessLog.AccessLogFormat = &envoyFileAccessLog.FileAccessLog_LogFormat{
+			LogFormat: makeTextLogFormat(accessLog.Fields, labels),
+		}
+	}
+
+	fileAccessLogAny, err := anypb.New(fileAccessLog)
+	if err != nil {
+		panic(err)
+	}
+
+	log := &envoyAccessLog.AccessLog{
+		Name: wellknown.FileAccessLog,
+		ConfigType: &envoyAccessLog.AccessLog_TypedConfig{
+			TypedConfig: fileAccessLogAny,
+		},
+	}
+
+	if accessLog.SamplingPercentage != nil && *accessLog.SamplingPercentage < 100 {
+		log.Filter = &envoyAccessLog.AccessLogFilter{
+			FilterSpecifier: &envoyAccessLog.AccessLogFilter_RuntimeFilter{
+				RuntimeFilter: &envoyAccessLog.RuntimeFilter{
+					RuntimeKey: accessLogSamplingRuntimeKey,
+					PercentSampled: &envoytypev3.FractionalPercent{
+						Numerator:   uint32(*accessLog.SamplingPercentage),
+						Denominator: envoytypev3.FractionalPercent_HUNDRED,
+					},
+					// L4 connections don't have a request ID that could be used for sampling.
+					UseIndependentRandomness: true,
+				},
+			},
+		}
+	}
+
+	return []*envoyAccessLog.AccessLog{log}
+}
+
+// makeTextLogFormat generates a text format with the default fields followed by the labels and the custom fields as key=value pairs.
+func makeTextLogFormat(fields map[string]string, labels accessLogLabels) *envoyCore.SubstitutionFormatString {
+	var builder strings.Builder
+	for i, field := range defaultAccessLogFields {
+		if i > 0 {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(fmt.Sprintf("%s=%s", field.name, field.value))
+	}
+	for _, fieldsMap := range []map[string]string{labels.fields(), fields} {
+		keys := make([]string, 0, len(fieldsMap))
+		for key := range fieldsMap {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			builder.WriteString(fmt.Sprintf(" %s=%s", key, fieldsMap[key]))
+		}
+	}
+	builder.WriteString("\n")
+
+	return &envoyCore.SubstitutionFormatString{
+		Format: &envoyCore.SubstitutionFormatString_TextFormatSource{
+			TextFormatSource: &envoyCore.DataSource{
+				Specifier: &envoyCore.DataSource_InlineString{
+					InlineString: builder.String(),
+				},
+			},
+		},
+	}
+}
+
+// makeJSONLogFormat generates a JSON format with the default fields, the labels and the custom fields. Custom fields can't override the labels.
+func makeJSONLogFormat(fields map[string]string, labels accessLogLabels) *envoyCore.SubstitutionFormatString {
+	jsonFields := make(map[string]interface{})
+	for _, field := range defaultAccessLogFields {
+		jsonFields[field.name] = field.value
+	}
+	for key, value := range fields {
+		jsonFields[key] = value
+	}
+	for key, value := range labels.fields() {
+		jsonFields[key] = value
+	}
+
+	jsonFormat, err := structpb.NewStruct(jsonFields)
+	if err != nil {
+		panic(err)
+	}
+
+	return &envoyCore.SubstitutionFormatString{
+		Format: &envoyCore.SubstitutionFormatString_JsonFormat{
+			JsonFormat: jsonFormat,
+		},
+	}
+}
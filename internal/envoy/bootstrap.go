@@ -17,75 +17,65 @@ limitations under the License.
 package envoy
 
 import (
+	"fmt"
 	"time"
 
 	envoyBootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
 	envoyCluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoyEndpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoyListener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoyRoute "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoyRouter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	envoyHCM "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 const xdsClusterName = "xds_cluster"
 
 const controlPlaneAddress = "envoycp.kubelb.svc"
 
-func (s *Server) GenerateBootstrap() string {
-	var adminCfg *envoyBootstrap.Admin
+const (
+	adminClusterName = "admin_cluster"
+	adminPort        = 9001
+	statsListener    = "stats_listener"
+	statsPath        = "/stats/prometheus"
+	clustersPath     = "/clusters"
+	// StatsPort is the port of the Envoy Proxy that serves the statistics in the Prometheus format, including the circuit breaker
+	// overflow counters of the clusters.
+	StatsPort = 19001
+)
+
+// GenerateBootstrap returns the bootstrap configuration of the Envoy proxies. The stats listener binds to the IP families of the proxies,
+// the status of the clusters is only exposed on it if exposeClusterStatus is true.
+func (s *Server) GenerateBootstrap(ipFamilies []corev1.IPFamily, exposeClusterStatus bool) string {
+	// The admin interface is always enabled to serve the statistics, but it's only reachable from outside the pod in debug mode.
+	adminAddress := "127.0.0.1"
 	if s.enableAdmin {
-		adminCfg = &envoyBootstrap.Admin{
-			Address: &envoyCore.Address{
-				Address: &envoyCore.Address_SocketAddress{SocketAddress: &envoyCore.SocketAddress{
-					Address: "0.0.0.0",
-					PortSpecifier: &envoyCore.SocketAddress_PortValue{
-						PortValue: 9001,
-					},
-				}},
-			},
-		}
+		adminAddress = "0.0.0.0"
+	}
+	adminCfg := &envoyBootstrap.Admin{
+		Address: makeSocketAddress(adminAddress, adminPort),
 	}
 
 	cfg := &envoyBootstrap.Bootstrap{
-		DynamicResources: &envoyBootstrap.Bootstrap_DynamicResources{
-			LdsConfig: &envoyCore.ConfigSource{
-				ResourceApiVersion: envoyCore.ApiVersion_V3,
-				ConfigSourceSpecifier: &envoyCore.ConfigSource_ApiConfigSource{
-					ApiConfigSource: &envoyCore.ApiConfigSource{
-						ApiType:                   envoyCore.ApiConfigSource_GRPC,
-						TransportApiVersion:       envoyCore.ApiVersion_V3,
-						SetNodeOnFirstMessageOnly: true,
-						GrpcServices: []*envoyCore.GrpcService{
-							{
-								TargetSpecifier: &envoyCore.GrpcService_EnvoyGrpc_{
-									EnvoyGrpc: &envoyCore.GrpcService_EnvoyGrpc{
-										ClusterName: xdsClusterName,
-									}},
-							},
-						},
-					},
-				},
-			},
-			CdsConfig: &envoyCore.ConfigSource{
-				ResourceApiVersion: envoyCore.ApiVersion_V3,
-				ConfigSourceSpecifier: &envoyCore.ConfigSource_ApiConfigSource{
-					ApiConfigSource: &envoyCore.ApiConfigSource{
-						ApiType:                   envoyCore.ApiConfigSource_GRPC,
-						TransportApiVersion:       envoyCore.ApiVersion_V3,
-						SetNodeOnFirstMessageOnly: true,
-						GrpcServices: []*envoyCore.GrpcService{
-							{
-								TargetSpecifier: &envoyCore.GrpcService_EnvoyGrpc_{
-									EnvoyGrpc: &envoyCore.GrpcService_EnvoyGrpc{
-										ClusterName: xdsClusterName,
-									}},
-							},
-						},
-					},
-				},
+		// The locality is taken from the environment of the Envoy proxy pod, see ProxyZoneEnvVar.
+		Node: &envoyCore.Node{
+			Locality: &envoyCore.Locality{
+				Region: fmt.Sprintf("$(%s)", ProxyRegionEnvVar),
+				Zone:   fmt.Sprintf("$(%s)", ProxyZoneEnvVar),
 			},
 		},
+		ClusterManager: &envoyBootstrap.ClusterManager{
+			LocalClusterName: localClusterName,
+		},
+		DynamicResources: s.makeDynamicResources(),
 		StaticResources: &envoyBootstrap.Bootstrap_StaticResources{
 			Clusters: []*envoyCluster.Cluster{{
 				Name:                 xdsClusterName,
@@ -136,7 +126,8 @@ func (s *Server) GenerateBootstrap() string {
 						},
 					},
 				},
-			}},
+			}, makeAdminCluster(), s.makeLocalCluster()},
+			Listeners: []*envoyListener.Listener{makeStatsListener(ipFamilies, exposeClusterStatus)},
 		},
 		Admin: adminCfg,
 	}
@@ -148,3 +139,188 @@ func (s *Server) GenerateBootstrap() string {
 
 	return string(jsonBytes)
 }
+
+func makeSocketAddress(address string, port uint32) *envoyCore.Address {
+	return &envoyCore.Address{
+		Address: &envoyCore.Address_SocketAddress{SocketAddress: &envoyCore.SocketAddress{
+			Address: address,
+			PortSpecifier: &envoyCore.SocketAddress_PortValue{
+				PortValue: port,
+			},
+		}},
+	}
+}
+
+// makeAdminCluster generates a cluster that points to the admin interface of the Envoy Proxy itself.
+func makeAdminCluster() *envoyCluster.Cluster {
+	return &envoyCluster.Cluster{
+		Name:                 adminClusterName,
+		ConnectTimeout:       durationpb.New(1 * time.Second),
+		ClusterDiscoveryType: &envoyCluster.Cluster_Type{Type: envoyCluster.Cluster_STATIC},
+		LoadAssignment: &envoyEndpoint.ClusterLoadAssignment{
+			ClusterName: adminClusterName,
+			Endpoints: []*envoyEndpoint.LocalityLbEndpoints{
+				{
+					LbEndpoints: []*envoyEndpoint.LbEndpoint{
+						{
+							HostIdentifier: &envoyEndpoint.LbEndpoint_Endpoint{
+								Endpoint: &envoyEndpoint.Endpoint{
+									Address: makeSocketAddress("127.0.0.1", adminPort),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// makeLocalCluster generates the cluster of the Envoy proxies that share a snapshot, it's used for zone-aware routing. The endpoints are
+// served by the control plane.
+func (s *Server) makeLocalCluster() *envoyCluster.Cluster {
+	edsConfig := &envoyCore.ConfigSource{
+		ResourceApiVersion: envoyCore.ApiVersion_V3,
+		ConfigSourceSpecifier: &envoyCore.ConfigSource_Ads{
+			Ads: &envoyCore.AggregatedConfigSource{},
+		},
+	}
+	if s.xdsMode == XDSModeSotW {
+		edsConfig.ConfigSourceSpecifier = &envoyCore.ConfigSource_ApiConfigSource{
+			ApiConfigSource: makeXDSAPIConfigSource(envoyCore.ApiConfigSource_GRPC),
+		}
+	}
+
+	return &envoyCluster.Cluster{
+		Name:                 localClusterName,
+		ConnectTimeout:       durationpb.New(1 * time.Second),
+		ClusterDiscoveryType: &envoyCluster.Cluster_Type{Type: envoyCluster.Cluster_EDS},
+		EdsClusterConfig: &envoyCluster.Cluster_EdsClusterConfig{
+			EdsConfig: edsConfig,
+		},
+	}
+}
+
+// makeStatsListener generates a listener that only exposes the Prometheus statistics and, if enabled, the status of the clusters of the
+// admin interface. This way the statistics can be scraped without exposing the rest of the admin interface.
+func makeStatsListener(ipFamilies []corev1.IPFamily, exposeClusterStatus bool) *envoyListener.Listener {
+	router, err := anypb.New(&envoyRouter.Router{})
+	if err != nil {
+		panic(err)
+	}
+
+	routes := []*envoyRoute.Route{makeAdminRoute(statsPath)}
+	if exposeClusterStatus {
+		routes = append(routes, makeAdminRoute(clustersPath))
+	}
+
+	manager := &envoyHCM.HttpConnectionManager{
+		StatPrefix: statsListener,
+		RouteSpecifier: &envoyHCM.HttpConnectionManager_RouteConfig{
+			RouteConfig: &envoyRoute.RouteConfiguration{
+				Name: statsListener,
+				VirtualHosts: []*envoyRoute.VirtualHost{
+					{
+						Name:    statsListener,
+						Domains: []string{"*"},
+						Routes:  routes,
+					},
+				},
+			},
+		},
+		HttpFilters: []*envoyHCM.HttpFilter{
+			{
+				Name:       wellknown.Router,
+				ConfigType: &envoyHCM.HttpFilter_TypedConfig{TypedConfig: router},
+			},
+		},
+	}
+	managerAny, err := anypb.New(manager)
+	if err != nil {
+		panic(err)
+	}
+
+	return &envoyListener.Listener{
+		Name:    statsListener,
+		Address: makeListenerAddress(envoyCore.SocketAddress_TCP, StatsPort, ipFamilies),
+		FilterChains: []*envoyListener.FilterChain{
+			{
+				Filters: []*envoyListener.Filter{
+					{
+						Name:       wellknown.HTTPConnectionManager,
+						ConfigType: &envoyListener.Filter_TypedConfig{TypedConfig: managerAny},
+					},
+				},
+			},
+		},
+	}
+}
+
+// makeAdminRoute generates a route that forwards the requests for a path to the admin interface. The query parameters are not part of the
+// path and are forwarded as well.
+func makeAdminRoute(path string) *envoyRoute.Route {
+	return &envoyRoute.Route{
+		Match: &envoyRoute.RouteMatch{
+			PathSpecifier: &envoyRoute.RouteMatch_Path{Path: path},
+		},
+		Action: &envoyRoute.Route_Route{
+			Route: &envoyRoute.RouteAction{
+				ClusterSpecifier: &envoyRoute.RouteAction_Cluster{Cluster: adminClusterName},
+			},
+		},
+	}
+}
+
+// makeDynamicResources configures how Envoy fetches the dynamic resources from the control plane. With ADS, all the resources are
+// fetched over a single stream which guarantees the ordering of the updates.
+func (s *Server) makeDynamicResources() *envoyBootstrap.Bootstrap_DynamicResources {
+	if s.xdsMode == XDSModeSotW {
+		return &envoyBootstrap.Bootstrap_DynamicResources{
+			LdsConfig: &envoyCore.ConfigSource{
+				ResourceApiVersion: envoyCore.ApiVersion_V3,
+				ConfigSourceSpecifier: &envoyCore.ConfigSource_ApiConfigSource{
+					ApiConfigSource: makeXDSAPIConfigSource(envoyCore.ApiConfigSource_GRPC),
+				},
+			},
+			CdsConfig: &envoyCore.ConfigSource{
+				ResourceApiVersion: envoyCore.ApiVersion_V3,
+				ConfigSourceSpecifier: &envoyCore.ConfigSource_ApiConfigSource{
+					ApiConfigSource: makeXDSAPIConfigSource(envoyCore.ApiConfigSource_GRPC),
+				},
+			},
+		}
+	}
+
+	apiType := envoyCore.ApiConfigSource_GRPC
+	if s.xdsMode == XDSModeDeltaADS {
+		apiType = envoyCore.ApiConfigSource_DELTA_GRPC
+	}
+
+	adsConfigSource := &envoyCore.ConfigSource{
+		ResourceApiVersion: envoyCore.ApiVersion_V3,
+		ConfigSourceSpecifier: &envoyCore.ConfigSource_Ads{
+			Ads: &envoyCore.AggregatedConfigSource{},
+		},
+	}
+	return &envoyBootstrap.Bootstrap_DynamicResources{
+		AdsConfig: makeXDSAPIConfigSource(apiType),
+		LdsConfig: adsConfigSource,
+		CdsConfig: adsConfigSource,
+	}
+}
+
+func makeXDSAPIConfigSource(apiType envoyCore.ApiConfigSource_ApiType) *envoyCore.ApiConfigSource {
+	return &envoyCore.ApiConfigSource{
+		ApiType:                   apiType,
+		TransportApiVersion:       envoyCore.ApiVersion_V3,
+		SetNodeOnFirstMessageOnly: true,
+		GrpcServices: []*envoyCore.GrpcService{
+			{
+				TargetSpecifier: &envoyCore.GrpcService_EnvoyGrpc_{
+					EnvoyGrpc: &envoyCore.GrpcService_EnvoyGrpc{
+						ClusterName: xdsClusterName,
+					}},
+			},
+		},
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"reflect"
+	"testing"
+
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoyHCM "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMakeStatsListener(t *testing.T) {
+	testCases := []struct {
+		name                string
+		ipFamilies          []corev1.IPFamily
+		exposeClusterStatus bool
+		address             string
+		paths               []string
+	}{
+		{
+			name:    "statistics only",
+			address: "0.0.0.0",
+			paths:   []string{statsPath},
+		},
+		{
+			name:                "cluster status is exposed",
+			exposeClusterStatus: true,
+			address:             "0.0.0.0",
+			paths:               []string{statsPath, clustersPath},
+		},
+		{
+			name:       "IPv6",
+			ipFamilies: []corev1.IPFamily{corev1.IPv6Protocol},
+			address:    "::",
+			paths:      []string{statsPath},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := makeStatsListener(tc.ipFamilies, tc.exposeClusterStatus)
+
+			socketAddress := listener.GetAddress().GetSocketAddress()
+			if socketAddress.GetAddress() != tc.address || socketAddress.GetPortValue() != StatsPort {
+				t.Errorf("expected the listener to bind to %s:%d, got %s:%d", tc.address, StatsPort, socketAddress.GetAddress(), socketAddress.GetPortValue())
+			}
+
+			manager := &envoyHCM.HttpConnectionManager{}
+			if err := listener.GetFilterChains()[0].GetFilters()[0].GetTypedConfig().UnmarshalTo(manager); err != nil {
+				t.Fatalf("failed to unmarshal the HTTP connection manager: %v", err)
+			}
+			var paths []string
+			for _, route := range manager.GetRouteConfig().GetVirtualHosts()[0].GetRoutes() {
+				paths = append(paths, route.GetMatch().GetPath())
+			}
+			if !reflect.DeepEqual(paths, tc.paths) {
+				t.Errorf("expected the paths %v to be exposed, got %v", tc.paths, paths)
+			}
+		})
+	}
+}
+
+func TestMakeDynamicResources(t *testing.T) {
+	testCases := []struct {
+		name    string
+		xdsMode XDSMode
+		ads     bool
+		apiType envoyCore.ApiConfigSource_ApiType
+		wantErr bool
+	}{
+		{
+			name:    "incremental ADS",
+			xdsMode: XDSModeDeltaADS,
+			ads:     true,
+			apiType: envoyCore.ApiConfigSource_DELTA_GRPC,
+		},
+		{
+			name:    "state of the world ADS",
+			xdsMode: XDSModeADS,
+			ads:     true,
+			apiType: envoyCore.ApiConfigSource_GRPC,
+		},
+		{
+			name:    "state of the world per resource type",
+			xdsMode: XDSModeSotW,
+			apiType: envoyCore.ApiConfigSource_GRPC,
+		},
+		{
+			name:    "unknown mode is rejected",
+			xdsMode: "delta",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, err := NewServer(":8001", tc.xdsMode, false)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected xDS mode %q to be rejected", tc.xdsMode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to create the server: %v", err)
+			}
+
+			dynamicResources := server.makeDynamicResources()
+			if tc.ads {
+				if dynamicResources.GetAdsConfig().GetApiType() != tc.apiType {
+					t.Errorf("expected the ADS config to use %s, got %s", tc.apiType, dynamicResources.GetAdsConfig().GetApiType())
+				}
+			} else if dynamicResources.GetAdsConfig() != nil {
+				t.Errorf("expected no ADS config, got %v", dynamicResources.GetAdsConfig())
+			}
+
+			for _, configSource := range []*envoyCore.ConfigSource{dynamicResources.GetLdsConfig(), dynamicResources.GetCdsConfig(), server.makeLocalCluster().GetEdsClusterConfig().GetEdsConfig()} {
+				if tc.ads {
+					if configSource.GetAds() == nil {
+						t.Errorf("expected the resources to be fetched over ADS, got %v", configSource)
+					}
+					continue
+				}
+				if configSource.GetApiConfigSource().GetApiType() != tc.apiType {
+					t.Errorf("expected the resources to be fetched with %s, got %v", tc.apiType, configSource)
+				}
+			}
+		})
+	}
+}
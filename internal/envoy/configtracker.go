@@ -0,0 +1,359 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/peer"
+
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	xdsRejectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubelb_envoy_xds_rejections_total",
+		Help: "Number of configuration versions that were rejected by the Envoy proxies.",
+	}, []string{"type"})
+	xdsRejectedProxies = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubelb_envoy_xds_rejected_proxies",
+		Help: "Number of Envoy proxy instances connected to this replica that rejected the last configuration version that was sent to them.",
+	}, []string{"type"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(xdsRejectionsTotal, xdsRejectedProxies)
+}
+
+// ConfigState is the state of the configuration of a resource type on the Envoy proxies of a node.
+type ConfigState struct {
+	// Node is the ID of the Envoy proxies.
+	Node string `json:"node"`
+	// TypeURL is the type of the resources.
+	TypeURL string `json:"typeURL"`
+	// Proxies is the number of Envoy proxy instances of the node that are served the resource type.
+	Proxies int `json:"proxies"`
+	// RejectedProxies is the number of Envoy proxy instances that rejected the last version that was sent to them.
+	RejectedProxies int `json:"rejectedProxies,omitempty"`
+	// Rejection is the latest rejection of the instances, it's set if any of them rejected the last version that was sent to it.
+	Rejection *ConfigRejection `json:"rejection,omitempty"`
+}
+
+// ConfigRejection is a version of the configuration that was rejected by an Envoy proxy.
+type ConfigRejection struct {
+	Version string `json:"version"`
+	// Message is the error detail reported by Envoy.
+	Message string `json:"message"`
+	// Resources are the names of the rejected resources, they are parsed from the error detail.
+	Resources []string  `json:"resources,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// ProxyConnection is an Envoy proxy that is connected to this replica of the control plane.
+type ProxyConnection struct {
+	// Node is the ID of the Envoy proxy, it's shared by all the replicas of an Envoy proxy deployment.
+	Node string `json:"node"`
+	// Address is the address of the Envoy proxy instance.
+	Address string `json:"address"`
+	// AckedVersions are the last versions that were accepted by the Envoy proxy instance, keyed by the resource type.
+	AckedVersions map[string]string `json:"ackedVersions"`
+}
+
+type configKey struct {
+	node    string
+	typeURL string
+}
+
+// sentResponse is the last response that was sent on a stream for a resource type. Envoy acknowledges or rejects it with its nonce.
+type sentResponse struct {
+	nonce   string
+	version string
+}
+
+// streamState is the state of an Envoy proxy instance. The replicas of an Envoy proxy deployment share the node ID, so the state is kept
+// per stream.
+type streamState struct {
+	// node is only known once Envoy sent the first request, the following requests on the stream don't contain it.
+	node       string
+	address    string
+	responses  map[string]sentResponse
+	acked      map[string]string
+	rejections map[string]*ConfigRejection
+}
+
+// ConfigTracker records whether the Envoy proxies accepted or rejected the configuration that was sent to them. It's registered as the
+// callbacks of the xDS server.
+type ConfigTracker struct {
+	mu          sync.Mutex
+	streams     map[int64]*streamState
+	changes     chan struct{}
+	ackHandlers []func(node, typeURL, version string)
+}
+
+func NewConfigTracker() *ConfigTracker {
+	return &ConfigTracker{
+		streams: make(map[int64]*streamState),
+		changes: make(chan struct{}, 1),
+	}
+}
+
+// Callbacks returns the xDS server callbacks for both state of the world and incremental xDS.
+func (t *ConfigTracker) Callbacks() serverv3.Callbacks {
+	return serverv3.CallbackFuncs{
+		StreamOpenFunc:        t.onStreamOpen,
+		DeltaStreamOpenFunc:   t.onStreamOpen,
+		StreamClosedFunc:      t.onStreamClosed,
+		DeltaStreamClosedFunc: t.onStreamClosed,
+		StreamRequestFunc: func(streamID int64, req *discovery.DiscoveryRequest) error {
+			t.onRequest(streamID, req.Node, req.TypeUrl, req.ResponseNonce, req.ErrorDetail != nil, req.GetErrorDetail().GetMessage())
+			return nil
+		},
+		StreamResponseFunc: func(_ context.Context, streamID int64, _ *discovery.DiscoveryRequest, resp *discovery.DiscoveryResponse) {
+			t.onResponse(streamID, resp.TypeUrl, resp.Nonce, resp.VersionInfo)
+		},
+		StreamDeltaRequestFunc: func(streamID int64, req *discovery.DeltaDiscoveryRequest) error {
+			t.onRequest(streamID, req.Node, req.TypeUrl, req.ResponseNonce, req.ErrorDetail != nil, req.GetErrorDetail().GetMessage())
+			return nil
+		},
+		StreamDeltaResponseFunc: func(streamID int64, _ *discovery.DeltaDiscoveryRequest, resp *discovery.DeltaDiscoveryResponse) {
+			t.onResponse(streamID, resp.TypeUrl, resp.Nonce, resp.SystemVersionInfo)
+		},
+	}
+}
+
+// OnAck registers a function that is called whenever an Envoy proxy accepted a version of a resource type. It must be registered before
+// the xDS server is started and must not call the tracker.
+func (t *ConfigTracker) OnAck(handler func(node, typeURL, version string)) {
+	t.ackHandlers = append(t.ackHandlers, handler)
+}
+
+// Changes returns a channel that receives a value whenever a rejection is recorded or cleared.
+func (t *ConfigTracker) Changes() <-chan struct{} {
+	return t.changes
+}
+
+// States returns the configuration states of the Envoy proxies that are connected to this replica, aggregated per node and sorted by node
+// and type.
+func (t *ConfigTracker) States() []ConfigState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	aggregated := make(map[configKey]*ConfigState)
+	for _, stream := range t.streams {
+		if stream.node == "" {
+			continue
+		}
+		for typeURL := range stream.responses {
+			key := configKey{node: stream.node, typeURL: typeURL}
+			state, ok := aggregated[key]
+			if !ok {
+				state = &ConfigState{Node: stream.node, TypeURL: typeURL}
+				aggregated[key] = state
+			}
+			state.Proxies++
+
+			rejection, ok := stream.rejections[typeURL]
+			if !ok {
+				continue
+			}
+			state.RejectedProxies++
+			if state.Rejection == nil || rejection.Time.After(state.Rejection.Time) {
+				state.Rejection = rejection.DeepCopy()
+			}
+		}
+	}
+
+	states := make([]ConfigState, 0, len(aggregated))
+	for _, state := range aggregated {
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Node != states[j].Node {
+			return states[i].Node < states[j].Node
+		}
+		return states[i].TypeURL < states[j].TypeURL
+	})
+	return states
+}
+
+// Connections returns the Envoy proxy instances that are connected to this replica, sorted by node and address. An Envoy proxy that uses
+// a separate stream per resource type is reported once per stream.
+func (t *ConfigTracker) Connections() []ProxyConnection {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	connections := make([]ProxyConnection, 0, len(t.streams))
+	for _, stream := range t.streams {
+		if stream.node == "" {
+			continue
+		}
+		connection := ProxyConnection{
+			Node:          stream.node,
+			Address:       stream.address,
+			AckedVersions: make(map[string]string, len(stream.acked)),
+		}
+		for typeURL, version := range stream.acked {
+			connection.AckedVersions[typeLabel(typeURL)] = version
+		}
+		connections = append(connections, connection)
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		if connections[i].Node != connections[j].Node {
+			return connections[i].Node < connections[j].Node
+		}
+		return connections[i].Address < connections[j].Address
+	})
+	return connections
+}
+
+func (t *ConfigTracker) onStreamOpen(ctx context.Context, streamID int64, _ string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if p, ok := peer.FromContext(ctx); ok {
+		t.stream(streamID).address = p.Addr.String()
+	}
+	return nil
+}
+
+func (t *ConfigTracker) onResponse(streamID int64, typeURL, nonce, version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stream(streamID).responses[typeURL] = sentResponse{nonce: nonce, version: version}
+}
+
+func (t *ConfigTracker) onRequest(streamID int64, node *envoyCore.Node, typeURL, nonce string, rejected bool, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stream := t.stream(streamID)
+	if node.GetId() != "" {
+		stream.node = node.GetId()
+	}
+	// Requests without a nonce are the initial requests, requests with an outdated nonce refer to a response that was superseded.
+	sent, ok := stream.responses[typeURL]
+	if nonce == "" || !ok || sent.nonce != nonce {
+		return
+	}
+
+	if rejected {
+		if rejection, ok := stream.rejections[typeURL]; ok && rejection.Version == sent.version {
+			return
+		}
+		stream.rejections[typeURL] = &ConfigRejection{
+			Version:   sent.version,
+			Message:   message,
+			Resources: parseRejectedResources(message),
+			Time:      time.Now(),
+		}
+		xdsRejectionsTotal.WithLabelValues(typeLabel(typeURL)).Inc()
+		t.changed()
+		return
+	}
+
+	stream.acked[typeURL] = sent.version
+	for _, handler := range t.ackHandlers {
+		handler(stream.node, typeURL, sent.version)
+	}
+	if _, ok := stream.rejections[typeURL]; ok {
+		delete(stream.rejections, typeURL)
+		t.changed()
+	}
+}
+
+// onStreamClosed forgets the state of the Envoy proxy instance. Envoy reports the state again once it has reconnected.
+func (t *ConfigTracker) onStreamClosed(streamID int64, _ *envoyCore.Node) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stream, ok := t.streams[streamID]
+	if !ok {
+		return
+	}
+	delete(t.streams, streamID)
+	if len(stream.rejections) > 0 {
+		t.changed()
+	}
+}
+
+func (t *ConfigTracker) stream(streamID int64) *streamState {
+	stream, ok := t.streams[streamID]
+	if !ok {
+		stream = &streamState{
+			responses:  make(map[string]sentResponse),
+			acked:      make(map[string]string),
+			rejections: make(map[string]*ConfigRejection),
+		}
+		t.streams[streamID] = stream
+	}
+	return stream
+}
+
+// changed updates the metrics and notifies the consumers of the changes, it must be called with the lock held.
+func (t *ConfigTracker) changed() {
+	xdsRejectedProxies.Reset()
+	for _, stream := range t.streams {
+		for typeURL := range stream.rejections {
+			xdsRejectedProxies.WithLabelValues(typeLabel(typeURL)).Inc()
+		}
+	}
+
+	select {
+	case t.changes <- struct{}{}:
+	default:
+	}
+}
+
+// DeepCopy returns a copy of the rejection.
+func (r *ConfigRejection) DeepCopy() *ConfigRejection {
+	rejection := *r
+	rejection.Resources = append([]string(nil), r.Resources...)
+	return &rejection
+}
+
+// rejectedResourcePattern matches the names of the resources in the error details of Envoy. Envoy lists each rejected resource with its
+// error, e.g. "Error adding/updating listener(s) foo: error\nbar: error" for listeners and "Error adding/updating cluster(s) foo: error,
+// bar: error" for clusters.
+var rejectedResourcePattern = regexp.MustCompile(`(?:^|\n|, |\(s\) )([A-Za-z0-9._-]+): `)
+
+// parseRejectedResources returns the names of the resources that are listed in the error detail of Envoy.
+func parseRejectedResources(message string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range rejectedResourcePattern.FindAllStringSubmatch(message, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// typeLabel returns the name of a resource type without the package, e.g. Listener or Cluster.
+func typeLabel(typeURL string) string {
+	return typeURL[strings.LastIndex(typeURL, ".")+1:]
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"reflect"
+	"testing"
+
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+func TestConfigTracker(t *testing.T) {
+	node := &envoyCore.Node{Id: "tenant-test"}
+
+	testCases := []struct {
+		name            string
+		run             func(tracker *ConfigTracker)
+		proxies         int
+		rejectedProxies int
+		resources       []string
+	}{
+		{
+			name: "accepted",
+			run: func(tracker *ConfigTracker) {
+				tracker.onResponse(1, resource.ListenerType, "1", "v1")
+				tracker.onRequest(1, node, resource.ListenerType, "1", false, "")
+			},
+			proxies: 1,
+		},
+		{
+			name: "rejected",
+			run: func(tracker *ConfigTracker) {
+				tracker.onResponse(1, resource.ListenerType, "1", "v1")
+				tracker.onRequest(1, node, resource.ListenerType, "1", true, "Error adding/updating listener(s) foo: invalid")
+			},
+			proxies:         1,
+			rejectedProxies: 1,
+			resources:       []string{"foo"},
+		},
+		{
+			name: "acknowledgement of another instance of the node doesn't clear the rejection",
+			run: func(tracker *ConfigTracker) {
+				tracker.onResponse(1, resource.ListenerType, "1", "v1")
+				tracker.onResponse(2, resource.ListenerType, "2", "v1")
+				tracker.onRequest(1, node, resource.ListenerType, "1", true, "Error adding/updating listener(s) foo: invalid")
+				tracker.onRequest(2, node, resource.ListenerType, "2", false, "")
+			},
+			proxies:         2,
+			rejectedProxies: 1,
+			resources:       []string{"foo"},
+		},
+		{
+			name: "closing the stream of another instance of the node keeps the rejection",
+			run: func(tracker *ConfigTracker) {
+				tracker.onResponse(1, resource.ListenerType, "1", "v1")
+				tracker.onResponse(2, resource.ListenerType, "2", "v1")
+				tracker.onRequest(1, node, resource.ListenerType, "1", true, "Error adding/updating listener(s) foo: invalid")
+				tracker.onRequest(2, node, resource.ListenerType, "2", true, "Error adding/updating listener(s) foo: invalid")
+				tracker.onStreamClosed(2, node)
+			},
+			proxies:         1,
+			rejectedProxies: 1,
+			resources:       []string{"foo"},
+		},
+		{
+			name: "acknowledgement of a newer version clears the rejection",
+			run: func(tracker *ConfigTracker) {
+				tracker.onResponse(1, resource.ListenerType, "1", "v1")
+				tracker.onRequest(1, node, resource.ListenerType, "1", true, "Error adding/updating listener(s) foo: invalid")
+				tracker.onResponse(1, resource.ListenerType, "2", "v2")
+				tracker.onRequest(1, nil, resource.ListenerType, "2", false, "")
+			},
+			proxies: 1,
+		},
+		{
+			name: "outdated nonce is ignored",
+			run: func(tracker *ConfigTracker) {
+				tracker.onResponse(1, resource.ListenerType, "1", "v1")
+				tracker.onResponse(1, resource.ListenerType, "2", "v2")
+				tracker.onRequest(1, node, resource.ListenerType, "1", true, "Error adding/updating listener(s) foo: invalid")
+			},
+			proxies: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := NewConfigTracker()
+			tc.run(tracker)
+
+			states := tracker.States()
+			if len(states) != 1 {
+				t.Fatalf("expected a single state, got %v", states)
+			}
+			state := states[0]
+			if state.Node != node.Id || state.Proxies != tc.proxies || state.RejectedProxies != tc.rejectedProxies {
+				t.Errorf("expected %d proxies of %q with %d rejections, got %+v", tc.proxies, node.Id, tc.rejectedProxies, state)
+			}
+			if (state.Rejection != nil) != (tc.rejectedProxies > 0) {
+				t.Fatalf("expected a rejection: %t, got %v", tc.rejectedProxies > 0, state.Rejection)
+			}
+			if state.Rejection != nil && !reflect.DeepEqual(state.Rejection.Resources, tc.resources) {
+				t.Errorf("expected the rejected resources %v, got %v", tc.resources, state.Rejection.Resources)
+			}
+		})
+	}
+}
+
+func TestParseRejectedResources(t *testing.T) {
+	testCases := []struct {
+		name      string
+		message   string
+		resources []string
+	}{
+		{
+			name:      "listeners",
+			message:   "Error adding/updating listener(s) tenant-test-test-ep-0-port-80-TCP: invalid\ntls-passthrough-443: error adding listener '0.0.0.0:443': address already in use\n",
+			resources: []string{"tenant-test-test-ep-0-port-80-TCP", "tls-passthrough-443"},
+		},
+		{
+			name:      "clusters",
+			message:   "Error adding/updating cluster(s) tenant-test-test-ep-0-port-80-TCP: invalid, tenant-test-test-ep-0-port-8080-TCP: invalid",
+			resources: []string{"tenant-test-test-ep-0-port-80-TCP", "tenant-test-test-ep-0-port-8080-TCP"},
+		},
+		{
+			name:    "no resources",
+			message: "malformed request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if resources := parseRejectedResources(tc.message); !reflect.DeepEqual(resources, tc.resources) {
+				t.Errorf("expected the resources %v, got %v", tc.resources, resources)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	envoycache "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+	"k8c.io/kubelb/internal/kubelb"
+	portlookup "k8c.io/kubelb/internal/port-lookup"
+
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DebugSnapshotsPath lists the snapshots of the Envoy proxies, the snapshot of a single node is served below it.
+	DebugSnapshotsPath = "/debug/snapshots"
+	// debugTenantParameter is the query parameter that limits the response to the resources of a tenant.
+	debugTenantParameter = "tenant"
+)
+
+// SnapshotInfo describes the snapshot that is served to the Envoy proxies of a node.
+type SnapshotInfo struct {
+	Node string `json:"node"`
+	// Version identifies the snapshot, it can be used to pin the node to this snapshot.
+	Version string `json:"version"`
+	// ResourceVersions are the versions of the resource types, Envoy reports them as the version of its configuration.
+	ResourceVersions map[string]string `json:"resourceVersions"`
+	// History are the versions of the last good snapshots of the node, newest first.
+	History []string `json:"history,omitempty"`
+	// Connections are the Envoy proxy instances of the node that are connected to this replica.
+	Connections []ProxyConnection `json:"connections,omitempty"`
+}
+
+// SnapshotDump contains the resources of the snapshot of a node.
+type SnapshotDump struct {
+	SnapshotInfo    `json:",inline"`
+	Listeners       []json.RawMessage      `json:"listeners"`
+	Clusters        []json.RawMessage      `json:"clusters"`
+	PortLookupTable portlookup.LookupTable `json:"portLookupTable,omitempty"`
+}
+
+type debugHandler struct {
+	client        ctrlclient.Reader
+	cache         envoycache.SnapshotCache
+	tracker       *ConfigTracker
+	history       *SnapshotHistory
+	portAllocator *portlookup.PortAllocator
+}
+
+// NewDebugHandler returns a handler that serves the snapshots of the Envoy proxies as JSON. This way the configuration can be inspected
+// without access to the admin interface of Envoy. The handler doesn't authenticate the requests, it must be served behind an
+// authenticating server.
+//
+// The responses can be limited to the resources of a tenant with the tenant query parameter. The resources are attributed to the tenants
+// by their owning LoadBalancers and Routes, which are read with the client.
+func NewDebugHandler(client ctrlclient.Reader, cache envoycache.SnapshotCache, tracker *ConfigTracker, history *SnapshotHistory, portAllocator *portlookup.PortAllocator) http.Handler {
+	h := &debugHandler{
+		client:        client,
+		cache:         cache,
+		tracker:       tracker,
+		history:       history,
+		portAllocator: portAllocator,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+DebugSnapshotsPath, h.listSnapshots)
+	mux.HandleFunc("GET "+DebugSnapshotsPath+"/{node}", h.dumpSnapshot)
+	return mux
+}
+
+func (h *debugHandler) listSnapshots(w http.ResponseWriter, req *http.Request) {
+	filter, err := h.newTenantFilter(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	snapshots := []SnapshotInfo{}
+	for _, node := range h.nodes() {
+		snapshot, err := h.cache.GetSnapshot(node)
+		if err != nil || !filter.matchesNode(node, snapshot) {
+			continue
+		}
+		snapshots = append(snapshots, h.getSnapshotInfo(node, snapshot))
+	}
+	writeJSON(w, snapshots)
+}
+
+func (h *debugHandler) dumpSnapshot(w http.ResponseWriter, req *http.Request) {
+	node := req.PathValue("node")
+	filter, err := h.newTenantFilter(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	snapshot, err := h.cache.GetSnapshot(node)
+	if err != nil || !filter.matchesNode(node, snapshot) {
+		http.Error(w, fmt.Sprintf("no snapshot found for node %q", node), http.StatusNotFound)
+		return
+	}
+
+	dump := SnapshotDump{
+		SnapshotInfo:    h.getSnapshotInfo(node, snapshot),
+		PortLookupTable: make(portlookup.LookupTable),
+	}
+	if dump.Listeners, err = marshalResources(snapshot, resource.ListenerType, filter.resourceFilter(node)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if dump.Clusters, err = marshalResources(snapshot, resource.ClusterType, filter.resourceFilter(node)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for endpointKey, ports := range h.portAllocator.GetPortLookupTable() {
+		if filter.matchesName(endpointKey) {
+			dump.PortLookupTable[endpointKey] = ports
+		}
+	}
+	writeJSON(w, dump)
+}
+
+// nodes returns the nodes that have a snapshot or are connected, sorted by name.
+func (h *debugHandler) nodes() []string {
+	nodes := make(map[string]bool)
+	for _, node := range h.history.Nodes() {
+		nodes[node] = true
+	}
+	for _, node := range h.cache.GetStatusKeys() {
+		nodes[node] = true
+	}
+
+	result := make([]string, 0, len(nodes))
+	for node := range nodes {
+		result = append(result, node)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (h *debugHandler) getSnapshotInfo(node string, snapshot envoycache.ResourceSnapshot) SnapshotInfo {
+	info := SnapshotInfo{
+		Node:             node,
+		Version:          SnapshotVersion(snapshot),
+		ResourceVersions: make(map[string]string),
+		History:          h.history.Versions(node),
+	}
+	for _, typ := range []resource.Type{resource.ClusterType, resource.EndpointType, resource.ListenerType, resource.SecretType} {
+		info.ResourceVersions[typeLabel(typ)] = snapshot.GetVersion(typ)
+	}
+	for _, connection := range h.tracker.Connections() {
+		if connection.Node == node {
+			info.Connections = append(info.Connections, connection)
+		}
+	}
+	return info
+}
+
+// marshalResources returns the resources of a type that are accepted by the filter as JSON, sorted by name.
+func marshalResources(snapshot envoycache.ResourceSnapshot, typ resource.Type, filter func(name string) bool) ([]json.RawMessage, error) {
+	resources := snapshot.GetResources(typ)
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		if filter(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := make([]json.RawMessage, 0, len(names))
+	for _, name := range names {
+		data, err := protojson.Marshal(resources[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s %q: %w", typeLabel(typ), name, err)
+		}
+		result = append(result, data)
+	}
+	return result, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// newTenantFilter returns the filter for the tenant query parameter of the request.
+func (h *debugHandler) newTenantFilter(req *http.Request) (tenantFilter, error) {
+	tenant := req.URL.Query().Get(debugTenantParameter)
+	if tenant == "" {
+		return tenantFilter{}, nil
+	}
+
+	owners, err := getResourceOwners(req.Context(), h.client)
+	if err != nil {
+		return tenantFilter{}, err
+	}
+	return tenantFilter{namespace: fmt.Sprintf("tenant-%s", tenant), owners: owners}, nil
+}
+
+// getResourceOwners maps the names of the resources and of the endpoint keys of the port lookup table to the namespaces of the
+// LoadBalancers and Routes that own them.
+func getResourceOwners(ctx context.Context, client ctrlclient.Reader) (map[string]string, error) {
+	lbs := &kubelbv1alpha1.LoadBalancerList{}
+	if err := client.List(ctx, lbs); err != nil {
+		return nil, fmt.Errorf("failed to list LoadBalancers: %w", err)
+	}
+	routes := &kubelbv1alpha1.RouteList{}
+	if err := client.List(ctx, routes); err != nil {
+		return nil, fmt.Errorf("failed to list Routes: %w", err)
+	}
+
+	owners := make(map[string]string)
+	for i := range lbs.Items {
+		lb := &lbs.Items[i]
+		for _, name := range LoadBalancerResourceNames(lb) {
+			owners[name] = lb.Namespace
+		}
+		for j := range lb.Spec.Endpoints {
+			owners[fmt.Sprintf(kubelb.EnvoyEndpointPattern, lb.Namespace, lb.Name, j)] = lb.Namespace
+		}
+	}
+	for i := range routes.Items {
+		route := &routes.Items[i]
+		for _, name := range RouteResourceNames(route) {
+			owners[name] = route.Namespace
+		}
+		if route.Spec.Source.Kubernetes == nil {
+			continue
+		}
+		for _, svc := range route.Spec.Source.Kubernetes.Services {
+			owners[fmt.Sprintf(kubelb.EnvoyEndpointRoutePattern, route.Namespace, svc.Namespace, svc.Name)] = route.Namespace
+		}
+	}
+	return owners, nil
+}
+
+// tenantFilter matches the nodes and resources of a tenant. The resources are matched by the namespace of the LoadBalancer or Route that
+// owns them, the shared TLS passthrough listeners are only served for the nodes that are dedicated to the tenant.
+type tenantFilter struct {
+	namespace string
+	// owners maps the names of the resources to the namespaces of their owners.
+	owners map[string]string
+}
+
+func (f tenantFilter) matchesName(name string) bool {
+	return f.namespace == "" || f.owners[name] == f.namespace
+}
+
+// matchesNode returns true if the node is dedicated to the tenant, or if it serves any listener or cluster of the tenant.
+func (f tenantFilter) matchesNode(node string, snapshot envoycache.ResourceSnapshot) bool {
+	if f.namespace == "" || node == f.namespace {
+		return true
+	}
+	for _, typ := range []resource.Type{resource.ListenerType, resource.ClusterType} {
+		for name := range snapshot.GetResources(typ) {
+			if f.matchesName(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resourceFilter returns the filter for the resources of a node. All the resources of a node that is dedicated to the tenant are served,
+// including the listeners that are shared by the LoadBalancers of the tenant.
+func (f tenantFilter) resourceFilter(node string) func(name string) bool {
+	if node == f.namespace {
+		return func(string) bool { return true }
+	}
+	return f.matchesName
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTenantFilter(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kubelbv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	foo := newLoadBalancer("test")
+	foo.Namespace = "tenant-foo"
+	fooBar := newLoadBalancer("test")
+	fooBar.Namespace = "tenant-foo-bar"
+	route := kubelbv1alpha1.Route{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "tenant-foo"},
+		Spec: kubelbv1alpha1.RouteSpec{
+			Source: kubelbv1alpha1.RouteSource{Kubernetes: &kubelbv1alpha1.KubernetesSource{
+				// The fake client can't serialize an empty resource.
+				Route: unstructured.Unstructured{Object: map[string]any{"apiVersion": "networking.k8s.io/v1", "kind": "Ingress"}},
+				Services: []kubelbv1alpha1.UpstreamService{{Service: corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default", UID: "uid"},
+					Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80, Protocol: corev1.ProtocolTCP}}},
+				}}},
+			}},
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&foo, &fooBar, &route).Build()
+	handler := &debugHandler{client: client}
+
+	testCases := []struct {
+		name     string
+		tenant   string
+		resource string
+		matches  bool
+	}{
+		{
+			name:     "no tenant",
+			resource: "tenant-foo-bar-test-ep-0-port-30080-TCP",
+			matches:  true,
+		},
+		{
+			name:     "LoadBalancer of the tenant",
+			tenant:   "foo",
+			resource: "tenant-foo-test-ep-0-port-30080-TCP",
+			matches:  true,
+		},
+		{
+			name:     "LoadBalancer of a tenant with the same prefix",
+			tenant:   "foo",
+			resource: "tenant-foo-bar-test-ep-0-port-30080-TCP",
+		},
+		{
+			name:     "Route of the tenant",
+			tenant:   "foo",
+			resource: "tenant-tenant-foo-route-default-backend-svc-uid-port-80-TCP",
+			matches:  true,
+		},
+		{
+			name:     "port lookup table entry of the tenant",
+			tenant:   "foo",
+			resource: "tenant-foo-test-ep-0",
+			matches:  true,
+		},
+		{
+			name:     "port lookup table entry of a tenant with the same prefix",
+			tenant:   "foo",
+			resource: "tenant-foo-bar-test-ep-0",
+		},
+		{
+			name:     "shared TLS passthrough listener",
+			tenant:   "foo",
+			resource: "tls-passthrough-443",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", DebugSnapshotsPath+"?tenant="+tc.tenant, nil)
+			filter, err := handler.newTenantFilter(req)
+			if err != nil {
+				t.Fatalf("failed to create the tenant filter: %v", err)
+			}
+			if matches := filter.matchesName(tc.resource); matches != tc.matches {
+				t.Errorf("expected %q to match: %t, got %t", tc.resource, tc.matches, matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"net"
+
+	envoyCluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoyEndpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+)
+
+var dnsLookupFamilies = map[kubelbv1alpha1.DNSLookupFamily]envoyCluster.Cluster_DnsLookupFamily{
+	kubelbv1alpha1.DNSLookupFamilyAuto:        envoyCluster.Cluster_AUTO,
+	kubelbv1alpha1.DNSLookupFamilyV4Only:      envoyCluster.Cluster_V4_ONLY,
+	kubelbv1alpha1.DNSLookupFamilyV6Only:      envoyCluster.Cluster_V6_ONLY,
+	kubelbv1alpha1.DNSLookupFamilyV4Preferred: envoyCluster.Cluster_V4_PREFERRED,
+	kubelbv1alpha1.DNSLookupFamilyAll:         envoyCluster.Cluster_ALL,
+}
+
+// getEndpointAddress returns the address that Envoy connects to. The IP has precedence, the hostname is only used if no IP is known.
+func getEndpointAddress(address kubelbv1alpha1.EndpointAddress) string {
+	if address.IP != "" {
+		return address.IP
+	}
+	return address.Hostname
+}
+
+// hasHostnames returns true if any endpoint of the cluster is addressed by a hostname instead of an IP.
+func hasHostnames(cla *envoyEndpoint.ClusterLoadAssignment) bool {
+	for _, localityLbEndpoints := range cla.Endpoints {
+		for _, lbEndpoint := range localityLbEndpoints.LbEndpoints {
+			if net.ParseIP(lbEndpoint.GetEndpoint().GetAddress().GetSocketAddress().GetAddress()) == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setDNSResolution turns an EDS cluster into a cluster that resolves the hostnames of its endpoints. EDS clusters can't resolve hostnames,
+// so the endpoints are embedded into the cluster instead. The IPs of the endpoints are used as they are, this way IPs and hostnames can be
+// mixed in a cluster.
+func setDNSResolution(cluster *envoyCluster.Cluster, cla *envoyEndpoint.ClusterLoadAssignment, dns *kubelbv1alpha1.DNSSettings) {
+	if dns == nil {
+		dns = &kubelbv1alpha1.DNSSettings{}
+	}
+
+	discoveryType := envoyCluster.Cluster_STRICT_DNS
+	// Envoy only supports logical DNS for clusters with a single endpoint.
+	if dns.Resolution == kubelbv1alpha1.DNSResolutionLogical && len(cla.Endpoints) == 1 && len(cla.Endpoints[0].LbEndpoints) == 1 {
+		discoveryType = envoyCluster.Cluster_LOGICAL_DNS
+	}
+
+	cluster.ClusterDiscoveryType = &envoyCluster.Cluster_Type{Type: discoveryType}
+	cluster.EdsClusterConfig = nil
+	cluster.LoadAssignment = cla
+	if dns.RefreshRate != nil {
+		cluster.DnsRefreshRate = durationpb.New(dns.RefreshRate.Duration)
+	}
+	if dns.RespectDNSTTL != nil {
+		cluster.RespectDnsTtl = *dns.RespectDNSTTL
+	}
+	if lookupFamily, ok := dnsLookupFamilies[dns.LookupFamily]; ok {
+		cluster.DnsLookupFamily = lookupFamily
+	}
+}
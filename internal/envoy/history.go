@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	envoycache "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+// maxSnapshotHistory is the number of good snapshots that are kept per node.
+const maxSnapshotHistory = 10
+
+// snapshotTypes are the resource types that are part of a snapshot version.
+var snapshotTypes = []resource.Type{resource.ClusterType, resource.EndpointType, resource.ListenerType, resource.SecretType}
+
+// SnapshotVersion returns a version that identifies the resources of all the types in a snapshot. It's stable across restarts and replicas
+// of the control plane since the versions of the resource types are hashes of their content.
+func SnapshotVersion(snapshot envoycache.ResourceSnapshot) string {
+	var versions []string
+	for _, typ := range snapshotTypes {
+		versions = append(versions, snapshot.GetVersion(typ))
+	}
+	hash := sha256.Sum256([]byte(strings.Join(versions, "/")))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// SnapshotHistory keeps the last good snapshots of each node, newest first. They can be served again to roll back a node. A snapshot is
+// only considered good once the Envoy proxies of the node accepted it.
+type SnapshotHistory struct {
+	mu        sync.Mutex
+	snapshots map[string][]*envoycache.Snapshot
+	// pending are the snapshots that are served to the nodes but were not accepted yet.
+	pending map[string]*envoycache.Snapshot
+	// acked are the last versions of each resource type that were accepted by the Envoy proxies of the nodes.
+	acked map[string]map[string]string
+	// pinned are the versions that the nodes are pinned to, they are never evicted.
+	pinned map[string]string
+}
+
+func NewSnapshotHistory() *SnapshotHistory {
+	return &SnapshotHistory{
+		snapshots: make(map[string][]*envoycache.Snapshot),
+		pending:   make(map[string]*envoycache.Snapshot),
+		acked:     make(map[string]map[string]string),
+		pinned:    make(map[string]string),
+	}
+}
+
+// SetPending records the snapshot that is served to a node. It's added to the history once the Envoy proxies accepted it.
+func (h *SnapshotHistory) SetPending(node string, snapshot *envoycache.Snapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pending[node] = snapshot
+	h.promote(node)
+}
+
+// Ack records that an Envoy proxy of a node accepted a version of a resource type. It's registered with ConfigTracker.OnAck.
+func (h *SnapshotHistory) Ack(node, typeURL, version string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.acked[node] == nil {
+		h.acked[node] = make(map[string]string)
+	}
+	h.acked[node][typeURL] = version
+	h.promote(node)
+}
+
+// SetPinnedVersion records the version that a node is pinned to, an empty version removes the pin.
+func (h *SnapshotHistory) SetPinnedVersion(node, version string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if version == "" {
+		delete(h.pinned, node)
+		return
+	}
+	h.pinned[node] = version
+}
+
+// promote adds the pending snapshot of a node to the history once the versions of all its resource types with resources were accepted.
+// The resource types that didn't change are not sent again, the versions that were accepted before count. It must be called with the lock
+// held.
+func (h *SnapshotHistory) promote(node string) {
+	snapshot, ok := h.pending[node]
+	if !ok {
+		return
+	}
+	for _, typ := range snapshotTypes {
+		if len(snapshot.GetResources(typ)) > 0 && h.acked[node][typ] != snapshot.GetVersion(typ) {
+			return
+		}
+	}
+	delete(h.pending, node)
+	h.add(node, snapshot)
+}
+
+// add records a good snapshot of a node. A snapshot that is already part of the history becomes the newest one. The oldest snapshots are
+// evicted, except for the pinned one. It must be called with the lock held.
+func (h *SnapshotHistory) add(node string, snapshot *envoycache.Snapshot) {
+	version := SnapshotVersion(snapshot)
+	snapshots := []*envoycache.Snapshot{snapshot}
+	for _, s := range h.snapshots[node] {
+		if SnapshotVersion(s) != version {
+			snapshots = append(snapshots, s)
+		}
+	}
+	for i := len(snapshots) - 1; i >= 0 && len(snapshots) > maxSnapshotHistory; i-- {
+		if SnapshotVersion(snapshots[i]) != h.pinned[node] {
+			snapshots = append(snapshots[:i], snapshots[i+1:]...)
+		}
+	}
+	h.snapshots[node] = snapshots
+}
+
+// Get returns the snapshot of a node with the given version.
+func (h *SnapshotHistory) Get(node, version string) (*envoycache.Snapshot, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, s := range h.snapshots[node] {
+		if SnapshotVersion(s) == version {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Versions returns the versions of the snapshots of a node, newest first.
+func (h *SnapshotHistory) Versions(node string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	versions := make([]string, 0, len(h.snapshots[node]))
+	for _, s := range h.snapshots[node] {
+		versions = append(versions, SnapshotVersion(s))
+	}
+	return versions
+}
+
+// Nodes returns the nodes that have snapshots in the history, sorted by name.
+func (h *SnapshotHistory) Nodes() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	nodes := make([]string, 0, len(h.snapshots))
+	for node := range h.snapshots {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Remove forgets the snapshots, the accepted versions and the pin of a node.
+func (h *SnapshotHistory) Remove(node string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.snapshots, node)
+	delete(h.pending, node)
+	delete(h.acked, node)
+	delete(h.pinned, node)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	envoycache "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+func newTestSnapshot(t *testing.T, name string) *envoycache.Snapshot {
+	t.Helper()
+	snapshot, err := newSnapshot(map[resource.Type][]types.Resource{
+		resource.ClusterType:  {makeCluster(name, nil, nil, "")},
+		resource.ListenerType: {makeTCPListener([]weightedCluster{{name: name, weight: 1}}, name, 80, nil, nil, nil, nil)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+// ackSnapshot acknowledges the versions of all the resource types of a snapshot.
+func ackSnapshot(history *SnapshotHistory, node string, snapshot *envoycache.Snapshot) {
+	for _, typ := range snapshotTypes {
+		history.Ack(node, typ, snapshot.GetVersion(typ))
+	}
+}
+
+func TestSnapshotHistory(t *testing.T) {
+	testCases := []struct {
+		name     string
+		run      func(t *testing.T, history *SnapshotHistory) []*envoycache.Snapshot
+		versions int
+	}{
+		{
+			name: "pending snapshot is not added",
+			run: func(t *testing.T, history *SnapshotHistory) []*envoycache.Snapshot {
+				history.SetPending("node", newTestSnapshot(t, "a"))
+				return nil
+			},
+		},
+		{
+			name: "partially acknowledged snapshot is not added",
+			run: func(t *testing.T, history *SnapshotHistory) []*envoycache.Snapshot {
+				snapshot := newTestSnapshot(t, "a")
+				history.SetPending("node", snapshot)
+				history.Ack("node", resource.ClusterType, snapshot.GetVersion(resource.ClusterType))
+				return nil
+			},
+		},
+		{
+			name: "acknowledged snapshot is added",
+			run: func(t *testing.T, history *SnapshotHistory) []*envoycache.Snapshot {
+				snapshot := newTestSnapshot(t, "a")
+				history.SetPending("node", snapshot)
+				ackSnapshot(history, "node", snapshot)
+				return []*envoycache.Snapshot{snapshot}
+			},
+			versions: 1,
+		},
+		{
+			name: "snapshot with versions that were acknowledged before is added",
+			run: func(t *testing.T, history *SnapshotHistory) []*envoycache.Snapshot {
+				snapshot := newTestSnapshot(t, "a")
+				ackSnapshot(history, "node", snapshot)
+				history.SetPending("node", snapshot)
+				return []*envoycache.Snapshot{snapshot}
+			},
+			versions: 1,
+		},
+		{
+			name: "oldest snapshots are evicted",
+			run: func(t *testing.T, history *SnapshotHistory) []*envoycache.Snapshot {
+				var snapshots []*envoycache.Snapshot
+				for i := 0; i <= maxSnapshotHistory; i++ {
+					snapshot := newTestSnapshot(t, fmt.Sprintf("snapshot-%d", i))
+					history.SetPending("node", snapshot)
+					ackSnapshot(history, "node", snapshot)
+					snapshots = append(snapshots, snapshot)
+				}
+				return snapshots[1:]
+			},
+			versions: maxSnapshotHistory,
+		},
+		{
+			name: "pinned snapshot is not evicted",
+			run: func(t *testing.T, history *SnapshotHistory) []*envoycache.Snapshot {
+				var snapshots []*envoycache.Snapshot
+				for i := 0; i <= maxSnapshotHistory; i++ {
+					snapshot := newTestSnapshot(t, fmt.Sprintf("snapshot-%d", i))
+					history.SetPending("node", snapshot)
+					ackSnapshot(history, "node", snapshot)
+					snapshots = append(snapshots, snapshot)
+					if i == 0 {
+						history.SetPinnedVersion("node", SnapshotVersion(snapshot))
+					}
+				}
+				return append(snapshots[:1], snapshots[2:]...)
+			},
+			versions: maxSnapshotHistory,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			history := NewSnapshotHistory()
+			expected := tc.run(t, history)
+
+			if versions := history.Versions("node"); len(versions) != tc.versions {
+				t.Errorf("expected %d versions, got %v", tc.versions, versions)
+			}
+			for _, snapshot := range expected {
+				if _, ok := history.Get("node", SnapshotVersion(snapshot)); !ok {
+					t.Errorf("expected snapshot version %s to be part of the history", SnapshotVersion(snapshot))
+				}
+			}
+		})
+	}
+}
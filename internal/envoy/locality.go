@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"sort"
+
+	envoyCluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoyEndpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	envoycache "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// localClusterName is the cluster that contains the Envoy proxies that share a snapshot. Envoy compares the zones of its peers with
+	// the zones of the endpoints to decide how much traffic can stay in its own zone. Envoy requires the local cluster to be part of the
+	// bootstrap config, only its endpoints are served by the control plane.
+	localClusterName = "kubelb_proxies"
+
+	// ProxyZoneEnvVar and ProxyRegionEnvVar are the environment variables of the Envoy proxy pods that contain the zone and the region of
+	// the pod. They are referenced by the bootstrap config and expanded by Kubernetes since the bootstrap config is passed as an argument.
+	ProxyZoneEnvVar   = "KUBELB_PROXY_ZONE"
+	ProxyRegionEnvVar = "KUBELB_PROXY_REGION"
+)
+
+// locality is the region and the zone of an endpoint. The zero value is used for the endpoints without topology labels.
+type locality struct {
+	region string
+	zone   string
+}
+
+// localityEndpoints groups the endpoints of a cluster by their locality.
+type localityEndpoints map[locality][]*envoyEndpoint.LbEndpoint
+
+func (l localityEndpoints) add(address kubelbv1alpha1.EndpointAddress, lbEndpoint *envoyEndpoint.LbEndpoint) {
+	key := locality{region: address.Region, zone: address.Zone}
+	l[key] = append(l[key], lbEndpoint)
+}
+
+// makeClusterLoadAssignment generates the endpoints of a cluster. Each entry is a priority level, starting with the highest priority. The
+// endpoints of a priority level are grouped by their locality, sorted by region and zone. Endpoints with an IP address that is not of the
+// IP families of the Envoy proxies are left out since the proxies can't reach them.
+func makeClusterLoadAssignment(clusterName string, ipFamilies []corev1.IPFamily, priorities ...localityEndpoints) *envoyEndpoint.ClusterLoadAssignment {
+	cla := &envoyEndpoint.ClusterLoadAssignment{
+		ClusterName: clusterName,
+	}
+	for priority, endpoints := range priorities {
+		endpoints = endpoints.filterIPFamilies(ipFamilies)
+		localities := make([]locality, 0, len(endpoints))
+		for key := range endpoints {
+			localities = append(localities, key)
+		}
+		sort.Slice(localities, func(i, j int) bool {
+			if localities[i].region != localities[j].region {
+				return localities[i].region < localities[j].region
+			}
+			return localities[i].zone < localities[j].zone
+		})
+
+		for _, key := range localities {
+			localityLbEndpoints := &envoyEndpoint.LocalityLbEndpoints{
+				LbEndpoints: endpoints[key],
+				Priority:    uint32(priority),
+			}
+			if key != (locality{}) {
+				localityLbEndpoints.Locality = &envoyCore.Locality{
+					Region: key.region,
+					Zone:   key.zone,
+				}
+			}
+			cla.Endpoints = append(cla.Endpoints, localityLbEndpoints)
+		}
+	}
+	// Envoy expects at least one group even if there are no endpoints.
+	if len(cla.Endpoints) == 0 {
+		cla.Endpoints = []*envoyEndpoint.LocalityLbEndpoints{{}}
+	}
+	return cla
+}
+
+// filterIPFamilies returns the endpoints whose addresses are of one of the IP families, IPv4 is used if no IP family is set. Hostnames are
+// kept, they are resolved by Envoy according to the DNS lookup family.
+func (l localityEndpoints) filterIPFamilies(ipFamilies []corev1.IPFamily) localityEndpoints {
+	if len(ipFamilies) == 0 {
+		ipFamilies = []corev1.IPFamily{corev1.IPv4Protocol}
+	}
+	filtered := make(localityEndpoints, len(l))
+	for key, lbEndpoints := range l {
+		for _, lbEndpoint := range lbEndpoints {
+			ip := net.ParseIP(lbEndpoint.GetEndpoint().GetAddress().GetSocketAddress().GetAddress())
+			if ip != nil && !slices.Contains(ipFamilies, getIPFamily(ip)) {
+				continue
+			}
+			filtered[key] = append(filtered[key], lbEndpoint)
+		}
+	}
+	return filtered
+}
+
+func getIPFamily(ip net.IP) corev1.IPFamily {
+	if ip.To4() != nil {
+		return corev1.IPv4Protocol
+	}
+	return corev1.IPv6Protocol
+}
+
+// makeLocalClusterLoadAssignment generates the endpoints of the local cluster from the addresses of the Envoy proxy pods. The port is
+// irrelevant since Envoy never connects to the local cluster.
+func makeLocalClusterLoadAssignment(proxies []kubelbv1alpha1.EndpointAddress, ipFamilies []corev1.IPFamily) *envoyEndpoint.ClusterLoadAssignment {
+	endpoints := make(localityEndpoints)
+	for _, proxy := range proxies {
+		endpoints.add(proxy, makeEndpoint(proxy.IP, StatsPort, 0))
+	}
+	return makeClusterLoadAssignment(localClusterName, ipFamilies, endpoints)
+}
+
+// setZoneAwareRouting enables zone-aware routing for a cluster. Envoy only supports it for the load balancing policies that don't hash
+// the connections.
+func setZoneAwareRouting(cluster *envoyCluster.Cluster, zoneAwareRouting *kubelbv1alpha1.ZoneAwareRouting) {
+	if zoneAwareRouting == nil || !zoneAwareRouting.Enabled {
+		return
+	}
+	if cluster.LbPolicy == envoyCluster.Cluster_RING_HASH || cluster.LbPolicy == envoyCluster.Cluster_MAGLEV {
+		return
+	}
+
+	zoneAwareLbConfig := &envoyCluster.Cluster_CommonLbConfig_ZoneAwareLbConfig{}
+	if zoneAwareRouting.MinClusterSize != nil {
+		zoneAwareLbConfig.MinClusterSize = &wrappers.UInt64Value{Value: *zoneAwareRouting.MinClusterSize}
+	}
+	cluster.CommonLbConfig.LocalityConfigSpecifier = &envoyCluster.Cluster_CommonLbConfig_ZoneAwareLbConfig_{
+		ZoneAwareLbConfig: zoneAwareLbConfig,
+	}
+}
+
+// CheckSnapshotConsistency ensures that all the endpoints in a snapshot are referenced by the clusters. The endpoints of the local
+// cluster are excluded since the local cluster is part of the bootstrap config.
+func CheckSnapshotConsistency(snapshot *envoycache.Snapshot) error {
+	if snapshot == nil {
+		return errors.New("nil snapshot")
+	}
+
+	endpoints := snapshot.Resources[types.Endpoint]
+	items := make(map[string]types.ResourceWithTTL, len(endpoints.Items))
+	for name, item := range endpoints.Items {
+		if name != localClusterName {
+			items[name] = item
+		}
+	}
+
+	withoutLocalCluster := *snapshot
+	withoutLocalCluster.Resources[types.Endpoint] = envoycache.Resources{Version: endpoints.Version, Items: items}
+	return withoutLocalCluster.Consistent()
+}
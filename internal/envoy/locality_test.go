@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"reflect"
+	"testing"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMakeClusterLoadAssignmentIPFamilies(t *testing.T) {
+	addresses := []string{"10.0.0.1", "fd00::1", "node.example.com"}
+
+	testCases := []struct {
+		name       string
+		ipFamilies []corev1.IPFamily
+		expected   []string
+	}{
+		{
+			name:     "IPv4 by default",
+			expected: []string{"10.0.0.1", "node.example.com"},
+		},
+		{
+			name:       "IPv6",
+			ipFamilies: []corev1.IPFamily{corev1.IPv6Protocol},
+			expected:   []string{"fd00::1", "node.example.com"},
+		},
+		{
+			name:       "dual stack",
+			ipFamilies: []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol},
+			expected:   addresses,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoints := make(localityEndpoints)
+			for _, address := range addresses {
+				endpoints.add(kubelbv1alpha1.EndpointAddress{Zone: "a"}, makeEndpoint(address, 30080, 0))
+			}
+
+			var served []string
+			for _, localityLbEndpoints := range makeClusterLoadAssignment("cluster", tc.ipFamilies, endpoints).GetEndpoints() {
+				for _, lbEndpoint := range localityLbEndpoints.GetLbEndpoints() {
+					served = append(served, lbEndpoint.GetEndpoint().GetAddress().GetSocketAddress().GetAddress())
+				}
+			}
+			if !reflect.DeepEqual(served, tc.expected) {
+				t.Errorf("expected endpoints %v, got %v", tc.expected, served)
+			}
+		})
+	}
+}
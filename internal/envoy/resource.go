@@ -18,6 +18,7 @@ package envoy
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -26,15 +27,17 @@ import (
 	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoyEndpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	envoyListener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
-	envoyFileAccessLog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
+	envoyProxyProtocolFilter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/proxy_protocol/v3"
+	envoyLocalRateLimit "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/local_ratelimit/v3"
 	envoyTcpProxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	envoyUdpProxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/udp/udp_proxy/v3"
+	envoyProxyProtocolTransport "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/proxy_protocol/v3"
+	envoyRawBuffer "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/raw_buffer/v3"
 	envoytypev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	envoycache "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
-	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
@@ -44,46 +47,173 @@ import (
 	portlookup "k8c.io/kubelb/internal/port-lookup"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	endpointAddressReferencePattern = "%s-address-%s"
+
+	defaultHealthCheckInterval           = 5 * time.Second
+	defaultHealthCheckTimeout            = 5 * time.Second
+	defaultHealthCheckHealthyThreshold   = 3
+	defaultHealthCheckUnhealthyThreshold = 3
+	kubeProxyHealthCheckPath             = "/healthz"
+
+	defaultConnectTimeout = 5 * time.Second
+	defaultUDPIdleTimeout = time.Minute
+
+	defaultUDPMaxBufferedDatagrams = 1024
+	// udpMaxDatagramSize is the default maximum size of the datagrams that are read by Envoy.
+	udpMaxDatagramSize = 1500
+	// The socket options are set on the Envoy proxies, which run on Linux.
+	linuxSOLSocket = 1
+	linuxSORcvbuf  = 8
+
+	defaultOutlierDetectionConsecutiveFailures = 5
+	defaultOutlierDetectionInterval            = 10 * time.Second
+	defaultOutlierDetectionBaseEjectionTime    = 30 * time.Second
+	defaultOutlierDetectionMaxEjectionPercent  = 10
+
+	upstreamProxyProtocolTransportSocket = "envoy.transport_sockets.upstream_proxy_protocol"
+	localRateLimitFilter                 = "envoy.filters.network.local_ratelimit"
 )
 
-func MapSnapshot(ctx context.Context, client ctrlclient.Client, loadBalancers []kubelbv1alpha1.LoadBalancer, routes []kubelbv1alpha1.Route, portAllocator *portlookup.PortAllocator, globalEnvoyProxyTopology bool) (*envoycache.Snapshot, error) {
+// SnapshotSettings contains the settings from the Config and the Tenants that are used to generate a snapshot.
+type SnapshotSettings struct {
+	Config *kubelbv1alpha1.Config
+	// Tenants maps the namespace of a tenant to the Tenant. Tenants are optional.
+	Tenants map[string]*kubelbv1alpha1.Tenant
+	// Proxies are the addresses of the Envoy proxy pods that use the snapshot along with their zone and region.
+	Proxies []kubelbv1alpha1.EndpointAddress
+	// Previous is the snapshot that is currently served. The resources of the LoadBalancers and Routes whose addresses can't be resolved
+	// are kept from it, so that their traffic isn't dropped while the addresses are unavailable.
+	Previous envoycache.ResourceSnapshot
+}
+
+// getTenantName returns the name of the tenant for a namespace, the namespace is used if the Tenant doesn't exist.
+func (s SnapshotSettings) getTenantName(namespace string) string {
+	if tenant, ok := s.Tenants[namespace]; ok && tenant.Name != "" {
+		return tenant.Name
+	}
+	return namespace
+}
+
+// getAllowedTLSHostnames returns the hostnames that a tenant can claim for TLS passthrough. Tenant has higher precedence than the Config.
+func (s SnapshotSettings) getAllowedTLSHostnames(namespace string) []string {
+	if tenant, ok := s.Tenants[namespace]; ok && len(tenant.Spec.LoadBalancer.AllowedTLSHostnames) > 0 {
+		return tenant.Spec.LoadBalancer.AllowedTLSHostnames
+	}
+	if s.Config != nil {
+		return s.Config.Spec.LoadBalancer.AllowedTLSHostnames
+	}
+	return nil
+}
+
+// getDeniedSourceRanges returns the source ranges that are denied for a tenant. The source ranges of both the Tenant and the Config are denied.
+func (s SnapshotSettings) getDeniedSourceRanges(namespace string) []string {
+	var denied []string
+	if tenant, ok := s.Tenants[namespace]; ok {
+		denied = append(denied, tenant.Spec.LoadBalancer.DeniedSourceRanges...)
+	}
+	if s.Config != nil {
+		denied = append(denied, s.Config.Spec.LoadBalancer.DeniedSourceRanges...)
+	}
+	return denied
+}
+
+// getAccessLog returns the access log configuration for a tenant. Tenant has higher precedence than the Config.
+func (s SnapshotSettings) getAccessLog(namespace string) *kubelbv1alpha1.AccessLog {
+	if tenant, ok := s.Tenants[namespace]; ok && tenant.Spec.LoadBalancer.AccessLog != nil {
+		return tenant.Spec.LoadBalancer.AccessLog
+	}
+	if s.Config != nil {
+		return s.Config.Spec.LoadBalancer.AccessLog
+	}
+	return nil
+}
+
+// getZoneAwareRouting returns the zone-aware routing configuration for a tenant. Tenant has higher precedence than the Config.
+func (s SnapshotSettings) getZoneAwareRouting(namespace string) *kubelbv1alpha1.ZoneAwareRouting {
+	if tenant, ok := s.Tenants[namespace]; ok && tenant.Spec.LoadBalancer.ZoneAwareRouting != nil {
+		return tenant.Spec.LoadBalancer.ZoneAwareRouting
+	}
+	if s.Config != nil {
+		return s.Config.Spec.LoadBalancer.ZoneAwareRouting
+	}
+	return nil
+}
+
+func MapSnapshot(ctx context.Context, client ctrlclient.Client, loadBalancers []kubelbv1alpha1.LoadBalancer, routes []kubelbv1alpha1.Route, portAllocator *portlookup.PortAllocator, globalEnvoyProxyTopology bool,
+	settings SnapshotSettings) (*envoycache.Snapshot, []SkippedObject, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	var ipFamilies []corev1.IPFamily
+	if settings.Config != nil {
+		ipFamilies = settings.Config.Spec.EnvoyProxy.IPFamilies
+	}
+
 	var listener []types.Resource
 	var cluster []types.Resource
+	// The local cluster is always served since Envoy waits for its endpoints during the startup.
+	endpoints := []types.Resource{makeLocalClusterLoadAssignment(settings.Proxies, ipFamilies)}
+	// tcpListenerPorts are the ports of the dedicated TCP listeners, they can't be shared for TLS passthrough.
+	tcpListenerPorts := make(map[uint32]bool)
+	secrets := make(tlsSecrets)
+	// skipped are the LoadBalancers and Routes that are left out of the snapshot, the other ones are not affected by them.
+	var skipped []SkippedObject
+	validLoadBalancers := make([]kubelbv1alpha1.LoadBalancer, 0, len(loadBalancers))
 
 	addressesMap := make(map[string][]kubelbv1alpha1.EndpointAddress)
+nextLoadBalancer:
 	for _, lb := range loadBalancers {
-		// multiple endpoints represent multiple clusters
-		for i, lbEndpoint := range lb.Spec.Endpoints {
-			if lbEndpoint.AddressesReference != nil {
-				// Check if map already contains the key
-				if val, ok := addressesMap[fmt.Sprintf(endpointAddressReferencePattern, lb.Namespace, lbEndpoint.AddressesReference.Name)]; ok {
-					lb.Spec.Endpoints[i].Addresses = val
-					lbEndpoint.Addresses = val
-				} else {
-					// Load addresses from reference
-					var addresses kubelbv1alpha1.Addresses
-					if err := client.Get(ctx, ctrlclient.ObjectKey{Namespace: lb.Namespace, Name: lbEndpoint.AddressesReference.Name}, &addresses); err != nil {
-						return nil, fmt.Errorf("failed to get addresses: %w", err)
-					}
-					addressesMap[fmt.Sprintf(endpointAddressReferencePattern, lb.Namespace, lbEndpoint.AddressesReference.Name)] = addresses.Spec.Addresses
-					lb.Spec.Endpoints[i].Addresses = addresses.Spec.Addresses
-					lbEndpoint.Addresses = addresses.Spec.Addresses
+		sourceRanges, err := makeSourceRanges(lb.Spec.SourceRanges, settings.getDeniedSourceRanges(lb.Namespace))
+		if err != nil {
+			// The LoadBalancer is left out instead of being exposed to all the clients.
+			log.Error(err, "failed to restrict source ranges, skipping LoadBalancer", "namespace", lb.Namespace, "name", lb.Name)
+			skipped = append(skipped, SkippedObject{Object: &lb, Reason: kubelbv1alpha1.ReasonInvalidSourceRanges, Err: err})
+			continue
+		}
+
+		// The addresses of all the sets of endpoints are resolved first since the sets can fail over to each other.
+		if err := resolveAddresses(ctx, client, lb.Namespace, lb.Spec.Endpoints, addressesMap); err != nil {
+			if lbResources, ok := reuseResources(settings.Previous, LoadBalancerResourceNames(&lb), secrets); ok {
+				log.Error(err, "failed to resolve addresses, keeping the served configuration of the LoadBalancer", "namespace", lb.Namespace, "name", lb.Name)
+				skipped = append(skipped, SkippedObject{Object: &lb, Reason: kubelbv1alpha1.ReasonAddressesUnavailable, Err: fmt.Errorf("%w, the served configuration is kept", err)})
+				listener = append(listener, lbResources.listeners...)
+				cluster = append(cluster, lbResources.clusters...)
+				endpoints = append(endpoints, lbResources.endpoints...)
+				for _, port := range lbResources.tcpListenerPorts {
+					tcpListenerPorts[port] = true
 				}
+				validLoadBalancers = append(validLoadBalancers, lb)
+				continue
 			}
+			log.Error(err, "failed to resolve addresses, skipping LoadBalancer", "namespace", lb.Namespace, "name", lb.Name)
+			skipped = append(skipped, SkippedObject{Object: &lb, Reason: kubelbv1alpha1.ReasonAddressesUnavailable, Err: err})
+			continue
+		}
+
+		var lbResources snapshotResources
 
-			for _, lbEndpointPort := range lbEndpoint.Ports {
-				var lbEndpoints []*envoyEndpoint.LbEndpoint
+		// multiple endpoints represent multiple clusters, the listeners are generated once per port and distribute the connections
+		// across the clusters
+		for i, lbEndpoint := range lb.Spec.Endpoints {
+			for p, lbEndpointPort := range lbEndpoint.Ports {
 				key := fmt.Sprintf(kubelb.EnvoyResourceIdentifierPattern, lb.Namespace, lb.Name, i, lbEndpointPort.Port, lbEndpointPort.Protocol)
 
-				// each address -> one port
-				for _, lbEndpointAddress := range lbEndpoint.Addresses {
-					lbEndpoints = append(lbEndpoints, makeEndpoint(lbEndpointAddress.IP, uint32(lbEndpointPort.Port)))
-				}
+				accessLogs := makeAccessLogs(settings.getAccessLog(lb.Namespace), accessLogLabels{
+					tenant:          settings.getTenantName(lb.Namespace),
+					loadBalancer:    lb.Name,
+					originNamespace: lb.Labels[kubelb.LabelOriginNamespace],
+					originName:      lb.Labels[kubelb.LabelOriginName],
+				})
+
+				healthCheck := kubelb.GetHealthCheck(&lb, p)
+				healthCheckMode, healthCheckPort := kubelb.GetHealthCheckMode(healthCheck, lbEndpointPort.Protocol, lb.Spec.HealthCheckNodePort)
+
+				lbEndpoints := makeLoadBalancerEndpoints(&lb, i, p, uint32(healthCheckPort))
 
 				port := uint32(lbEndpointPort.Port)
 				if globalEnvoyProxyTopology && portAllocator != nil {
@@ -94,46 +224,129 @@ func MapSnapshot(ctx context.Context, client ctrlclient.Client, loadBalancers []
 					}
 				}
 
+				policy := getLoadBalancingPolicy(lb.Spec.LoadBalancingPolicy, lb.Spec.SessionAffinity)
+				if udpSettings := getUDPSettings(&lb, p); lbEndpointPort.Protocol == corev1.ProtocolUDP && udpSettings != nil && udpSettings.HashSourceIP {
+					policy = withSourceIPHash(policy)
+				}
+				lbCluster := makeCluster(key, policy, healthCheck, healthCheckMode)
+				setCircuitBreakers(lbCluster, lb.Spec.CircuitBreakers)
+				setOutlierDetection(lbCluster, lb.Spec.OutlierDetection)
+				setZoneAwareRouting(lbCluster, settings.getZoneAwareRouting(lb.Namespace))
 				if lbEndpointPort.Protocol == corev1.ProtocolTCP {
-					listener = append(listener, makeTCPListener(key, key, port))
-				} else if lbEndpointPort.Protocol == corev1.ProtocolUDP {
-					listener = append(listener, makeUDPListener(key, key, port))
+					setTCPSettings(lbCluster, lb.Spec.TCP)
+					if lb.Spec.UpstreamTLS != nil {
+						if err := secrets.originateTLS(ctx, client, lb.Namespace, lbCluster, lb.Spec.UpstreamTLS); err != nil {
+							// The LoadBalancer is left out instead of falling back to plaintext until the certificates are available.
+							log.Error(err, "failed to configure upstream TLS, skipping LoadBalancer", "namespace", lb.Namespace, "name", lb.Name)
+							skipped = append(skipped, SkippedObject{Object: &lb, Reason: kubelbv1alpha1.ReasonUpstreamTLSUnavailable, Err: err})
+							continue nextLoadBalancer
+						}
+					}
+					// PROXY protocol is only supported for TCP.
+					if lb.Spec.ProxyProtocol != nil {
+						setUpstreamProxyProtocol(lbCluster, lb.Spec.ProxyProtocol.Upstream)
+					}
+					// Ports with TLS passthrough are served by the shared listeners that are generated below.
+					if i == 0 && !kubelb.UsesTLSPassthrough(&lb, p) {
+						tcpListener := makeTCPListener(getWeightedClusters(&lb, p), key, port, ipFamilies, policy, lb.Spec.TCP, accessLogs)
+						if lb.Spec.ProxyProtocol != nil && ptr.Deref(lb.Spec.ProxyProtocol.Downstream, false) {
+							acceptProxyProtocol(tcpListener)
+						}
+						if rateLimit := getConnectionRateLimit(&lb, p); rateLimit != nil {
+							limitConnectionRate(tcpListener.FilterChains[0], fmt.Sprintf(kubelb.EnvoyConnectionRateLimitPattern, lb.Namespace, lb.Name, p), rateLimit)
+						}
+						// Rejected clients don't consume the tokens of the rate limit.
+						restrictSourceRanges(tcpListener.FilterChains[0], key, sourceRanges)
+						if listenerTLS := getListenerTLS(&lb, p); listenerTLS != nil {
+							if err := secrets.terminateTLS(ctx, client, lb.Namespace, tcpListener, listenerTLS); err != nil {
+								// Serving the port without TLS would expose the connections unencrypted, the listener is left out until the
+								// certificates are available.
+								log.Error(err, "failed to configure TLS termination, skipping listener", "listener", key)
+								tcpListener = nil
+							}
+						}
+						if tcpListener != nil {
+							lbResources.listeners = append(lbResources.listeners, tcpListener)
+							lbResources.tcpListenerPorts = append(lbResources.tcpListenerPorts, port)
+						}
+					}
+				} else if i == 0 && lbEndpointPort.Protocol == corev1.ProtocolUDP {
+					lbResources.listeners = append(lbResources.listeners, makeUDPListener(getUDPCluster(&lb, p), key, port, ipFamilies, policy, getUDPSettings(&lb, p), sourceRanges, accessLogs))
+				}
+				cla := makeClusterLoadAssignment(key, ipFamilies, lbEndpoints...)
+				setOverprovisioningFactor(cla, lbEndpoint.OverprovisioningFactor)
+				lbResources.clusters = append(lbResources.clusters, lbCluster)
+				if hasHostnames(cla) {
+					setDNSResolution(lbCluster, cla, lbEndpoint.DNS)
+				} else {
+					lbResources.endpoints = append(lbResources.endpoints, cla)
 				}
-				cluster = append(cluster, makeCluster(key, lbEndpoints))
 			}
 		}
+
+		if err := lbResources.validate(); err != nil {
+			log.Error(err, "invalid Envoy resources, skipping LoadBalancer", "namespace", lb.Namespace, "name", lb.Name)
+			skipped = append(skipped, SkippedObject{Object: &lb, Reason: kubelbv1alpha1.ReasonInvalidResource, Err: err})
+			continue
+		}
+		listener = append(listener, lbResources.listeners...)
+		cluster = append(cluster, lbResources.clusters...)
+		endpoints = append(endpoints, lbResources.endpoints...)
+		for _, port := range lbResources.tcpListenerPorts {
+			tcpListenerPorts[port] = true
+		}
+		validLoadBalancers = append(validLoadBalancers, lb)
 	}
 
+nextRoute:
 	for _, route := range routes {
 		if route.Spec.Source.Kubernetes == nil {
 			continue
 		}
-		for i, routeendpoint := range route.Spec.Endpoints {
-			if routeendpoint.AddressesReference != nil {
-				// Check if map already contains the key
-				if val, ok := addressesMap[fmt.Sprintf(endpointAddressReferencePattern, route.Namespace, routeendpoint.AddressesReference.Name)]; ok {
-					route.Spec.Endpoints[i].Addresses = val
-					continue
-				}
-
-				// Load addresses from reference
-				var addresses kubelbv1alpha1.Addresses
-				if err := client.Get(ctx, ctrlclient.ObjectKey{Namespace: route.Namespace, Name: routeendpoint.AddressesReference.Name}, &addresses); err != nil {
-					return nil, fmt.Errorf("failed to get addresses: %w", err)
+		if err := resolveAddresses(ctx, client, route.Namespace, route.Spec.Endpoints, addressesMap); err != nil {
+			if routeResources, ok := reuseResources(settings.Previous, RouteResourceNames(&route), secrets); ok {
+				log.Error(err, "failed to resolve addresses, keeping the served configuration of the Route", "namespace", route.Namespace, "name", route.Name)
+				skipped = append(skipped, SkippedObject{Object: &route, Reason: kubelbv1alpha1.ReasonAddressesUnavailable, Err: fmt.Errorf("%w, the served configuration is kept", err)})
+				listener = append(listener, routeResources.listeners...)
+				cluster = append(cluster, routeResources.clusters...)
+				endpoints = append(endpoints, routeResources.endpoints...)
+				for _, port := range routeResources.tcpListenerPorts {
+					tcpListenerPorts[port] = true
 				}
-				addressesMap[fmt.Sprintf(endpointAddressReferencePattern, route.Namespace, routeendpoint.AddressesReference.Name)] = addresses.Spec.Addresses
-				route.Spec.Endpoints[i].Addresses = addresses.Spec.Addresses
+				continue
 			}
+			log.Error(err, "failed to resolve addresses, skipping Route", "namespace", route.Namespace, "name", route.Name)
+			skipped = append(skipped, SkippedObject{Object: &route, Reason: kubelbv1alpha1.ReasonAddressesUnavailable, Err: err})
+			continue
+		}
+		sourceRanges, err := makeSourceRanges(nil, settings.getDeniedSourceRanges(route.Namespace))
+		if err != nil {
+			log.Error(err, "failed to restrict source ranges, skipping Route", "namespace", route.Namespace, "name", route.Name)
+			skipped = append(skipped, SkippedObject{Object: &route, Reason: kubelbv1alpha1.ReasonInvalidSourceRanges, Err: err})
+			continue
 		}
+
+		var routeResources snapshotResources
 		source := route.Spec.Source.Kubernetes
 		for _, svc := range source.Services {
 			endpointKey := fmt.Sprintf(kubelb.EnvoyEndpointRoutePattern, route.Namespace, svc.Namespace, svc.Name)
+			serviceSettings := route.Spec.ServiceSettings[fmt.Sprintf(kubelb.RouteServiceMapKey, kubelb.GetNamespace(&svc.Service), kubelb.GetName(&svc.Service))]
+			accessLogs := makeAccessLogs(settings.getAccessLog(route.Namespace), accessLogLabels{
+				tenant:          settings.getTenantName(route.Namespace),
+				loadBalancer:    route.Name,
+				originNamespace: kubelb.GetNamespace(&svc.Service),
+				originName:      kubelb.GetName(&svc.Service),
+			})
+			// Session affinity is part of the Service that is propagated by the CCM.
+			sessionAffinity := kubelb.GetSessionAffinity(&svc.Service)
+			policy := getLoadBalancingPolicy(nil, sessionAffinity)
 			for _, port := range svc.Spec.Ports {
 				portLookupKey := fmt.Sprintf(kubelb.EnvoyListenerPattern, port.Port, port.Protocol)
-				var lbEndpoints []*envoyEndpoint.LbEndpoint
+				healthCheckMode, healthCheckPort := kubelb.GetHealthCheckMode(serviceSettings.HealthCheck, port.Protocol, svc.Spec.HealthCheckNodePort)
+				lbEndpoints := make(localityEndpoints)
 				for _, address := range route.Spec.Endpoints {
 					for _, routeEndpoints := range address.Addresses {
-						lbEndpoints = append(lbEndpoints, makeEndpoint(routeEndpoints.IP, uint32(port.NodePort)))
+						lbEndpoints.add(routeEndpoints, makeEndpoint(getEndpointAddress(routeEndpoints), uint32(port.NodePort), uint32(healthCheckPort)))
 					}
 				}
 
@@ -144,146 +357,850 @@ func MapSnapshot(ctx context.Context, client ctrlclient.Client, loadBalancers []
 
 				key := fmt.Sprintf(kubelb.EnvoyRoutePortIdentifierPattern, route.Namespace, svc.Namespace, svc.Name, svc.UID, port.Port, port.Protocol)
 
+				routeCluster := makeCluster(key, policy, serviceSettings.HealthCheck, healthCheckMode)
+				setCircuitBreakers(routeCluster, serviceSettings.CircuitBreakers)
+				setZoneAwareRouting(routeCluster, settings.getZoneAwareRouting(route.Namespace))
 				if port.Protocol == corev1.ProtocolTCP {
-					listener = append(listener, makeTCPListener(key, key, listenerPort))
+					tcpListener := makeTCPListener([]weightedCluster{{name: key, weight: 1}}, key, listenerPort, ipFamilies, policy, nil, accessLogs)
+					restrictSourceRanges(tcpListener.FilterChains[0], key, sourceRanges)
+					routeResources.listeners = append(routeResources.listeners, tcpListener)
+					routeResources.tcpListenerPorts = append(routeResources.tcpListenerPorts, listenerPort)
+					if serviceSettings.UpstreamTLS != nil {
+						if err := secrets.originateTLS(ctx, client, route.Namespace, routeCluster, serviceSettings.UpstreamTLS); err != nil {
+							log.Error(err, "failed to configure upstream TLS, skipping Route", "namespace", route.Namespace, "name", route.Name)
+							skipped = append(skipped, SkippedObject{Object: &route, Reason: kubelbv1alpha1.ReasonUpstreamTLSUnavailable, Err: err})
+							continue nextRoute
+						}
+					}
 				} else if port.Protocol == corev1.ProtocolUDP {
-					listener = append(listener, makeUDPListener(key, key, listenerPort))
+					routeResources.listeners = append(routeResources.listeners, makeUDPListener(key, key, listenerPort, ipFamilies, policy, nil, sourceRanges, accessLogs))
+				}
+				cla := makeClusterLoadAssignment(key, ipFamilies, lbEndpoints)
+				routeResources.clusters = append(routeResources.clusters, routeCluster)
+				if hasHostnames(cla) {
+					setDNSResolution(routeCluster, cla, getRouteDNSSettings(&route))
+				} else {
+					routeResources.endpoints = append(routeResources.endpoints, cla)
 				}
-				cluster = append(cluster, makeCluster(key, lbEndpoints))
 			}
 		}
+
+		if err := routeResources.validate(); err != nil {
+			log.Error(err, "invalid Envoy resources, skipping Route", "namespace", route.Namespace, "name", route.Name)
+			skipped = append(skipped, SkippedObject{Object: &route, Reason: kubelbv1alpha1.ReasonInvalidResource, Err: err})
+			continue
+		}
+		listener = append(listener, routeResources.listeners...)
+		cluster = append(cluster, routeResources.clusters...)
+		endpoints = append(endpoints, routeResources.endpoints...)
+		for _, port := range routeResources.tcpListenerPorts {
+			tcpListenerPorts[port] = true
+		}
 	}
 
-	var content []byte
-	var resources []types.Resource
-	resources = append(resources, cluster...)
-	resources = append(resources, listener...)
-	for _, r := range resources {
-		mr, err := envoycache.MarshalResource(r)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal resource: %w", err)
+	// The skipped LoadBalancers can't claim hostnames since their clusters are missing.
+	claims := kubelb.ResolveTLSPassthroughClaims(validLoadBalancers, settings.getAllowedTLSHostnames)
+	tlsPassthroughListeners, skippedTLSPassthrough := makeTLSPassthroughListeners(ctx, claims, tcpListenerPorts, ipFamilies, settings)
+	skipped = append(skipped, skippedTLSPassthrough...)
+	for _, tlsPassthroughListener := range tlsPassthroughListeners {
+		// The listeners are shared by multiple LoadBalancers, an invalid listener can't be attributed to one of them.
+		if err := validateResource(tlsPassthroughListener); err != nil {
+			log.Error(err, "skipping TLS passthrough listener")
+			continue
 		}
-		content = append(content, mr...)
+		listener = append(listener, tlsPassthroughListener)
 	}
-	version := envoycache.HashResource(content)
 
-	return envoycache.NewSnapshot(
-		version,
-		map[resource.Type][]types.Resource{
-			resource.ClusterType:  cluster,
-			resource.ListenerType: listener,
-		},
-	)
+	snapshot, err := newSnapshot(map[resource.Type][]types.Resource{
+		resource.ClusterType:  cluster,
+		resource.EndpointType: endpoints,
+		resource.ListenerType: listener,
+		resource.SecretType:   secrets.resources(),
+	})
+	return snapshot, skipped, err
+}
+
+// SkippedObject is a LoadBalancer or a Route that was left out of a snapshot, or whose TLS passthrough routes were left out, since its
+// resources could not be generated or are invalid. If its addresses are unavailable, its resources can be kept from the previous snapshot
+// instead.
+type SkippedObject struct {
+	Object ctrlclient.Object
+	Reason string
+	Err    error
 }
 
-func makeCluster(clusterName string, lbEndpoints []*envoyEndpoint.LbEndpoint) *envoyCluster.Cluster {
-	return &envoyCluster.Cluster{
+// snapshotResources are the resources that are generated for a LoadBalancer or a Route. They are only added to the snapshot if all of
+// them are valid.
+type snapshotResources struct {
+	listeners        []types.Resource
+	clusters         []types.Resource
+	endpoints        []types.Resource
+	tcpListenerPorts []uint32
+}
+
+func (r *snapshotResources) validate() error {
+	for _, resources := range [][]types.Resource{r.listeners, r.clusters, r.endpoints} {
+		for _, res := range resources {
+			if err := validateResource(res); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reuseResources returns the resources with the given names from the previous snapshot and adds the secrets that they reference. It fails
+// if any of the clusters or secrets is missing, e.g. since the object is new or its ports changed since the previous snapshot.
+func reuseResources(previous envoycache.ResourceSnapshot, names []string, secrets tlsSecrets) (snapshotResources, bool) {
+	if previous == nil || len(names) == 0 {
+		return snapshotResources{}, false
+	}
+	clusters := previous.GetResources(resource.ClusterType)
+	listeners := previous.GetResources(resource.ListenerType)
+	endpoints := previous.GetResources(resource.EndpointType)
+
+	var reused snapshotResources
+	for _, name := range names {
+		cluster, ok := clusters[name]
+		if !ok {
+			return snapshotResources{}, false
+		}
+		reused.clusters = append(reused.clusters, cluster)
+		// Clusters with hostnames don't have a cluster load assignment, only the first set of endpoints of a LoadBalancer has listeners.
+		if cla, ok := endpoints[name]; ok {
+			reused.endpoints = append(reused.endpoints, cla)
+		}
+		if res, ok := listeners[name]; ok {
+			reused.listeners = append(reused.listeners, res)
+			if address := res.(*envoyListener.Listener).GetAddress().GetSocketAddress(); address.GetProtocol() == envoyCore.SocketAddress_TCP {
+				reused.tcpListenerPorts = append(reused.tcpListenerPorts, address.GetPortValue())
+			}
+		}
+	}
+
+	previousSecrets := previous.GetResources(resource.SecretType)
+	reusedSecrets := make(tlsSecrets)
+	for _, resources := range [][]types.Resource{reused.clusters, reused.listeners} {
+		for _, res := range resources {
+			names, err := getSecretNames(res)
+			if err != nil {
+				return snapshotResources{}, false
+			}
+			for _, name := range names {
+				secret, ok := previousSecrets[name]
+				if !ok {
+					return snapshotResources{}, false
+				}
+				reusedSecrets[name] = secret
+			}
+		}
+	}
+	// The secrets that were loaded for the other objects are newer.
+	for name, secret := range reusedSecrets {
+		if _, ok := secrets[name]; !ok {
+			secrets[name] = secret
+		}
+	}
+	return reused, true
+}
+
+// LoadBalancerResourceNames returns the names of the dedicated listeners, the clusters and the cluster load assignments of a
+// LoadBalancer. The shared TLS passthrough listeners are not included.
+func LoadBalancerResourceNames(lb *kubelbv1alpha1.LoadBalancer) []string {
+	var names []string
+	for i, endpoints := range lb.Spec.Endpoints {
+		for _, port := range endpoints.Ports {
+			names = append(names, fmt.Sprintf(kubelb.EnvoyResourceIdentifierPattern, lb.Namespace, lb.Name, i, port.Port, port.Protocol))
+		}
+	}
+	return names
+}
+
+// RouteResourceNames returns the names of the listeners, the clusters and the cluster load assignments of the services of a Route.
+func RouteResourceNames(route *kubelbv1alpha1.Route) []string {
+	if route.Spec.Source.Kubernetes == nil {
+		return nil
+	}
+
+	var names []string
+	for _, svc := range route.Spec.Source.Kubernetes.Services {
+		for _, port := range svc.Spec.Ports {
+			names = append(names, fmt.Sprintf(kubelb.EnvoyRoutePortIdentifierPattern, route.Namespace, svc.Namespace, svc.Name, svc.UID, port.Port, port.Protocol))
+		}
+	}
+	return names
+}
+
+// validateResource checks the constraints of the Envoy API that Envoy would otherwise enforce by rejecting the whole update.
+func validateResource(res types.Resource) error {
+	validator, ok := res.(interface{ Validate() error })
+	if !ok {
+		return nil
+	}
+	if err := validator.Validate(); err != nil {
+		return fmt.Errorf("invalid %s %q: %w", typeLabel(string(res.ProtoReflect().Descriptor().FullName())), envoycache.GetResourceName(res), err)
+	}
+	return nil
+}
+
+// resolveAddresses loads the addresses of the endpoints that reference an Addresses object. The addresses are cached in addressesMap
+// since all the LoadBalancers and Routes of a tenant usually share the same Addresses object.
+func resolveAddresses(ctx context.Context, client ctrlclient.Client, namespace string, endpoints []kubelbv1alpha1.LoadBalancerEndpoints,
+	addressesMap map[string][]kubelbv1alpha1.EndpointAddress) error {
+	for i, endpoint := range endpoints {
+		if endpoint.AddressesReference == nil {
+			continue
+		}
+		key := fmt.Sprintf(endpointAddressReferencePattern, namespace, endpoint.AddressesReference.Name)
+		if val, ok := addressesMap[key]; ok {
+			endpoints[i].Addresses = val
+			continue
+		}
+
+		var addresses kubelbv1alpha1.Addresses
+		if err := client.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: endpoint.AddressesReference.Name}, &addresses); err != nil {
+			return fmt.Errorf("failed to get addresses: %w", err)
+		}
+		addressesMap[key] = addresses.Spec.Addresses
+		endpoints[i].Addresses = addresses.Spec.Addresses
+	}
+	return nil
+}
+
+// newSnapshot creates a snapshot where each resource type is versioned independently. This way, a change in the endpoints only results
+// in an EDS update while the clusters and listeners remain untouched.
+func newSnapshot(resources map[resource.Type][]types.Resource) (*envoycache.Snapshot, error) {
+	snapshot := &envoycache.Snapshot{}
+	for typ, items := range resources {
+		var content []byte
+		for _, r := range items {
+			mr, err := envoycache.MarshalResource(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal resource: %w", err)
+			}
+			content = append(content, mr...)
+		}
+		snapshot.Resources[envoycache.GetResponseType(typ)] = envoycache.NewResources(envoycache.HashResource(content), items)
+	}
+	return snapshot, nil
+}
+
+// SnapshotIsEqual returns true if the versions of all the resource types in both snapshots are the same.
+func SnapshotIsEqual(actual, desired envoycache.ResourceSnapshot) bool {
+	for _, typ := range []resource.Type{resource.ClusterType, resource.EndpointType, resource.ListenerType, resource.SecretType} {
+		if actual.GetVersion(typ) != desired.GetVersion(typ) {
+			return false
+		}
+	}
+	return true
+}
+
+// makeCluster generates an EDS cluster, the endpoints of the cluster are served separately by makeClusterLoadAssignment.
+func makeCluster(clusterName string, policy *kubelbv1alpha1.LoadBalancingPolicy, healthCheck *kubelbv1alpha1.HealthCheck, healthCheckMode kubelbv1alpha1.HealthCheckMode) *envoyCluster.Cluster {
+	cluster := &envoyCluster.Cluster{
 		Name:                 clusterName,
-		ConnectTimeout:       durationpb.New(5 * time.Second),
-		ClusterDiscoveryType: &envoyCluster.Cluster_Type{Type: envoyCluster.Cluster_STRICT_DNS},
-		LbPolicy:             envoyCluster.Cluster_ROUND_ROBIN,
-		LoadAssignment: &envoyEndpoint.ClusterLoadAssignment{
-			ClusterName: clusterName,
-			Endpoints: []*envoyEndpoint.LocalityLbEndpoints{{
-				LbEndpoints: lbEndpoints,
-			}},
-		},
-		DnsLookupFamily: envoyCluster.Cluster_V4_ONLY,
-		HealthChecks: []*envoyCore.HealthCheck{
-			{
-				Timeout:            &duration.Duration{Seconds: 5},
-				Interval:           &duration.Duration{Seconds: 5},
-				UnhealthyThreshold: &wrappers.UInt32Value{Value: 3},
-				HealthyThreshold:   &wrappers.UInt32Value{Value: 3},
-				HealthChecker: &envoyCore.HealthCheck_TcpHealthCheck_{
-					TcpHealthCheck: &envoyCore.HealthCheck_TcpHealthCheck{},
-				},
-			},
+		ConnectTimeout:       durationpb.New(defaultConnectTimeout),
+		ClusterDiscoveryType: &envoyCluster.Cluster_Type{Type: envoyCluster.Cluster_EDS},
+		EdsClusterConfig: &envoyCluster.Cluster_EdsClusterConfig{
+			// The endpoints are fetched from the same config source as the cluster, this works for all the xDS modes.
+			EdsConfig: makeSelfConfigSource(),
 		},
+		LbPolicy:     envoyCluster.Cluster_ROUND_ROBIN,
+		HealthChecks: makeHealthChecks(healthCheck, healthCheckMode),
 		CommonLbConfig: &envoyCluster.Cluster_CommonLbConfig{
 			HealthyPanicThreshold: &envoytypev3.Percent{Value: 0},
 		},
 	}
+	setLoadBalancingPolicy(cluster, policy)
+	return cluster
 }
 
-func makeEndpoint(address string, port uint32) *envoyEndpoint.LbEndpoint {
-	return &envoyEndpoint.LbEndpoint{
-		HostIdentifier: &envoyEndpoint.LbEndpoint_Endpoint{
-			Endpoint: &envoyEndpoint.Endpoint{
-				Address: &envoyCore.Address{
-					Address: &envoyCore.Address_SocketAddress{
-						SocketAddress: &envoyCore.SocketAddress{
-							Protocol: envoyCore.SocketAddress_TCP,
-							Address:  address,
-							PortSpecifier: &envoyCore.SocketAddress_PortValue{
-								PortValue: port,
-							},
-						},
-					},
-				},
+// makeSelfConfigSource generates a config source that points to the config source of the resource that references it.
+func makeSelfConfigSource() *envoyCore.ConfigSource {
+	return &envoyCore.ConfigSource{
+		ResourceApiVersion: envoyCore.ApiVersion_V3,
+		ConfigSourceSpecifier: &envoyCore.ConfigSource_Self{
+			Self: &envoyCore.SelfConfigSource{
+				TransportApiVersion: envoyCore.ApiVersion_V3,
 			},
 		},
 	}
 }
 
-func makeTCPListener(clusterName string, listenerName string, listenerPort uint32) *envoyListener.Listener {
-	tcpProxyAccessLog := &envoyFileAccessLog.FileAccessLog{
-		Path: "/dev/stdout",
+// setOverprovisioningFactor configures how early the connections fail over to the next priority level of the endpoints.
+func setOverprovisioningFactor(cla *envoyEndpoint.ClusterLoadAssignment, overprovisioningFactor *uint32) {
+	if overprovisioningFactor == nil {
+		return
 	}
-	tcpProxyAccessLogAny, err := anypb.New(tcpProxyAccessLog)
-	if err != nil {
-		panic(err)
+	cla.Policy = &envoyEndpoint.ClusterLoadAssignment_Policy{
+		OverprovisioningFactor: &wrappers.UInt32Value{Value: *overprovisioningFactor},
 	}
+}
 
-	tcpProxy := &envoyTcpProxy.TcpProxy{
-		StatPrefix: listenerName,
-		ClusterSpecifier: &envoyTcpProxy.TcpProxy_Cluster{
-			Cluster: clusterName,
+// makeHealthChecks generates the active health checks for a cluster. By default, a TCP connect health check is used. For the
+// healthCheckNodePort mode, kube-proxy is probed over HTTP instead.
+func makeHealthChecks(healthCheck *kubelbv1alpha1.HealthCheck, healthCheckMode kubelbv1alpha1.HealthCheckMode) []*envoyCore.HealthCheck {
+	if healthCheckMode == kubelbv1alpha1.HealthCheckModeDisabled {
+		return nil
+	}
+
+	hc := &envoyCore.HealthCheck{
+		Timeout:            durationpb.New(defaultHealthCheckTimeout),
+		Interval:           durationpb.New(defaultHealthCheckInterval),
+		UnhealthyThreshold: &wrappers.UInt32Value{Value: defaultHealthCheckUnhealthyThreshold},
+		HealthyThreshold:   &wrappers.UInt32Value{Value: defaultHealthCheckHealthyThreshold},
+		HealthChecker: &envoyCore.HealthCheck_TcpHealthCheck_{
+			TcpHealthCheck: &envoyCore.HealthCheck_TcpHealthCheck{},
 		},
-		AccessLog: []*envoyAccessLog.AccessLog{
-			{
-				Name: "envoy.file_access_log",
-				ConfigType: &envoyAccessLog.AccessLog_TypedConfig{
-					TypedConfig: tcpProxyAccessLogAny,
+	}
+
+	if healthCheck != nil {
+		if healthCheck.Timeout != nil {
+			hc.Timeout = durationpb.New(healthCheck.Timeout.Duration)
+		}
+		if healthCheck.Interval != nil {
+			hc.Interval = durationpb.New(healthCheck.Interval.Duration)
+		}
+		if healthCheck.UnhealthyThreshold != nil {
+			hc.UnhealthyThreshold = &wrappers.UInt32Value{Value: *healthCheck.UnhealthyThreshold}
+		}
+		if healthCheck.HealthyThreshold != nil {
+			hc.HealthyThreshold = &wrappers.UInt32Value{Value: *healthCheck.HealthyThreshold}
+		}
+	}
+
+	switch {
+	case healthCheckMode == kubelbv1alpha1.HealthCheckModeHealthCheckNodePort:
+		// kube-proxy responds with 200 only if the node has local endpoints for the service.
+		hc.HealthChecker = &envoyCore.HealthCheck_HttpHealthCheck_{
+			HttpHealthCheck: &envoyCore.HealthCheck_HttpHealthCheck{
+				Path: kubeProxyHealthCheckPath,
+			},
+		}
+	case healthCheck == nil:
+	case healthCheck.HTTP != nil:
+		httpHealthCheck := &envoyCore.HealthCheck_HttpHealthCheck{
+			Path: healthCheck.HTTP.Path,
+			Host: healthCheck.HTTP.Host,
+		}
+		for _, code := range healthCheck.HTTP.ExpectedStatusCodes {
+			// Ranges are half-open i.e. [start, end).
+			httpHealthCheck.ExpectedStatuses = append(httpHealthCheck.ExpectedStatuses, &envoytypev3.Int64Range{Start: int64(code), End: int64(code) + 1})
+		}
+		hc.HealthChecker = &envoyCore.HealthCheck_HttpHealthCheck_{
+			HttpHealthCheck: httpHealthCheck,
+		}
+	case healthCheck.TCP != nil:
+		tcpHealthCheck := &envoyCore.HealthCheck_TcpHealthCheck{}
+		if healthCheck.TCP.Send != "" {
+			tcpHealthCheck.Send = makeHealthCheckPayload(healthCheck.TCP.Send)
+		}
+		for _, receive := range healthCheck.TCP.Receive {
+			tcpHealthCheck.Receive = append(tcpHealthCheck.Receive, makeHealthCheckPayload(receive))
+		}
+		hc.HealthChecker = &envoyCore.HealthCheck_TcpHealthCheck_{
+			TcpHealthCheck: tcpHealthCheck,
+		}
+	}
+	return []*envoyCore.HealthCheck{hc}
+}
+
+// makeHealthCheckPayload converts a plain text payload to the hex encoded text payload expected by Envoy.
+func makeHealthCheckPayload(payload string) *envoyCore.HealthCheck_Payload {
+	return &envoyCore.HealthCheck_Payload{
+		Payload: &envoyCore.HealthCheck_Payload_Text{
+			Text: hex.EncodeToString([]byte(payload)),
+		},
+	}
+}
+
+// setLoadBalancingPolicy configures the load balancing algorithm of the cluster along with the algorithm specific configuration.
+func setLoadBalancingPolicy(cluster *envoyCluster.Cluster, policy *kubelbv1alpha1.LoadBalancingPolicy) {
+	if policy == nil {
+		return
+	}
+
+	switch policy.Type {
+	case kubelbv1alpha1.LoadBalancingPolicyLeastRequest:
+		cluster.LbPolicy = envoyCluster.Cluster_LEAST_REQUEST
+		if policy.LeastRequest != nil && policy.LeastRequest.ChoiceCount != nil {
+			cluster.LbConfig = &envoyCluster.Cluster_LeastRequestLbConfig_{
+				LeastRequestLbConfig: &envoyCluster.Cluster_LeastRequestLbConfig{
+					ChoiceCount: &wrappers.UInt32Value{Value: *policy.LeastRequest.ChoiceCount},
+				},
+			}
+		}
+	case kubelbv1alpha1.LoadBalancingPolicyRandom:
+		cluster.LbPolicy = envoyCluster.Cluster_RANDOM
+	case kubelbv1alpha1.LoadBalancingPolicyRingHash:
+		cluster.LbPolicy = envoyCluster.Cluster_RING_HASH
+		if policy.RingHash != nil {
+			ringHash := &envoyCluster.Cluster_RingHashLbConfig{}
+			if policy.RingHash.MinimumRingSize != nil {
+				ringHash.MinimumRingSize = &wrappers.UInt64Value{Value: *policy.RingHash.MinimumRingSize}
+			}
+			if policy.RingHash.MaximumRingSize != nil {
+				ringHash.MaximumRingSize = &wrappers.UInt64Value{Value: *policy.RingHash.MaximumRingSize}
+			}
+			cluster.LbConfig = &envoyCluster.Cluster_RingHashLbConfig_{RingHashLbConfig: ringHash}
+		}
+	case kubelbv1alpha1.LoadBalancingPolicyMaglev:
+		cluster.LbPolicy = envoyCluster.Cluster_MAGLEV
+		if policy.Maglev != nil && policy.Maglev.TableSize != nil {
+			cluster.LbConfig = &envoyCluster.Cluster_MaglevLbConfig_{
+				MaglevLbConfig: &envoyCluster.Cluster_MaglevLbConfig{
+					TableSize: &wrappers.UInt64Value{Value: *policy.Maglev.TableSize},
 				},
+			}
+		}
+	default:
+		cluster.LbPolicy = envoyCluster.Cluster_ROUND_ROBIN
+	}
+}
+
+// setCircuitBreakers limits the connections to the endpoints of the cluster. The remaining capacity is tracked so that it's exposed in
+// the statistics of the cluster next to the overflow counters.
+func setCircuitBreakers(cluster *envoyCluster.Cluster, circuitBreakers *kubelbv1alpha1.CircuitBreakers) {
+	if circuitBreakers == nil {
+		return
+	}
+
+	thresholds := &envoyCluster.CircuitBreakers_Thresholds{
+		Priority:       envoyCore.RoutingPriority_DEFAULT,
+		TrackRemaining: true,
+	}
+	if circuitBreakers.MaxConnections != nil {
+		thresholds.MaxConnections = &wrappers.UInt32Value{Value: *circuitBreakers.MaxConnections}
+	}
+	if circuitBreakers.MaxPendingRequests != nil {
+		thresholds.MaxPendingRequests = &wrappers.UInt32Value{Value: *circuitBreakers.MaxPendingRequests}
+	}
+	if circuitBreakers.MaxRetries != nil {
+		thresholds.MaxRetries = &wrappers.UInt32Value{Value: *circuitBreakers.MaxRetries}
+	}
+	cluster.CircuitBreakers = &envoyCluster.CircuitBreakers{
+		Thresholds: []*envoyCluster.CircuitBreakers_Thresholds{thresholds},
+	}
+}
+
+// setOutlierDetection ejects the endpoints of the cluster that repeatedly fail to accept connections. Connection failures and timeouts
+// are locally originated errors in Envoy, hence they are tracked separately and the detection based on HTTP responses is not enforced.
+func setOutlierDetection(cluster *envoyCluster.Cluster, outlierDetection *kubelbv1alpha1.OutlierDetection) {
+	if outlierDetection == nil || outlierDetection.Disable {
+		return
+	}
+
+	consecutiveFailures := uint32(defaultOutlierDetectionConsecutiveFailures)
+	if outlierDetection.ConsecutiveConnectionFailures != nil {
+		consecutiveFailures = *outlierDetection.ConsecutiveConnectionFailures
+	}
+	interval := defaultOutlierDetectionInterval
+	if outlierDetection.Interval != nil {
+		interval = outlierDetection.Interval.Duration
+	}
+	baseEjectionTime := defaultOutlierDetectionBaseEjectionTime
+	if outlierDetection.BaseEjectionTime != nil {
+		baseEjectionTime = outlierDetection.BaseEjectionTime.Duration
+	}
+	maxEjectionPercent := uint32(defaultOutlierDetectionMaxEjectionPercent)
+	if outlierDetection.MaxEjectionPercent != nil {
+		maxEjectionPercent = *outlierDetection.MaxEjectionPercent
+	}
+
+	cluster.OutlierDetection = &envoyCluster.OutlierDetection{
+		SplitExternalLocalOriginErrors:         true,
+		ConsecutiveLocalOriginFailure:          &wrappers.UInt32Value{Value: consecutiveFailures},
+		EnforcingConsecutiveLocalOriginFailure: &wrappers.UInt32Value{Value: 100},
+		EnforcingConsecutive_5Xx:               &wrappers.UInt32Value{Value: 0},
+		EnforcingSuccessRate:                   &wrappers.UInt32Value{Value: 0},
+		EnforcingLocalOriginSuccessRate:        &wrappers.UInt32Value{Value: 0},
+		Interval:                               durationpb.New(interval),
+		BaseEjectionTime:                       durationpb.New(baseEjectionTime),
+		MaxEjectionPercent:                     &wrappers.UInt32Value{Value: maxEjectionPercent},
+	}
+}
+
+// setTCPSettings configures the connect timeout and the TCP keepalive of the connections to the endpoints.
+func setTCPSettings(cluster *envoyCluster.Cluster, tcp *kubelbv1alpha1.TCPSettings) {
+	if tcp == nil {
+		return
+	}
+
+	if tcp.ConnectTimeout != nil {
+		cluster.ConnectTimeout = durationpb.New(tcp.ConnectTimeout.Duration)
+	}
+	if tcp.Keepalive != nil {
+		keepalive := &envoyCore.TcpKeepalive{}
+		if tcp.Keepalive.Probes != nil {
+			keepalive.KeepaliveProbes = &wrappers.UInt32Value{Value: *tcp.Keepalive.Probes}
+		}
+		if tcp.Keepalive.Time != nil {
+			keepalive.KeepaliveTime = &wrappers.UInt32Value{Value: uint32(tcp.Keepalive.Time.Seconds())}
+		}
+		if tcp.Keepalive.Interval != nil {
+			keepalive.KeepaliveInterval = &wrappers.UInt32Value{Value: uint32(tcp.Keepalive.Interval.Seconds())}
+		}
+		cluster.UpstreamConnectionOptions = &envoyCluster.UpstreamConnectionOptions{
+			TcpKeepalive: keepalive,
+		}
+	}
+}
+
+// setUpstreamProxyProtocol wraps the transport socket of the cluster to send the PROXY protocol header with the address of the client
+// to the endpoints. The header is sent before the TLS handshake if TLS is originated to the endpoints.
+func setUpstreamProxyProtocol(cluster *envoyCluster.Cluster, version kubelbv1alpha1.ProxyProtocolVersion) {
+	if version == "" {
+		return
+	}
+
+	proxyProtocolVersion := envoyCore.ProxyProtocolConfig_V1
+	if version == kubelbv1alpha1.ProxyProtocolVersionV2 {
+		proxyProtocolVersion = envoyCore.ProxyProtocolConfig_V2
+	}
+
+	transportSocket := cluster.TransportSocket
+	if transportSocket == nil {
+		rawBuffer, err := anypb.New(&envoyRawBuffer.RawBuffer{})
+		if err != nil {
+			panic(err)
+		}
+		transportSocket = &envoyCore.TransportSocket{
+			Name: wellknown.TransportSocketRawBuffer,
+			ConfigType: &envoyCore.TransportSocket_TypedConfig{
+				TypedConfig: rawBuffer,
 			},
+		}
+	}
+	upstreamTransport, err := anypb.New(&envoyProxyProtocolTransport.ProxyProtocolUpstreamTransport{
+		Config: &envoyCore.ProxyProtocolConfig{
+			Version: proxyProtocolVersion,
 		},
+		TransportSocket: transportSocket,
+	})
+	if err != nil {
+		panic(err)
 	}
-	pbst, err := anypb.New(tcpProxy)
+
+	cluster.TransportSocket = &envoyCore.TransportSocket{
+		Name: upstreamProxyProtocolTransportSocket,
+		ConfigType: &envoyCore.TransportSocket_TypedConfig{
+			TypedConfig: upstreamTransport,
+		},
+	}
+}
+
+// limitConnectionRate adds a local rate limit filter in front of the filters of the filter chain. The token bucket is shared by all the
+// filters with the same key. The key is derived from EnvoyConnectionRateLimitPattern for each port of a LoadBalancer, so it's used by a
+// single filter chain: the dedicated listener of the port, which is only generated for the first set of endpoints, or the filter chain
+// of the LoadBalancer in the shared TLS passthrough listener. LoadBalancers sharing a TLS passthrough listener don't share a bucket.
+func limitConnectionRate(filterChain *envoyListener.FilterChain, key string, rateLimit *kubelbv1alpha1.ConnectionRateLimit) {
+	tokenBucket := &envoytypev3.TokenBucket{
+		MaxTokens:    rateLimit.MaxTokens,
+		FillInterval: durationpb.New(rateLimit.FillInterval.Duration),
+	}
+	if rateLimit.TokensPerFill != nil {
+		tokenBucket.TokensPerFill = &wrappers.UInt32Value{Value: *rateLimit.TokensPerFill}
+	}
+
+	localRateLimit, err := anypb.New(&envoyLocalRateLimit.LocalRateLimit{
+		StatPrefix:  key,
+		TokenBucket: tokenBucket,
+		ShareKey:    key,
+	})
 	if err != nil {
 		panic(err)
 	}
 
-	return &envoyListener.Listener{
-		Name: listenerName,
+	filterChain.Filters = append([]*envoyListener.Filter{{
+		Name: localRateLimitFilter,
+		ConfigType: &envoyListener.Filter_TypedConfig{
+			TypedConfig: localRateLimit,
+		},
+	}}, filterChain.Filters...)
+}
+
+// acceptProxyProtocol configures the listener to read the PROXY protocol header sent by a downstream load balancer.
+func acceptProxyProtocol(listener *envoyListener.Listener) {
+	proxyProtocol, err := anypb.New(&envoyProxyProtocolFilter.ProxyProtocol{})
+	if err != nil {
+		panic(err)
+	}
+
+	listener.ListenerFilters = append(listener.ListenerFilters, &envoyListener.ListenerFilter{
+		Name: wellknown.ProxyProtocol,
+		ConfigType: &envoyListener.ListenerFilter_TypedConfig{
+			TypedConfig: proxyProtocol,
+		},
+	})
+}
+
+// getLoadBalancingPolicy returns the load balancing policy for a cluster. With ClientIP session affinity, a policy that uses the source
+// IP as the hash key is required. Maglev is used unless such a policy is already configured.
+func getLoadBalancingPolicy(policy *kubelbv1alpha1.LoadBalancingPolicy, sessionAffinity *kubelbv1alpha1.SessionAffinity) *kubelbv1alpha1.LoadBalancingPolicy {
+	if sessionAffinity == nil || sessionAffinity.Type != corev1.ServiceAffinityClientIP {
+		return policy
+	}
+	return withSourceIPHash(policy)
+}
+
+// withSourceIPHash returns the policy if it uses the source IP as the hash key, otherwise the Maglev policy is returned.
+func withSourceIPHash(policy *kubelbv1alpha1.LoadBalancingPolicy) *kubelbv1alpha1.LoadBalancingPolicy {
+	if hashOnSourceIP(policy) {
+		return policy
+	}
+	return &kubelbv1alpha1.LoadBalancingPolicy{
+		Type: kubelbv1alpha1.LoadBalancingPolicyMaglev,
+	}
+}
+
+// getConnectionRateLimit returns the connection rate limit for a port of the LoadBalancer.
+func getConnectionRateLimit(lb *kubelbv1alpha1.LoadBalancer, portIndex int) *kubelbv1alpha1.ConnectionRateLimit {
+	if portIndex < len(lb.Spec.Ports) {
+		return lb.Spec.Ports[portIndex].ConnectionRateLimit
+	}
+	return nil
+}
+
+// weightedCluster is a cluster that receives a share of the connections of a listener.
+type weightedCluster struct {
+	name   string
+	weight uint32
+}
+
+// getWeightedClusters returns the clusters of the sets of endpoints with the highest priority for a port of the LoadBalancer. The sets of
+// endpoints with a lower priority are reached through the failover of these clusters.
+func getWeightedClusters(lb *kubelbv1alpha1.LoadBalancer, portIndex int) []weightedCluster {
+	levels := kubelb.GetPriorityLevels(lb, portIndex)
+	if len(levels) == 0 {
+		return nil
+	}
+
+	clusters := make([]weightedCluster, 0, len(levels[0]))
+	for _, i := range levels[0] {
+		endpointPort := lb.Spec.Endpoints[i].Ports[portIndex]
+		clusters = append(clusters, weightedCluster{
+			name:   fmt.Sprintf(kubelb.EnvoyResourceIdentifierPattern, lb.Namespace, lb.Name, i, endpointPort.Port, endpointPort.Protocol),
+			weight: kubelb.GetEndpointsWeight(&lb.Spec.Endpoints[i]),
+		})
+	}
+	return clusters
+}
+
+// getUDPCluster returns the cluster with the highest weight for a UDP port of the LoadBalancer, the UDP proxy can't split the sessions
+// across multiple clusters.
+func getUDPCluster(lb *kubelbv1alpha1.LoadBalancer, portIndex int) string {
+	var udpCluster weightedCluster
+	for _, cluster := range getWeightedClusters(lb, portIndex) {
+		if cluster.weight > udpCluster.weight {
+			udpCluster = cluster
+		}
+	}
+	return udpCluster.name
+}
+
+// makeLoadBalancerEndpoints generates the endpoints of the cluster of a set of endpoints for a port of the LoadBalancer, one entry per
+// priority level. The sets of endpoints with a lower priority are added as the next priority levels, connections fail over to them as the
+// endpoints of the set become unhealthy.
+func makeLoadBalancerEndpoints(lb *kubelbv1alpha1.LoadBalancer, endpointsIndex, portIndex int, healthCheckPort uint32) []localityEndpoints {
+	var priorities []localityEndpoints
+	for _, level := range kubelb.GetPriorityLevels(lb, portIndex) {
+		if lb.Spec.Endpoints[level[0]].Priority < lb.Spec.Endpoints[endpointsIndex].Priority {
+			continue
+		}
+		if lb.Spec.Endpoints[level[0]].Priority == lb.Spec.Endpoints[endpointsIndex].Priority {
+			// The other sets of endpoints with the same priority have their own clusters.
+			level = []int{endpointsIndex}
+		}
+
+		lbEndpoints := make(localityEndpoints)
+		for _, i := range level {
+			// each address -> one port
+			port := uint32(lb.Spec.Endpoints[i].Ports[portIndex].Port)
+			for _, address := range lb.Spec.Endpoints[i].Addresses {
+				lbEndpoints.add(address, makeEndpoint(getEndpointAddress(address), port, healthCheckPort))
+			}
+		}
+		priorities = append(priorities, lbEndpoints)
+	}
+	return priorities
+}
+
+// getRouteDNSSettings returns the DNS settings for the clusters of a Route. All the sets of endpoints of a Route share the same clusters, the
+// settings of the first set are used.
+func getRouteDNSSettings(route *kubelbv1alpha1.Route) *kubelbv1alpha1.DNSSettings {
+	if len(route.Spec.Endpoints) == 0 {
+		return nil
+	}
+	return route.Spec.Endpoints[0].DNS
+}
+
+// getUDPSettings returns the UDP proxy settings for a port of the LoadBalancer.
+func getUDPSettings(lb *kubelbv1alpha1.LoadBalancer, portIndex int) *kubelbv1alpha1.UDPSettings {
+	if portIndex < len(lb.Spec.Ports) {
+		return lb.Spec.Ports[portIndex].UDP
+	}
+	return nil
+}
+
+// hashOnSourceIP returns true if the policy requires a hash key. For L4 traffic, the source IP of the client is used as the hash key.
+func hashOnSourceIP(policy *kubelbv1alpha1.LoadBalancingPolicy) bool {
+	return policy != nil && (policy.Type == kubelbv1alpha1.LoadBalancingPolicyRingHash || policy.Type == kubelbv1alpha1.LoadBalancingPolicyMaglev)
+}
+
+// makeEndpoint generates an endpoint. If healthCheckPort is set, the endpoint is health checked on that port instead of the traffic port.
+func makeEndpoint(address string, port uint32, healthCheckPort uint32) *envoyEndpoint.LbEndpoint {
+	endpoint := &envoyEndpoint.Endpoint{
 		Address: &envoyCore.Address{
 			Address: &envoyCore.Address_SocketAddress{
 				SocketAddress: &envoyCore.SocketAddress{
 					Protocol: envoyCore.SocketAddress_TCP,
-					Address:  "0.0.0.0",
+					Address:  address,
 					PortSpecifier: &envoyCore.SocketAddress_PortValue{
-						PortValue: listenerPort,
+						PortValue: port,
 					},
 				},
 			},
 		},
+	}
+	if healthCheckPort != 0 {
+		endpoint.HealthCheckConfig = &envoyEndpoint.Endpoint_HealthCheckConfig{
+			PortValue: healthCheckPort,
+		}
+	}
+
+	return &envoyEndpoint.LbEndpoint{
+		HostIdentifier: &envoyEndpoint.LbEndpoint_Endpoint{
+			Endpoint: endpoint,
+		},
+	}
+}
+
+// makeListenerAddress returns the address that the listener binds to. For IPv6 and dual-stack, the listener binds to the IPv6 wildcard
+// address. With dual-stack, IPv4 connections are accepted on the same socket and Envoy reports the IPv4 address of the client.
+func makeListenerAddress(protocol envoyCore.SocketAddress_Protocol, listenerPort uint32, ipFamilies []corev1.IPFamily) *envoyCore.Address {
+	socketAddress := &envoyCore.SocketAddress{
+		Protocol: protocol,
+		Address:  "0.0.0.0",
+		PortSpecifier: &envoyCore.SocketAddress_PortValue{
+			PortValue: listenerPort,
+		},
+	}
+
+	for _, family := range ipFamilies {
+		if family == corev1.IPv6Protocol {
+			socketAddress.Address = "::"
+			socketAddress.Ipv4Compat = len(ipFamilies) > 1
+		}
+	}
+
+	return &envoyCore.Address{
+		Address: &envoyCore.Address_SocketAddress{
+			SocketAddress: socketAddress,
+		},
+	}
+}
+
+func makeTCPListener(clusters []weightedCluster, listenerName string, listenerPort uint32, ipFamilies []corev1.IPFamily, policy *kubelbv1alpha1.LoadBalancingPolicy,
+	tcp *kubelbv1alpha1.TCPSettings, accessLogs []*envoyAccessLog.AccessLog) *envoyListener.Listener {
+	return &envoyListener.Listener{
+		Name:    listenerName,
+		Address: makeListenerAddress(envoyCore.SocketAddress_TCP, listenerPort, ipFamilies),
 		FilterChains: []*envoyListener.FilterChain{{
-			Filters: []*envoyListener.Filter{{
-				Name: wellknown.TCPProxy,
-				ConfigType: &envoyListener.Filter_TypedConfig{
-					TypedConfig: pbst,
-				},
-			}},
+			Filters: []*envoyListener.Filter{makeTCPProxyFilter(listenerName, clusters, policy, tcp, accessLogs)},
 		}},
 	}
 }
 
-func makeUDPListener(clusterName string, listenerName string, listenerPort uint32) *envoyListener.Listener {
+// makeTCPProxyFilter generates a TCP proxy filter for the clusters, connections are distributed by the weights of the clusters if there
+// are multiple clusters.
+func makeTCPProxyFilter(statPrefix string, clusters []weightedCluster, policy *kubelbv1alpha1.LoadBalancingPolicy, tcp *kubelbv1alpha1.TCPSettings,
+	accessLogs []*envoyAccessLog.AccessLog) *envoyListener.Filter {
+	tcpProxy := &envoyTcpProxy.TcpProxy{
+		StatPrefix: statPrefix,
+		AccessLog:  accessLogs,
+	}
+	if tcp != nil {
+		if tcp.IdleTimeout != nil {
+			tcpProxy.IdleTimeout = durationpb.New(tcp.IdleTimeout.Duration)
+		}
+		if tcp.MaxConnectionDuration != nil {
+			tcpProxy.MaxDownstreamConnectionDuration = durationpb.New(tcp.MaxConnectionDuration.Duration)
+		}
+	}
+	if len(clusters) == 1 {
+		tcpProxy.ClusterSpecifier = &envoyTcpProxy.TcpProxy_Cluster{
+			Cluster: clusters[0].name,
+		}
+	} else {
+		weightedClusters := &envoyTcpProxy.TcpProxy_WeightedCluster{}
+		for _, cluster := range clusters {
+			weightedClusters.Clusters = append(weightedClusters.Clusters, &envoyTcpProxy.TcpProxy_WeightedCluster_ClusterWeight{
+				Name:   cluster.name,
+				Weight: cluster.weight,
+			})
+		}
+		tcpProxy.ClusterSpecifier = &envoyTcpProxy.TcpProxy_WeightedClusters{
+			WeightedClusters: weightedClusters,
+		}
+	}
+	if hashOnSourceIP(policy) {
+		tcpProxy.HashPolicy = []*envoytypev3.HashPolicy{
+			{
+				PolicySpecifier: &envoytypev3.HashPolicy_SourceIp_{
+					SourceIp: &envoytypev3.HashPolicy_SourceIp{},
+				},
+			},
+		}
+	}
+
+	pbst, err := anypb.New(tcpProxy)
+	if err != nil {
+		panic(err)
+	}
+
+	return &envoyListener.Filter{
+		Name: wellknown.TCPProxy,
+		ConfigType: &envoyListener.Filter_TypedConfig{
+			TypedConfig: pbst,
+		},
+	}
+}
+
+func makeUDPListener(clusterName string, listenerName string, listenerPort uint32, ipFamilies []corev1.IPFamily, policy *kubelbv1alpha1.LoadBalancingPolicy,
+	udpSettings *kubelbv1alpha1.UDPSettings, sourceRanges *sourceRanges, accessLogs []*envoyAccessLog.AccessLog) *envoyListener.Listener {
 	udpProxy := &envoyUdpProxy.UdpProxyConfig{
 		StatPrefix: listenerName,
 		RouteSpecifier: &envoyUdpProxy.UdpProxyConfig_Cluster{
 			Cluster: clusterName,
 		},
+		IdleTimeout: durationpb.New(defaultUDPIdleTimeout),
+		// Session access logs are emitted once the UDP session ends.
+		AccessLog: accessLogs,
+	}
+	if hashOnSourceIP(policy) {
+		udpProxy.HashPolicies = []*envoyUdpProxy.UdpProxyConfig_HashPolicy{
+			{
+				PolicySpecifier: &envoyUdpProxy.UdpProxyConfig_HashPolicy_SourceIp{
+					SourceIp: true,
+				},
+			},
+		}
+	}
+	maxBufferedDatagrams := uint32(defaultUDPMaxBufferedDatagrams)
+	if udpSettings != nil {
+		if udpSettings.IdleTimeout != nil {
+			udpProxy.IdleTimeout = durationpb.New(udpSettings.IdleTimeout.Duration)
+		}
+		udpProxy.UsePerPacketLoadBalancing = udpSettings.LoadBalancingMode == kubelbv1alpha1.UDPLoadBalancingModePerPacket
+		if udpSettings.MaxBufferedDatagrams != nil {
+			maxBufferedDatagrams = *udpSettings.MaxBufferedDatagrams
+		}
+	}
+	// Network filters are not supported for UDP, the source ranges are enforced by routing only the permitted clients to the cluster.
+	if sourceRanges != nil {
+		udpProxy.RouteSpecifier = &envoyUdpProxy.UdpProxyConfig_Matcher{
+			Matcher: makeUDPSourceRangesMatcher(clusterName, listenerName, sourceRanges),
+		}
 	}
 
 	pbst, err := anypb.New(udpProxy)
@@ -292,18 +1209,8 @@ func makeUDPListener(clusterName string, listenerName string, listenerPort uint3
 	}
 
 	return &envoyListener.Listener{
-		Name: listenerName,
-		Address: &envoyCore.Address{
-			Address: &envoyCore.Address_SocketAddress{
-				SocketAddress: &envoyCore.SocketAddress{
-					Protocol: envoyCore.SocketAddress_UDP,
-					Address:  "0.0.0.0",
-					PortSpecifier: &envoyCore.SocketAddress_PortValue{
-						PortValue: listenerPort,
-					},
-				},
-			},
-		},
+		Name:    listenerName,
+		Address: makeListenerAddress(envoyCore.SocketAddress_UDP, listenerPort, ipFamilies),
 		ListenerFilters: []*envoyListener.ListenerFilter{
 			{
 				Name: "envoy.filters.udp_listener.udp_proxy",
@@ -312,6 +1219,16 @@ func makeUDPListener(clusterName string, listenerName string, listenerPort uint3
 				},
 			},
 		},
+		// The datagrams are buffered by the socket of the listener until Envoy reads them.
+		SocketOptions: []*envoyCore.SocketOption{
+			{
+				Description: "receive buffer for the buffered datagrams",
+				Level:       linuxSOLSocket,
+				Name:        linuxSORcvbuf,
+				Value:       &envoyCore.SocketOption_IntValue{IntValue: int64(maxBufferedDatagrams) * udpMaxDatagramSize},
+				State:       envoyCore.SocketOption_STATE_PREBIND,
+			},
+		},
 		ReusePort: true,
 	}
 }
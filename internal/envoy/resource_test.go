@@ -0,0 +1,551 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	envoyCluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoyEndpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoyUdpProxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/udp/udp_proxy/v3"
+	envoytypev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	envoycache "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+	portlookup "k8c.io/kubelb/internal/port-lookup"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newLoadBalancer(name string) kubelbv1alpha1.LoadBalancer {
+	return kubelbv1alpha1.LoadBalancer{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tenant-test"},
+		Spec: kubelbv1alpha1.LoadBalancerSpec{
+			Ports: []kubelbv1alpha1.LoadBalancerPort{{Port: 80, Protocol: corev1.ProtocolTCP}},
+			Endpoints: []kubelbv1alpha1.LoadBalancerEndpoints{{
+				Addresses: []kubelbv1alpha1.EndpointAddress{{IP: "10.0.0.1"}},
+				Ports:     []kubelbv1alpha1.EndpointPort{{Port: 30080, Protocol: corev1.ProtocolTCP}},
+			}},
+		},
+	}
+}
+
+func TestMapSnapshot(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kubelbv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name          string
+		modify        func(lb *kubelbv1alpha1.LoadBalancer)
+		served        bool
+		clusters      []string
+		skipped       bool
+		skippedReason string
+	}{
+		{
+			name:     "LoadBalancer is served",
+			modify:   func(*kubelbv1alpha1.LoadBalancer) {},
+			clusters: []string{"tenant-test-other-ep-0-port-30080-TCP", "tenant-test-test-ep-0-port-30080-TCP"},
+		},
+		{
+			name: "invalid source ranges",
+			modify: func(lb *kubelbv1alpha1.LoadBalancer) {
+				lb.Spec.SourceRanges = []string{"10.0.0.0/33"}
+			},
+			clusters:      []string{"tenant-test-other-ep-0-port-30080-TCP"},
+			skipped:       true,
+			skippedReason: kubelbv1alpha1.ReasonInvalidSourceRanges,
+		},
+		{
+			name: "missing addresses",
+			modify: func(lb *kubelbv1alpha1.LoadBalancer) {
+				lb.Spec.Endpoints[0].Addresses = nil
+				lb.Spec.Endpoints[0].AddressesReference = &corev1.ObjectReference{Name: "missing"}
+			},
+			clusters:      []string{"tenant-test-other-ep-0-port-30080-TCP"},
+			skipped:       true,
+			skippedReason: kubelbv1alpha1.ReasonAddressesUnavailable,
+		},
+		{
+			name: "missing addresses keep the served configuration",
+			modify: func(lb *kubelbv1alpha1.LoadBalancer) {
+				lb.Spec.Endpoints[0].Addresses = nil
+				lb.Spec.Endpoints[0].AddressesReference = &corev1.ObjectReference{Name: "missing"}
+			},
+			served:        true,
+			clusters:      []string{"tenant-test-other-ep-0-port-30080-TCP", "tenant-test-test-ep-0-port-30080-TCP"},
+			skipped:       true,
+			skippedReason: kubelbv1alpha1.ReasonAddressesUnavailable,
+		},
+		{
+			name: "missing upstream TLS certificates",
+			modify: func(lb *kubelbv1alpha1.LoadBalancer) {
+				lb.Spec.UpstreamTLS = &kubelbv1alpha1.UpstreamTLS{CACertificateRef: &kubelbv1alpha1.SecretReference{Name: "missing"}}
+			},
+			clusters:      []string{"tenant-test-other-ep-0-port-30080-TCP"},
+			skipped:       true,
+			skippedReason: kubelbv1alpha1.ReasonUpstreamTLSUnavailable,
+		},
+		{
+			name: "upstream TLS without verification of the certificates",
+			modify: func(lb *kubelbv1alpha1.LoadBalancer) {
+				lb.Spec.UpstreamTLS = &kubelbv1alpha1.UpstreamTLS{SNI: "backend.example.com"}
+			},
+			clusters:      []string{"tenant-test-other-ep-0-port-30080-TCP"},
+			skipped:       true,
+			skippedReason: kubelbv1alpha1.ReasonUpstreamTLSUnavailable,
+		},
+		{
+			name: "upstream TLS with insecure skip verify",
+			modify: func(lb *kubelbv1alpha1.LoadBalancer) {
+				lb.Spec.UpstreamTLS = &kubelbv1alpha1.UpstreamTLS{SNI: "backend.example.com", InsecureSkipVerify: true}
+			},
+			clusters: []string{"tenant-test-other-ep-0-port-30080-TCP", "tenant-test-test-ep-0-port-30080-TCP"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lb := newLoadBalancer("test")
+			tc.modify(&lb)
+			client := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+			var settings SnapshotSettings
+			if tc.served {
+				served, _, err := MapSnapshot(context.Background(), client, []kubelbv1alpha1.LoadBalancer{newLoadBalancer("test")}, nil, nil, false, SnapshotSettings{})
+				if err != nil {
+					t.Fatalf("failed to map the served snapshot: %v", err)
+				}
+				settings.Previous = served
+			}
+
+			snapshot, skipped, err := MapSnapshot(context.Background(), client, []kubelbv1alpha1.LoadBalancer{newLoadBalancer("other"), lb}, nil, nil, false, settings)
+			if err != nil {
+				t.Fatalf("failed to map snapshot: %v", err)
+			}
+
+			var clusters []string
+			for name := range snapshot.GetResources(resource.ClusterType) {
+				clusters = append(clusters, name)
+			}
+			sort.Strings(clusters)
+			if !reflect.DeepEqual(clusters, tc.clusters) {
+				t.Errorf("expected clusters %v, got %v", tc.clusters, clusters)
+			}
+			if len(snapshot.GetResources(resource.ListenerType)) != len(tc.clusters) {
+				t.Errorf("expected %d listeners, got %d", len(tc.clusters), len(snapshot.GetResources(resource.ListenerType)))
+			}
+
+			if !tc.skipped {
+				if len(skipped) != 0 {
+					t.Errorf("expected no skipped objects, got %v", skipped)
+				}
+				return
+			}
+			if len(skipped) != 1 || skipped[0].Object.GetName() != "test" || skipped[0].Reason != tc.skippedReason {
+				t.Errorf("expected the LoadBalancer to be skipped for %s, got %v", tc.skippedReason, skipped)
+			}
+		})
+	}
+}
+
+func TestMapSnapshotRouteEndpoints(t *testing.T) {
+	testCases := []struct {
+		name          string
+		address       kubelbv1alpha1.EndpointAddress
+		discoveryType envoyCluster.Cluster_DiscoveryType
+		endpoints     int
+	}{
+		{
+			name:          "IP",
+			address:       kubelbv1alpha1.EndpointAddress{IP: "10.0.0.1"},
+			discoveryType: envoyCluster.Cluster_EDS,
+			endpoints:     2,
+		},
+		{
+			name:          "hostname",
+			address:       kubelbv1alpha1.EndpointAddress{Hostname: "node.example.com"},
+			discoveryType: envoyCluster.Cluster_STRICT_DNS,
+			endpoints:     1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			route := kubelbv1alpha1.Route{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "tenant-test"},
+				Spec: kubelbv1alpha1.RouteSpec{
+					Endpoints: []kubelbv1alpha1.LoadBalancerEndpoints{{Addresses: []kubelbv1alpha1.EndpointAddress{tc.address}}},
+					Source: kubelbv1alpha1.RouteSource{Kubernetes: &kubelbv1alpha1.KubernetesSource{
+						Services: []kubelbv1alpha1.UpstreamService{{Service: corev1.Service{
+							ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default", UID: "uid"},
+							Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80, NodePort: 30080, Protocol: corev1.ProtocolTCP}}},
+						}}},
+					}},
+				},
+			}
+
+			snapshot, skipped, err := MapSnapshot(context.Background(), nil, nil, []kubelbv1alpha1.Route{route}, portlookup.NewPortAllocator(), false, SnapshotSettings{})
+			if err != nil || len(skipped) != 0 {
+				t.Fatalf("failed to map snapshot: %v %v", err, skipped)
+			}
+
+			key := "tenant-tenant-test-route-default-backend-svc-uid-port-80-TCP"
+			cluster, ok := snapshot.GetResources(resource.ClusterType)[key].(*envoyCluster.Cluster)
+			if !ok {
+				t.Fatalf("expected cluster %q, got %v", key, snapshot.GetResources(resource.ClusterType))
+			}
+			if cluster.GetType() != tc.discoveryType {
+				t.Errorf("expected a %s cluster, got %s", tc.discoveryType, cluster.GetType())
+			}
+			if tc.address.Hostname != "" {
+				endpoint := cluster.GetLoadAssignment().GetEndpoints()[0].GetLbEndpoints()[0].GetEndpoint()
+				if address := endpoint.GetAddress().GetSocketAddress().GetAddress(); address != tc.address.Hostname {
+					t.Errorf("expected the cluster to resolve %q, got %q", tc.address.Hostname, address)
+				}
+			}
+			// The local cluster is always served.
+			if len(snapshot.GetResources(resource.EndpointType)) != tc.endpoints {
+				t.Errorf("expected %d cluster load assignments, got %d", tc.endpoints, len(snapshot.GetResources(resource.EndpointType)))
+			}
+		})
+	}
+}
+
+func TestMapSnapshotAddressesUpdate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kubelbv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	mapSnapshot := func(ip string) *envoycache.Snapshot {
+		addresses := &kubelbv1alpha1.Addresses{
+			ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "tenant-test"},
+			Spec:       kubelbv1alpha1.AddressesSpec{Addresses: []kubelbv1alpha1.EndpointAddress{{IP: ip}}},
+		}
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(addresses).Build()
+
+		lb := newLoadBalancer("test")
+		lb.Spec.Endpoints[0].Addresses = nil
+		lb.Spec.Endpoints[0].AddressesReference = &corev1.ObjectReference{Name: "default"}
+		snapshot, skipped, err := MapSnapshot(context.Background(), client, []kubelbv1alpha1.LoadBalancer{lb}, nil, nil, false, SnapshotSettings{})
+		if err != nil || len(skipped) != 0 {
+			t.Fatalf("failed to map snapshot: %v %v", err, skipped)
+		}
+		return snapshot
+	}
+
+	previous := mapSnapshot("10.0.0.1")
+	current := mapSnapshot("10.0.0.2")
+
+	// Only EDS is updated when the addresses change, the clusters and listeners are left untouched.
+	for _, typ := range []resource.Type{resource.ClusterType, resource.ListenerType} {
+		if previous.GetVersion(typ) != current.GetVersion(typ) {
+			t.Errorf("expected the version of %s to be unchanged, got %q and %q", typ, previous.GetVersion(typ), current.GetVersion(typ))
+		}
+		if len(previous.GetResources(typ)) != len(current.GetResources(typ)) {
+			t.Fatalf("expected the same number of %s, got %d and %d", typ, len(previous.GetResources(typ)), len(current.GetResources(typ)))
+		}
+		for name, r := range previous.GetResources(typ) {
+			if !proto.Equal(r, current.GetResources(typ)[name]) {
+				t.Errorf("expected %s %q to be unchanged", typ, name)
+			}
+		}
+	}
+
+	if previous.GetVersion(resource.EndpointType) == current.GetVersion(resource.EndpointType) {
+		t.Errorf("expected the version of the cluster load assignments to change")
+	}
+	key := "tenant-test-test-ep-0-port-30080-TCP"
+	cla, ok := current.GetResources(resource.EndpointType)[key].(*envoyEndpoint.ClusterLoadAssignment)
+	if !ok {
+		t.Fatalf("expected cluster load assignment %q, got %v", key, current.GetResources(resource.EndpointType))
+	}
+	if address := cla.GetEndpoints()[0].GetLbEndpoints()[0].GetEndpoint().GetAddress().GetSocketAddress().GetAddress(); address != "10.0.0.2" {
+		t.Errorf("expected the cluster load assignment to serve %q, got %q", "10.0.0.2", address)
+	}
+}
+
+func TestSetLoadBalancingPolicy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   *kubelbv1alpha1.LoadBalancingPolicy
+		lbPolicy envoyCluster.Cluster_LbPolicy
+		lbConfig proto.Message
+	}{
+		{
+			name:     "default",
+			lbPolicy: envoyCluster.Cluster_ROUND_ROBIN,
+		},
+		{
+			name:     "least request with choice count",
+			policy:   &kubelbv1alpha1.LoadBalancingPolicy{Type: kubelbv1alpha1.LoadBalancingPolicyLeastRequest, LeastRequest: &kubelbv1alpha1.LeastRequestConfig{ChoiceCount: ptr.To[uint32](3)}},
+			lbPolicy: envoyCluster.Cluster_LEAST_REQUEST,
+			lbConfig: &envoyCluster.Cluster_LeastRequestLbConfig{ChoiceCount: wrapperspb.UInt32(3)},
+		},
+		{
+			name:     "random",
+			policy:   &kubelbv1alpha1.LoadBalancingPolicy{Type: kubelbv1alpha1.LoadBalancingPolicyRandom},
+			lbPolicy: envoyCluster.Cluster_RANDOM,
+		},
+		{
+			name:     "ring hash with ring sizes",
+			policy:   &kubelbv1alpha1.LoadBalancingPolicy{Type: kubelbv1alpha1.LoadBalancingPolicyRingHash, RingHash: &kubelbv1alpha1.RingHashConfig{MinimumRingSize: ptr.To[uint64](1024), MaximumRingSize: ptr.To[uint64](4096)}},
+			lbPolicy: envoyCluster.Cluster_RING_HASH,
+			lbConfig: &envoyCluster.Cluster_RingHashLbConfig{MinimumRingSize: wrapperspb.UInt64(1024), MaximumRingSize: wrapperspb.UInt64(4096)},
+		},
+		{
+			name:     "maglev with table size",
+			policy:   &kubelbv1alpha1.LoadBalancingPolicy{Type: kubelbv1alpha1.LoadBalancingPolicyMaglev, Maglev: &kubelbv1alpha1.MaglevConfig{TableSize: ptr.To[uint64](65537)}},
+			lbPolicy: envoyCluster.Cluster_MAGLEV,
+			lbConfig: &envoyCluster.Cluster_MaglevLbConfig{TableSize: wrapperspb.UInt64(65537)},
+		},
+		{
+			name:     "maglev without table size",
+			policy:   &kubelbv1alpha1.LoadBalancingPolicy{Type: kubelbv1alpha1.LoadBalancingPolicyMaglev},
+			lbPolicy: envoyCluster.Cluster_MAGLEV,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &envoyCluster.Cluster{}
+			setLoadBalancingPolicy(cluster, tc.policy)
+
+			if cluster.GetLbPolicy() != tc.lbPolicy {
+				t.Errorf("expected load balancing policy %s, got %s", tc.lbPolicy, cluster.GetLbPolicy())
+			}
+			var lbConfig proto.Message
+			switch {
+			case cluster.GetLeastRequestLbConfig() != nil:
+				lbConfig = cluster.GetLeastRequestLbConfig()
+			case cluster.GetRingHashLbConfig() != nil:
+				lbConfig = cluster.GetRingHashLbConfig()
+			case cluster.GetMaglevLbConfig() != nil:
+				lbConfig = cluster.GetMaglevLbConfig()
+			}
+			if (lbConfig == nil) != (tc.lbConfig == nil) || (lbConfig != nil && !proto.Equal(lbConfig, tc.lbConfig)) {
+				t.Errorf("expected load balancing config %v, got %v", tc.lbConfig, lbConfig)
+			}
+		})
+	}
+}
+
+func TestMakeHealthChecks(t *testing.T) {
+	testCases := []struct {
+		name            string
+		healthCheck     *kubelbv1alpha1.HealthCheck
+		healthCheckMode kubelbv1alpha1.HealthCheckMode
+		expected        *envoyCore.HealthCheck
+	}{
+		{
+			name:            "disabled",
+			healthCheckMode: kubelbv1alpha1.HealthCheckModeDisabled,
+		},
+		{
+			name:            "TCP connect by default",
+			healthCheckMode: kubelbv1alpha1.HealthCheckModeTCP,
+			expected: &envoyCore.HealthCheck{
+				Timeout:            durationpb.New(defaultHealthCheckTimeout),
+				Interval:           durationpb.New(defaultHealthCheckInterval),
+				UnhealthyThreshold: wrapperspb.UInt32(defaultHealthCheckUnhealthyThreshold),
+				HealthyThreshold:   wrapperspb.UInt32(defaultHealthCheckHealthyThreshold),
+				HealthChecker:      &envoyCore.HealthCheck_TcpHealthCheck_{TcpHealthCheck: &envoyCore.HealthCheck_TcpHealthCheck{}},
+			},
+		},
+		{
+			name: "HTTP with custom thresholds",
+			healthCheck: &kubelbv1alpha1.HealthCheck{
+				Interval:           &metav1.Duration{Duration: time.Second},
+				Timeout:            &metav1.Duration{Duration: 2 * time.Second},
+				HealthyThreshold:   ptr.To[uint32](1),
+				UnhealthyThreshold: ptr.To[uint32](2),
+				HTTP:               &kubelbv1alpha1.HTTPHealthCheck{Path: "/ready", Host: "example.com", ExpectedStatusCodes: []int32{200, 204}},
+			},
+			healthCheckMode: kubelbv1alpha1.HealthCheckModeHTTP,
+			expected: &envoyCore.HealthCheck{
+				Timeout:            durationpb.New(2 * time.Second),
+				Interval:           durationpb.New(time.Second),
+				UnhealthyThreshold: wrapperspb.UInt32(2),
+				HealthyThreshold:   wrapperspb.UInt32(1),
+				HealthChecker: &envoyCore.HealthCheck_HttpHealthCheck_{HttpHealthCheck: &envoyCore.HealthCheck_HttpHealthCheck{
+					Path:             "/ready",
+					Host:             "example.com",
+					ExpectedStatuses: []*envoytypev3.Int64Range{{Start: 200, End: 201}, {Start: 204, End: 205}},
+				}},
+			},
+		},
+		{
+			name:            "TCP with payloads",
+			healthCheck:     &kubelbv1alpha1.HealthCheck{TCP: &kubelbv1alpha1.TCPHealthCheck{Send: "PING", Receive: []string{"PONG"}}},
+			healthCheckMode: kubelbv1alpha1.HealthCheckModeTCP,
+			expected: &envoyCore.HealthCheck{
+				Timeout:            durationpb.New(defaultHealthCheckTimeout),
+				Interval:           durationpb.New(defaultHealthCheckInterval),
+				UnhealthyThreshold: wrapperspb.UInt32(defaultHealthCheckUnhealthyThreshold),
+				HealthyThreshold:   wrapperspb.UInt32(defaultHealthCheckHealthyThreshold),
+				HealthChecker: &envoyCore.HealthCheck_TcpHealthCheck_{TcpHealthCheck: &envoyCore.HealthCheck_TcpHealthCheck{
+					Send:    &envoyCore.HealthCheck_Payload{Payload: &envoyCore.HealthCheck_Payload_Text{Text: "50494e47"}},
+					Receive: []*envoyCore.HealthCheck_Payload{{Payload: &envoyCore.HealthCheck_Payload_Text{Text: "504f4e47"}}},
+				}},
+			},
+		},
+		{
+			name:            "kube-proxy is probed for the health check node port",
+			healthCheck:     &kubelbv1alpha1.HealthCheck{HTTP: &kubelbv1alpha1.HTTPHealthCheck{Path: "/ready"}},
+			healthCheckMode: kubelbv1alpha1.HealthCheckModeHealthCheckNodePort,
+			expected: &envoyCore.HealthCheck{
+				Timeout:            durationpb.New(defaultHealthCheckTimeout),
+				Interval:           durationpb.New(defaultHealthCheckInterval),
+				UnhealthyThreshold: wrapperspb.UInt32(defaultHealthCheckUnhealthyThreshold),
+				HealthyThreshold:   wrapperspb.UInt32(defaultHealthCheckHealthyThreshold),
+				HealthChecker:      &envoyCore.HealthCheck_HttpHealthCheck_{HttpHealthCheck: &envoyCore.HealthCheck_HttpHealthCheck{Path: kubeProxyHealthCheckPath}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			healthChecks := makeHealthChecks(tc.healthCheck, tc.healthCheckMode)
+			if tc.expected == nil {
+				if len(healthChecks) != 0 {
+					t.Errorf("expected no health checks, got %v", healthChecks)
+				}
+				return
+			}
+			if len(healthChecks) != 1 || !proto.Equal(healthChecks[0], tc.expected) {
+				t.Errorf("expected health check %v, got %v", tc.expected, healthChecks)
+			}
+		})
+	}
+}
+
+func TestMakeListenerAddress(t *testing.T) {
+	testCases := []struct {
+		name       string
+		ipFamilies []corev1.IPFamily
+		address    string
+		ipv4Compat bool
+	}{
+		{
+			name:    "default",
+			address: "0.0.0.0",
+		},
+		{
+			name:       "IPv4",
+			ipFamilies: []corev1.IPFamily{corev1.IPv4Protocol},
+			address:    "0.0.0.0",
+		},
+		{
+			name:       "IPv6",
+			ipFamilies: []corev1.IPFamily{corev1.IPv6Protocol},
+			address:    "::",
+		},
+		{
+			name:       "dual stack",
+			ipFamilies: []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol},
+			address:    "::",
+			ipv4Compat: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			socketAddress := makeListenerAddress(envoyCore.SocketAddress_UDP, 53, tc.ipFamilies).GetSocketAddress()
+			if socketAddress.GetAddress() != tc.address || socketAddress.GetIpv4Compat() != tc.ipv4Compat {
+				t.Errorf("expected address %s with IPv4 compatibility %t, got %s with %t", tc.address, tc.ipv4Compat, socketAddress.GetAddress(), socketAddress.GetIpv4Compat())
+			}
+			if socketAddress.GetProtocol() != envoyCore.SocketAddress_UDP || socketAddress.GetPortValue() != 53 {
+				t.Errorf("expected UDP port 53, got %s port %d", socketAddress.GetProtocol(), socketAddress.GetPortValue())
+			}
+		})
+	}
+}
+
+func TestMakeUDPListener(t *testing.T) {
+	testCases := []struct {
+		name                      string
+		udpSettings               *kubelbv1alpha1.UDPSettings
+		idleTimeout               time.Duration
+		usePerPacketLoadBalancing bool
+		receiveBuffer             int64
+	}{
+		{
+			name:          "default",
+			idleTimeout:   defaultUDPIdleTimeout,
+			receiveBuffer: defaultUDPMaxBufferedDatagrams * udpMaxDatagramSize,
+		},
+		{
+			name:          "idle timeout",
+			udpSettings:   &kubelbv1alpha1.UDPSettings{IdleTimeout: &metav1.Duration{Duration: 5 * time.Minute}},
+			idleTimeout:   5 * time.Minute,
+			receiveBuffer: defaultUDPMaxBufferedDatagrams * udpMaxDatagramSize,
+		},
+		{
+			name:                      "per packet load balancing",
+			udpSettings:               &kubelbv1alpha1.UDPSettings{LoadBalancingMode: kubelbv1alpha1.UDPLoadBalancingModePerPacket},
+			idleTimeout:               defaultUDPIdleTimeout,
+			usePerPacketLoadBalancing: true,
+			receiveBuffer:             defaultUDPMaxBufferedDatagrams * udpMaxDatagramSize,
+		},
+		{
+			name:          "max buffered datagrams",
+			udpSettings:   &kubelbv1alpha1.UDPSettings{MaxBufferedDatagrams: ptr.To[uint32](64)},
+			idleTimeout:   defaultUDPIdleTimeout,
+			receiveBuffer: 64 * udpMaxDatagramSize,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := makeUDPListener("cluster", "listener", 53, nil, nil, tc.udpSettings, nil, nil)
+
+			udpProxy := &envoyUdpProxy.UdpProxyConfig{}
+			if err := listener.GetListenerFilters()[0].GetTypedConfig().UnmarshalTo(udpProxy); err != nil {
+				t.Fatalf("failed to unmarshal the UDP proxy: %v", err)
+			}
+			if udpProxy.GetIdleTimeout().AsDuration() != tc.idleTimeout {
+				t.Errorf("expected idle timeout %s, got %s", tc.idleTimeout, udpProxy.GetIdleTimeout().AsDuration())
+			}
+			if udpProxy.GetUsePerPacketLoadBalancing() != tc.usePerPacketLoadBalancing {
+				t.Errorf("expected per packet load balancing %t, got %t", tc.usePerPacketLoadBalancing, udpProxy.GetUsePerPacketLoadBalancing())
+			}
+			socketOptions := listener.GetSocketOptions()
+			if len(socketOptions) != 1 || socketOptions[0].GetName() != linuxSORcvbuf || socketOptions[0].GetIntValue() != tc.receiveBuffer {
+				t.Errorf("expected a receive buffer of %d bytes, got %v", tc.receiveBuffer, socketOptions)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package envoy
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
@@ -39,6 +40,18 @@ const (
 	grpcMaxConcurrentStreams = 1000000
 )
 
+// XDSMode is the transport protocol that is used by Envoy to fetch the dynamic resources from the control plane.
+type XDSMode string
+
+const (
+	// XDSModeDeltaADS uses incremental xDS over a single aggregated stream, only the resources that changed are sent to Envoy.
+	XDSModeDeltaADS XDSMode = "delta-ads"
+	// XDSModeADS uses state of the world xDS over a single aggregated stream.
+	XDSModeADS XDSMode = "ads"
+	// XDSModeSotW uses state of the world xDS with a separate stream per resource type.
+	XDSModeSotW XDSMode = "sotw"
+)
+
 func registerServer(grpcServer *grpc.Server, server serverv3.Server) {
 	// register services
 	discoverygrpc.RegisterAggregatedDiscoveryServiceServer(grpcServer, server)
@@ -51,31 +64,43 @@ func registerServer(grpcServer *grpc.Server, server serverv3.Server) {
 }
 
 type Server struct {
-	Cache         cachev3.SnapshotCache
+	Cache cachev3.SnapshotCache
+	// Tracker records the configuration versions that were accepted or rejected by the Envoy proxies.
+	Tracker       *ConfigTracker
 	listenAddress string
 	listenPort    uint32
+	xdsMode       XDSMode
 	enableAdmin   bool
 }
 
-func NewServer(listenAddress string, enableDebug bool) (*Server, error) {
+func NewServer(listenAddress string, xdsMode XDSMode, enableDebug bool) (*Server, error) {
 	portString := strings.Split(listenAddress, ":")[1]
 	port, err := strconv.ParseUint(portString, 10, 32)
 	if err != nil {
 		return nil, err
 	}
 
+	switch xdsMode {
+	case XDSModeDeltaADS, XDSModeADS, XDSModeSotW:
+	default:
+		return nil, fmt.Errorf("invalid xDS mode %q", xdsMode)
+	}
+
 	return &Server{
 		listenAddress: listenAddress,
 		listenPort:    uint32(port),
-		Cache:         cachev3.NewSnapshotCache(false, cachev3.IDHash{}, Logger{enableDebug}),
-		enableAdmin:   enableDebug,
+		// The snapshot cache serves both state of the world and incremental xDS, for the latter the resources are diffed per version.
+		Cache:       cachev3.NewSnapshotCache(xdsMode != XDSModeSotW, cachev3.IDHash{}, Logger{enableDebug}),
+		Tracker:     NewConfigTracker(),
+		xdsMode:     xdsMode,
+		enableAdmin: enableDebug,
 	}, nil
 }
 
 // Start the Envoy control plane server.
 func (s *Server) Start(ctx context.Context) error {
 	// Create a Cache
-	srv3 := serverv3.NewServer(ctx, s.Cache, nil)
+	srv3 := serverv3.NewServer(ctx, s.Cache, s.Tracker.Callbacks())
 
 	// gRPC golang library sets a very small upper bound for the number gRPC/h2
 	// streams over a single TCP connection. If a proxy multiplexes requests over
@@ -0,0 +1,204 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"fmt"
+	"net"
+
+	xdsCore "github.com/cncf/xds/go/xds/core/v3"
+	xdsMatcher "github.com/cncf/xds/go/xds/type/matcher/v3"
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoyListener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoyRBAC "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	envoyRBACFilter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+	envoyUdpProxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/udp/udp_proxy/v3"
+	envoyNetworkInputs "github.com/envoyproxy/go-control-plane/envoy/extensions/matching/common_inputs/network/v3"
+	envoyIPMatcher "github.com/envoyproxy/go-control-plane/envoy/extensions/matching/input_matchers/ip/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	rbacFilter            = "envoy.filters.network.rbac"
+	sourceIPInput         = "envoy.matching.inputs.source_ip"
+	ipMatcher             = "envoy.matching.matchers.ip"
+	udpProxyRouteAction   = "route"
+	sourceRangesPolicy    = "source-ranges"
+	deniedStatPrefixPart  = "denied"
+	allowedStatPrefixPart = "allowed"
+)
+
+// sourceRanges restrict the clients that can connect to a listener. Denied ranges take precedence over allowed ranges. If allowed ranges
+// are specified, only clients from these ranges are accepted.
+type sourceRanges struct {
+	allowed []*envoyCore.CidrRange
+	denied  []*envoyCore.CidrRange
+}
+
+// makeSourceRanges parses the allowed and denied CIDRs. Nil is returned if the listener is not restricted.
+func makeSourceRanges(allowed, denied []string) (*sourceRanges, error) {
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil, nil
+	}
+
+	var err error
+	ranges := &sourceRanges{}
+	if ranges.allowed, err = parseCIDRs(allowed); err != nil {
+		return nil, err
+	}
+	if ranges.denied, err = parseCIDRs(denied); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+func parseCIDRs(cidrs []string) ([]*envoyCore.CidrRange, error) {
+	var ranges []*envoyCore.CidrRange
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source range %q: %w", cidr, err)
+		}
+		prefixLength, _ := ipNet.Mask.Size()
+		ranges = append(ranges, &envoyCore.CidrRange{
+			AddressPrefix: ipNet.IP.String(),
+			PrefixLen:     &wrappers.UInt32Value{Value: uint32(prefixLength)},
+		})
+	}
+	return ranges, nil
+}
+
+// restrictSourceRanges adds RBAC filters in front of the filters of the filter chain. The remote address is matched, which is the address
+// of the client from the PROXY protocol header if the listener accepts the PROXY protocol.
+func restrictSourceRanges(filterChain *envoyListener.FilterChain, statPrefix string, ranges *sourceRanges) {
+	if ranges == nil {
+		return
+	}
+
+	var filters []*envoyListener.Filter
+	if len(ranges.denied) > 0 {
+		filters = append(filters, makeRBACFilter(fmt.Sprintf("%s-%s", statPrefix, deniedStatPrefixPart), envoyRBAC.RBAC_DENY, ranges.denied))
+	}
+	if len(ranges.allowed) > 0 {
+		filters = append(filters, makeRBACFilter(fmt.Sprintf("%s-%s", statPrefix, allowedStatPrefixPart), envoyRBAC.RBAC_ALLOW, ranges.allowed))
+	}
+	filterChain.Filters = append(filters, filterChain.Filters...)
+}
+
+func makeRBACFilter(statPrefix string, action envoyRBAC.RBAC_Action, cidrs []*envoyCore.CidrRange) *envoyListener.Filter {
+	policy := &envoyRBAC.Policy{
+		Permissions: []*envoyRBAC.Permission{
+			{Rule: &envoyRBAC.Permission_Any{Any: true}},
+		},
+	}
+	for _, cidr := range cidrs {
+		policy.Principals = append(policy.Principals, &envoyRBAC.Principal{
+			Identifier: &envoyRBAC.Principal_RemoteIp{RemoteIp: cidr},
+		})
+	}
+
+	rbac, err := anypb.New(&envoyRBACFilter.RBAC{
+		StatPrefix: statPrefix,
+		Rules: &envoyRBAC.RBAC{
+			Action:   action,
+			Policies: map[string]*envoyRBAC.Policy{sourceRangesPolicy: policy},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &envoyListener.Filter{
+		Name: rbacFilter,
+		ConfigType: &envoyListener.Filter_TypedConfig{
+			TypedConfig: rbac,
+		},
+	}
+}
+
+// makeUDPSourceRangesMatcher generates a matcher for the UDP proxy that only routes the datagrams of the permitted clients to the cluster.
+// The UDP proxy drops datagrams without a matching route.
+func makeUDPSourceRangesMatcher(clusterName, statPrefix string, ranges *sourceRanges) *xdsMatcher.Matcher {
+	var predicates []*xdsMatcher.Matcher_MatcherList_Predicate
+	if len(ranges.denied) > 0 {
+		predicates = append(predicates, &xdsMatcher.Matcher_MatcherList_Predicate{
+			MatchType: &xdsMatcher.Matcher_MatcherList_Predicate_NotMatcher{
+				NotMatcher: makeSourceIPPredicate(fmt.Sprintf("%s-%s", statPrefix, deniedStatPrefixPart), ranges.denied),
+			},
+		})
+	}
+	if len(ranges.allowed) > 0 {
+		predicates = append(predicates, makeSourceIPPredicate(fmt.Sprintf("%s-%s", statPrefix, allowedStatPrefixPart), ranges.allowed))
+	}
+
+	predicate := predicates[0]
+	if len(predicates) > 1 {
+		predicate = &xdsMatcher.Matcher_MatcherList_Predicate{
+			MatchType: &xdsMatcher.Matcher_MatcherList_Predicate_AndMatcher{
+				AndMatcher: &xdsMatcher.Matcher_MatcherList_Predicate_PredicateList{
+					Predicate: predicates,
+				},
+			},
+		}
+	}
+
+	return &xdsMatcher.Matcher{
+		MatcherType: &xdsMatcher.Matcher_MatcherList_{
+			MatcherList: &xdsMatcher.Matcher_MatcherList{
+				Matchers: []*xdsMatcher.Matcher_MatcherList_FieldMatcher{
+					{
+						Predicate: predicate,
+						OnMatch: &xdsMatcher.Matcher_OnMatch{
+							OnMatch: &xdsMatcher.Matcher_OnMatch_Action{
+								Action: makeTypedExtensionConfig(udpProxyRouteAction, &envoyUdpProxy.Route{Cluster: clusterName}),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func makeSourceIPPredicate(statPrefix string, cidrs []*envoyCore.CidrRange) *xdsMatcher.Matcher_MatcherList_Predicate {
+	return &xdsMatcher.Matcher_MatcherList_Predicate{
+		MatchType: &xdsMatcher.Matcher_MatcherList_Predicate_SinglePredicate_{
+			SinglePredicate: &xdsMatcher.Matcher_MatcherList_Predicate_SinglePredicate{
+				Input: makeTypedExtensionConfig(sourceIPInput, &envoyNetworkInputs.SourceIPInput{}),
+				Matcher: &xdsMatcher.Matcher_MatcherList_Predicate_SinglePredicate_CustomMatch{
+					CustomMatch: makeTypedExtensionConfig(ipMatcher, &envoyIPMatcher.Ip{
+						CidrRanges: cidrs,
+						StatPrefix: statPrefix,
+					}),
+				},
+			},
+		},
+	}
+}
+
+func makeTypedExtensionConfig(name string, config proto.Message) *xdsCore.TypedExtensionConfig {
+	typedConfig, err := anypb.New(config)
+	if err != nil {
+		panic(err)
+	}
+	return &xdsCore.TypedExtensionConfig{
+		Name:        name,
+		TypedConfig: typedConfig,
+	}
+}
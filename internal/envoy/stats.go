@@ -0,0 +1,238 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	statsClusterNameLabel     = "envoy_cluster_name"
+	statsEjectionsActive      = "envoy_cluster_outlier_detection_ejections_active"
+	statsConnectionOverflow   = "envoy_cluster_upstream_cx_overflow"
+	statsPendingOverflow      = "envoy_cluster_upstream_rq_pending_overflow"
+	statsRetryOverflow        = "envoy_cluster_upstream_rq_retry_overflow"
+	statsRateLimitPrefixLabel = "envoy_local_network_ratelimit_prefix"
+	statsRateLimited          = "envoy_local_rate_limit_rate_limited"
+
+	// DefaultOverprovisioningFactor is the overprovisioning factor that Envoy uses if the endpoints don't specify one.
+	DefaultOverprovisioningFactor = 140
+)
+
+// Stats are the statistics of the Envoy proxies that are reported in the status of the load balancers.
+type Stats struct {
+	// Clusters are the statistics of the clusters keyed by the name of the cluster.
+	Clusters map[string]ClusterStats
+	// RateLimitedConnections are the connections that were rejected by the connection rate limits keyed by the key of the rate limit.
+	RateLimitedConnections map[string]uint64
+}
+
+func NewStats() Stats {
+	return Stats{
+		Clusters:               make(map[string]ClusterStats),
+		RateLimitedConnections: make(map[string]uint64),
+	}
+}
+
+// Add adds the statistics of another Envoy proxy. Cluster statistics are merged, while the rejected connections are summed up since
+// each replica rejects connections independently.
+func (s Stats) Add(other Stats) {
+	for cluster, clusterStats := range other.Clusters {
+		s.Clusters[cluster] = s.Clusters[cluster].Merge(clusterStats)
+	}
+	for key, rateLimited := range other.RateLimitedConnections {
+		s.RateLimitedConnections[key] += rateLimited
+	}
+}
+
+// ClusterStats are the statistics of a cluster that are reported in the status of the load balancers.
+type ClusterStats struct {
+	EjectedEndpoints uint64
+	// ConnectionOverflows, PendingRequestOverflows and RetryOverflows are the connections, pending requests and retries that were
+	// rejected by the circuit breakers of the cluster since the Envoy proxy was started.
+	ConnectionOverflows     uint64
+	PendingRequestOverflows uint64
+	RetryOverflows          uint64
+	// Priorities are the endpoints of the priority levels of the cluster, starting with the highest priority.
+	Priorities []PriorityStats
+}
+
+// PriorityStats are the endpoints of a priority level of a cluster.
+type PriorityStats struct {
+	HealthyEndpoints uint64
+	Endpoints        uint64
+}
+
+// Merge combines the statistics of the same cluster that are observed by different Envoy proxies. The highest value is kept, since
+// the replicas share the same endpoints but track them independently. For the same reason, the lowest number of healthy endpoints is kept.
+// The overflows are summed up since each replica enforces the circuit breakers independently.
+func (s ClusterStats) Merge(other ClusterStats) ClusterStats {
+	merged := ClusterStats{
+		EjectedEndpoints:        max(s.EjectedEndpoints, other.EjectedEndpoints),
+		ConnectionOverflows:     s.ConnectionOverflows + other.ConnectionOverflows,
+		PendingRequestOverflows: s.PendingRequestOverflows + other.PendingRequestOverflows,
+		RetryOverflows:          s.RetryOverflows + other.RetryOverflows,
+	}
+	for i := 0; i < max(len(s.Priorities), len(other.Priorities)); i++ {
+		switch {
+		case i >= len(s.Priorities):
+			merged.Priorities = append(merged.Priorities, other.Priorities[i])
+		case i >= len(other.Priorities):
+			merged.Priorities = append(merged.Priorities, s.Priorities[i])
+		default:
+			merged.Priorities = append(merged.Priorities, PriorityStats{
+				HealthyEndpoints: min(s.Priorities[i].HealthyEndpoints, other.Priorities[i].HealthyEndpoints),
+				Endpoints:        max(s.Priorities[i].Endpoints, other.Priorities[i].Endpoints),
+			})
+		}
+	}
+	return merged
+}
+
+// ActivePriority returns the lowest priority level of the cluster that receives connections. It follows the load distribution of Envoy:
+// each priority level receives the share of the connections that its healthy endpoints, scaled by the overprovisioning factor, can handle
+// and the rest is sent to the next priority level. If there are no healthy endpoints at all, the connections are sent to the highest
+// priority level.
+func (s ClusterStats) ActivePriority(overprovisioningFactor uint32) int {
+	active := 0
+	remaining := uint64(100)
+	for i, priority := range s.Priorities {
+		if remaining == 0 {
+			break
+		}
+		if priority.Endpoints == 0 {
+			continue
+		}
+		health := min(100, uint64(overprovisioningFactor)*priority.HealthyEndpoints/priority.Endpoints)
+		if health > 0 {
+			active = i
+		}
+		remaining -= min(remaining, health)
+	}
+	return active
+}
+
+// ScrapeStats fetches the statistics and, if clusterStatus is true, the status of the clusters from the stats listener of an Envoy proxy.
+// The status of the clusters is only served if it is exposed in the bootstrap configuration.
+func ScrapeStats(ctx context.Context, client *http.Client, address string, clusterStatus bool) (Stats, error) {
+	body, err := get(ctx, client, fmt.Sprintf("http://%s%s", address, statsPath))
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to parse statistics: %w", err)
+	}
+
+	stats := NewStats()
+	for _, metric := range families[statsEjectionsActive].GetMetric() {
+		cluster := getLabelValue(metric, statsClusterNameLabel)
+		clusterStats := stats.Clusters[cluster]
+		clusterStats.EjectedEndpoints = uint64(metric.GetGauge().GetValue())
+		stats.Clusters[cluster] = clusterStats
+	}
+	for name, overflows := range map[string]func(*ClusterStats) *uint64{
+		statsConnectionOverflow: func(s *ClusterStats) *uint64 { return &s.ConnectionOverflows },
+		statsPendingOverflow:    func(s *ClusterStats) *uint64 { return &s.PendingRequestOverflows },
+		statsRetryOverflow:      func(s *ClusterStats) *uint64 { return &s.RetryOverflows },
+	} {
+		for _, metric := range families[name].GetMetric() {
+			cluster := getLabelValue(metric, statsClusterNameLabel)
+			clusterStats := stats.Clusters[cluster]
+			*overflows(&clusterStats) = uint64(metric.GetCounter().GetValue())
+			stats.Clusters[cluster] = clusterStats
+		}
+	}
+	for _, metric := range families[statsRateLimited].GetMetric() {
+		stats.RateLimitedConnections[getLabelValue(metric, statsRateLimitPrefixLabel)] = uint64(metric.GetCounter().GetValue())
+	}
+	if !clusterStatus {
+		return stats, nil
+	}
+
+	body, err = get(ctx, client, fmt.Sprintf("http://%s%s?format=json", address, clustersPath))
+	if err != nil {
+		return Stats{}, err
+	}
+
+	clusters := &envoyAdmin.Clusters{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, clusters); err != nil {
+		return Stats{}, fmt.Errorf("failed to parse clusters: %w", err)
+	}
+	for _, clusterStatus := range clusters.GetClusterStatuses() {
+		clusterStats := stats.Clusters[clusterStatus.GetName()]
+		for _, host := range clusterStatus.GetHostStatuses() {
+			for len(clusterStats.Priorities) <= int(host.GetPriority()) {
+				clusterStats.Priorities = append(clusterStats.Priorities, PriorityStats{})
+			}
+			clusterStats.Priorities[host.GetPriority()].Endpoints++
+			if isHealthy(host.GetHealthStatus()) {
+				clusterStats.Priorities[host.GetPriority()].HealthyEndpoints++
+			}
+		}
+		stats.Clusters[clusterStatus.GetName()] = clusterStats
+	}
+	return stats, nil
+}
+
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// isHealthy returns true if Envoy considers the endpoint healthy for load balancing.
+func isHealthy(status *envoyAdmin.HostHealthStatus) bool {
+	if status.GetFailedActiveHealthCheck() || status.GetFailedOutlierCheck() || status.GetExcludedViaImmediateHcFail() {
+		return false
+	}
+	edsHealthStatus := status.GetEdsHealthStatus()
+	return edsHealthStatus == envoyCore.HealthStatus_UNKNOWN || edsHealthStatus == envoyCore.HealthStatus_HEALTHY
+}
+
+func getLabelValue(metric *dto.Metric, name string) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testStats = `# TYPE envoy_cluster_outlier_detection_ejections_active gauge
+envoy_cluster_outlier_detection_ejections_active{envoy_cluster_name="a"} 2
+# TYPE envoy_cluster_upstream_cx_overflow counter
+envoy_cluster_upstream_cx_overflow{envoy_cluster_name="a"} 5
+envoy_cluster_upstream_cx_overflow{envoy_cluster_name="b"} 1
+# TYPE envoy_cluster_upstream_rq_pending_overflow counter
+envoy_cluster_upstream_rq_pending_overflow{envoy_cluster_name="a"} 3
+# TYPE envoy_cluster_upstream_rq_retry_overflow counter
+envoy_cluster_upstream_rq_retry_overflow{envoy_cluster_name="b"} 4
+# TYPE envoy_local_rate_limit_rate_limited counter
+envoy_local_rate_limit_rate_limited{envoy_local_network_ratelimit_prefix="ratelimit"} 7
+`
+
+func TestScrapeStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != statsPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(testStats))
+	}))
+	defer server.Close()
+
+	stats, err := ScrapeStats(context.Background(), server.Client(), strings.TrimPrefix(server.URL, "http://"), false)
+	if err != nil {
+		t.Fatalf("failed to scrape statistics: %v", err)
+	}
+
+	expected := Stats{
+		Clusters: map[string]ClusterStats{
+			"a": {EjectedEndpoints: 2, ConnectionOverflows: 5, PendingRequestOverflows: 3},
+			"b": {ConnectionOverflows: 1, RetryOverflows: 4},
+		},
+		RateLimitedConnections: map[string]uint64{"ratelimit": 7},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected statistics %+v, got %+v", expected, stats)
+	}
+
+	// The overflows of the replicas are summed up, while the ejected endpoints are shared.
+	stats.Add(stats)
+	merged := stats.Clusters["a"]
+	if merged.EjectedEndpoints != 2 || merged.ConnectionOverflows != 10 || merged.PendingRequestOverflows != 6 {
+		t.Errorf("expected the overflows of the replicas to be summed up, got %+v", merged)
+	}
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	envoyCluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoyListener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoyProxyProtocolTransport "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/proxy_protocol/v3"
+	envoyTLS "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	envoyMatcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// secretNamePattern is the name of a secret in Envoy, it's built from the namespace, kind and name of the referenced resource along
+	// with the usage of the secret.
+	secretNamePattern = "%s-%s-%s-%s"
+
+	secretUsageCertificate = "certificate"
+	secretUsageValidation  = "validation"
+
+	caCertificateKey = "ca.crt"
+)
+
+// tlsSecrets are the secrets that are served to Envoy over SDS keyed by their name. Secrets that are referenced multiple times are only
+// loaded once.
+type tlsSecrets map[string]types.Resource
+
+// resources returns the secrets sorted by name, so that the version of the snapshot is stable.
+func (s tlsSecrets) resources() []types.Resource {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resources := make([]types.Resource, 0, len(names))
+	for _, name := range names {
+		resources = append(resources, s[name])
+	}
+	return resources
+}
+
+// addCertificate loads the certificate chain and the private key of a Secret or SyncSecret and returns the name of the secret in Envoy.
+func (s tlsSecrets) addCertificate(ctx context.Context, client ctrlclient.Client, namespace string, ref kubelbv1alpha1.SecretReference) (string, error) {
+	name := makeSecretName(namespace, ref, secretUsageCertificate)
+	if _, ok := s[name]; ok {
+		return name, nil
+	}
+
+	data, err := getSecretData(ctx, client, namespace, ref)
+	if err != nil {
+		return "", err
+	}
+	certificateChain, privateKey := data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]
+	if len(certificateChain) == 0 || len(privateKey) == 0 {
+		return "", fmt.Errorf("%s %s/%s must contain the %s and %s keys", ref.Kind, namespace, ref.Name, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+
+	s[name] = &envoyTLS.Secret{
+		Name: name,
+		Type: &envoyTLS.Secret_TlsCertificate{
+			TlsCertificate: &envoyTLS.TlsCertificate{
+				CertificateChain: makeInlineDataSource(certificateChain),
+				PrivateKey:       makeInlineDataSource(privateKey),
+			},
+		},
+	}
+	return name, nil
+}
+
+// addValidationContext loads the CA bundle of a Secret or SyncSecret and returns the name of the secret in Envoy.
+func (s tlsSecrets) addValidationContext(ctx context.Context, client ctrlclient.Client, namespace string, ref kubelbv1alpha1.SecretReference) (string, error) {
+	name := makeSecretName(namespace, ref, secretUsageValidation)
+	if _, ok := s[name]; ok {
+		return name, nil
+	}
+
+	data, err := getSecretData(ctx, client, namespace, ref)
+	if err != nil {
+		return "", err
+	}
+	caCertificate := data[caCertificateKey]
+	if len(caCertificate) == 0 {
+		return "", fmt.Errorf("%s %s/%s must contain the %s key", ref.Kind, namespace, ref.Name, caCertificateKey)
+	}
+
+	s[name] = &envoyTLS.Secret{
+		Name: name,
+		Type: &envoyTLS.Secret_ValidationContext{
+			ValidationContext: &envoyTLS.CertificateValidationContext{
+				TrustedCa: makeInlineDataSource(caCertificate),
+			},
+		},
+	}
+	return name, nil
+}
+
+// terminateTLS loads the certificates of a listener and terminates TLS on all the filter chains of the listener.
+func (s tlsSecrets) terminateTLS(ctx context.Context, client ctrlclient.Client, namespace string, listener *envoyListener.Listener, listenerTLS *kubelbv1alpha1.ListenerTLS) error {
+	certificateSecret, err := s.addCertificate(ctx, client, namespace, listenerTLS.CertificateRef)
+	if err != nil {
+		return err
+	}
+
+	tlsContext := &envoyTLS.DownstreamTlsContext{
+		CommonTlsContext: &envoyTLS.CommonTlsContext{
+			TlsCertificateSdsSecretConfigs: []*envoyTLS.SdsSecretConfig{makeSdsSecretConfig(certificateSecret)},
+		},
+	}
+	if listenerTLS.ClientValidation != nil {
+		validationSecret, err := s.addValidationContext(ctx, client, namespace, listenerTLS.ClientValidation.CACertificateRef)
+		if err != nil {
+			return err
+		}
+		tlsContext.RequireClientCertificate = &wrappers.BoolValue{Value: true}
+		tlsContext.CommonTlsContext.ValidationContextType = &envoyTLS.CommonTlsContext_ValidationContextSdsSecretConfig{
+			ValidationContextSdsSecretConfig: makeSdsSecretConfig(validationSecret),
+		}
+	}
+
+	tlsContextAny, err := anypb.New(tlsContext)
+	if err != nil {
+		panic(err)
+	}
+	for _, filterChain := range listener.FilterChains {
+		filterChain.TransportSocket = &envoyCore.TransportSocket{
+			Name: wellknown.TransportSocketTLS,
+			ConfigType: &envoyCore.TransportSocket_TypedConfig{
+				TypedConfig: tlsContextAny,
+			},
+		}
+	}
+	return nil
+}
+
+// originateTLS loads the certificates that are referenced by the upstream TLS configuration and originates TLS from the cluster to the
+// endpoints. Objects that were created before the validation was enforced by the CRD might lack both a CA and InsecureSkipVerify, they
+// are rejected instead of silently skipping the verification.
+func (s tlsSecrets) originateTLS(ctx context.Context, client ctrlclient.Client, namespace string, cluster *envoyCluster.Cluster, upstreamTLS *kubelbv1alpha1.UpstreamTLS) error {
+	if upstreamTLS.CACertificateRef == nil && !upstreamTLS.InsecureSkipVerify {
+		return fmt.Errorf("upstream TLS requires a CA certificate unless insecureSkipVerify is set")
+	}
+
+	tlsContext := &envoyTLS.UpstreamTlsContext{
+		CommonTlsContext: &envoyTLS.CommonTlsContext{},
+		Sni:              upstreamTLS.SNI,
+	}
+
+	if upstreamTLS.ClientCertificateRef != nil {
+		certificateSecret, err := s.addCertificate(ctx, client, namespace, *upstreamTLS.ClientCertificateRef)
+		if err != nil {
+			return err
+		}
+		tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs = []*envoyTLS.SdsSecretConfig{makeSdsSecretConfig(certificateSecret)}
+	}
+
+	if upstreamTLS.CACertificateRef != nil {
+		validationSecret, err := s.addValidationContext(ctx, client, namespace, *upstreamTLS.CACertificateRef)
+		if err != nil {
+			return err
+		}
+		// The subject alt names are part of the configuration of the cluster, they are merged with the CA bundle served over SDS.
+		defaultValidationContext := &envoyTLS.CertificateValidationContext{}
+		for _, name := range upstreamTLS.SubjectAltNames {
+			defaultValidationContext.MatchTypedSubjectAltNames = append(defaultValidationContext.MatchTypedSubjectAltNames, &envoyTLS.SubjectAltNameMatcher{
+				SanType: envoyTLS.SubjectAltNameMatcher_DNS,
+				Matcher: &envoyMatcher.StringMatcher{
+					MatchPattern: &envoyMatcher.StringMatcher_Exact{Exact: name},
+				},
+			})
+		}
+		tlsContext.CommonTlsContext.ValidationContextType = &envoyTLS.CommonTlsContext_CombinedValidationContext{
+			CombinedValidationContext: &envoyTLS.CommonTlsContext_CombinedCertificateValidationContext{
+				DefaultValidationContext:         defaultValidationContext,
+				ValidationContextSdsSecretConfig: makeSdsSecretConfig(validationSecret),
+			},
+		}
+	}
+
+	tlsContextAny, err := anypb.New(tlsContext)
+	if err != nil {
+		panic(err)
+	}
+	cluster.TransportSocket = &envoyCore.TransportSocket{
+		Name: wellknown.TransportSocketTLS,
+		ConfigType: &envoyCore.TransportSocket_TypedConfig{
+			TypedConfig: tlsContextAny,
+		},
+	}
+	return nil
+}
+
+// getListenerTLS returns the TLS termination configuration for a port of the LoadBalancer.
+func getListenerTLS(lb *kubelbv1alpha1.LoadBalancer, portIndex int) *kubelbv1alpha1.ListenerTLS {
+	if portIndex < len(lb.Spec.Ports) {
+		return lb.Spec.Ports[portIndex].TLS
+	}
+	return nil
+}
+
+// getSecretData returns the data of a Secret or a SyncSecret. The data of a SyncSecret is used directly, since the corresponding Secret
+// might not have been created yet.
+func getSecretData(ctx context.Context, client ctrlclient.Client, namespace string, ref kubelbv1alpha1.SecretReference) (map[string][]byte, error) {
+	key := ctrlclient.ObjectKey{Namespace: namespace, Name: ref.Name}
+	if ref.Kind == kubelbv1alpha1.SecretReferenceKindSyncSecret {
+		syncSecret := &kubelbv1alpha1.SyncSecret{}
+		if err := client.Get(ctx, key, syncSecret); err != nil {
+			return nil, fmt.Errorf("failed to get SyncSecret: %w", err)
+		}
+		data := make(map[string][]byte, len(syncSecret.Data)+len(syncSecret.StringData))
+		for k, v := range syncSecret.Data {
+			data[k] = v
+		}
+		for k, v := range syncSecret.StringData {
+			data[k] = []byte(v)
+		}
+		return data, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := client.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("failed to get Secret: %w", err)
+	}
+	return secret.Data, nil
+}
+
+// getSecretNames returns the names of the secrets that a cluster or a listener references over SDS.
+func getSecretNames(res types.Resource) ([]string, error) {
+	var transportSockets []*envoyCore.TransportSocket
+	switch r := res.(type) {
+	case *envoyCluster.Cluster:
+		transportSockets = append(transportSockets, r.GetTransportSocket())
+	case *envoyListener.Listener:
+		for _, filterChain := range r.GetFilterChains() {
+			transportSockets = append(transportSockets, filterChain.GetTransportSocket())
+		}
+	}
+
+	var names []string
+	for _, transportSocket := range transportSockets {
+		transportSocketNames, err := getTransportSocketSecretNames(transportSocket)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, transportSocketNames...)
+	}
+	return names, nil
+}
+
+// getTransportSocketSecretNames returns the names of the secrets that a transport socket references, the TLS transport socket can be
+// wrapped by the upstream PROXY protocol transport socket.
+func getTransportSocketSecretNames(transportSocket *envoyCore.TransportSocket) ([]string, error) {
+	config := transportSocket.GetTypedConfig()
+	var commonTLSContext *envoyTLS.CommonTlsContext
+	switch {
+	case config == nil:
+		return nil, nil
+	case config.MessageIs(&envoyProxyProtocolTransport.ProxyProtocolUpstreamTransport{}):
+		upstreamTransport := &envoyProxyProtocolTransport.ProxyProtocolUpstreamTransport{}
+		if err := config.UnmarshalTo(upstreamTransport); err != nil {
+			return nil, err
+		}
+		return getTransportSocketSecretNames(upstreamTransport.GetTransportSocket())
+	case config.MessageIs(&envoyTLS.UpstreamTlsContext{}):
+		tlsContext := &envoyTLS.UpstreamTlsContext{}
+		if err := config.UnmarshalTo(tlsContext); err != nil {
+			return nil, err
+		}
+		commonTLSContext = tlsContext.GetCommonTlsContext()
+	case config.MessageIs(&envoyTLS.DownstreamTlsContext{}):
+		tlsContext := &envoyTLS.DownstreamTlsContext{}
+		if err := config.UnmarshalTo(tlsContext); err != nil {
+			return nil, err
+		}
+		commonTLSContext = tlsContext.GetCommonTlsContext()
+	default:
+		return nil, nil
+	}
+
+	var names []string
+	for _, sdsSecretConfig := range commonTLSContext.GetTlsCertificateSdsSecretConfigs() {
+		names = append(names, sdsSecretConfig.GetName())
+	}
+	if sdsSecretConfig := commonTLSContext.GetValidationContextSdsSecretConfig(); sdsSecretConfig != nil {
+		names = append(names, sdsSecretConfig.GetName())
+	}
+	if sdsSecretConfig := commonTLSContext.GetCombinedValidationContext().GetValidationContextSdsSecretConfig(); sdsSecretConfig != nil {
+		names = append(names, sdsSecretConfig.GetName())
+	}
+	return names, nil
+}
+
+func makeSecretName(namespace string, ref kubelbv1alpha1.SecretReference, usage string) string {
+	kind := ref.Kind
+	if kind == "" {
+		kind = kubelbv1alpha1.SecretReferenceKindSecret
+	}
+	return fmt.Sprintf(secretNamePattern, namespace, strings.ToLower(string(kind)), ref.Name, usage)
+}
+
+func makeSdsSecretConfig(name string) *envoyTLS.SdsSecretConfig {
+	return &envoyTLS.SdsSecretConfig{
+		Name:      name,
+		SdsConfig: makeSelfConfigSource(),
+	}
+}
+
+func makeInlineDataSource(data []byte) *envoyCore.DataSource {
+	return &envoyCore.DataSource{
+		Specifier: &envoyCore.DataSource_InlineBytes{
+			InlineBytes: data,
+		},
+	}
+}
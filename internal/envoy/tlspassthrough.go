@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	envoyCore "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoyListener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoyTLSInspector "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/tls_inspector/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+	"k8c.io/kubelb/internal/kubelb"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const tlsTransportProtocol = "tls"
+
+// makeTLSPassthroughListeners generates a listener for each shared listener port. The TLS inspector extracts the server name from the TLS
+// client hello, which selects the filter chain that proxies the connection to the LoadBalancer that claimed the hostname. Connections
+// without a matching server name are closed. The PROXY protocol header precedes the client hello, so it's accepted for all filter chains
+// of a listener or none; the first LoadBalancer of a port decides. The LoadBalancers whose filter chains can't be generated are returned
+// as skipped.
+func makeTLSPassthroughListeners(ctx context.Context, claims kubelb.TLSPassthroughClaims, tcpListenerPorts map[uint32]bool, ipFamilies []corev1.IPFamily,
+	settings SnapshotSettings) ([]types.Resource, []SkippedObject) {
+	log := ctrl.LoggerFrom(ctx)
+
+	ports := make([]int32, 0, len(claims.Routes))
+	for port := range claims.Routes {
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+
+	var listeners []types.Resource
+	var skipped []SkippedObject
+	for _, port := range ports {
+		if tcpListenerPorts[uint32(port)] {
+			err := fmt.Errorf("TLS passthrough port %d is already used by another listener", port)
+			log.Error(err, "skipping TLS passthrough listener")
+			for _, route := range claims.Routes[port] {
+				skipped = append(skipped, SkippedObject{Object: route.LoadBalancer, Reason: kubelbv1alpha1.ReasonInvalidTLSPassthrough, Err: err})
+			}
+			continue
+		}
+
+		var filterChains []*envoyListener.FilterChain
+		var downstreamProxyProtocol bool
+		for _, route := range claims.Routes[port] {
+			lb := route.LoadBalancer
+
+			clusters := getWeightedClusters(lb, route.PortIndex)
+			if len(clusters) == 0 {
+				err := fmt.Errorf("port %d is not served by any set of endpoints", lb.Spec.Ports[route.PortIndex].Port)
+				log.Error(err, "skipping TLS passthrough route", "namespace", lb.Namespace, "name", lb.Name)
+				skipped = append(skipped, SkippedObject{Object: lb, Reason: kubelbv1alpha1.ReasonInvalidTLSPassthrough, Err: err})
+				continue
+			}
+			downstream := lb.Spec.ProxyProtocol != nil && ptr.Deref(lb.Spec.ProxyProtocol.Downstream, false)
+			if len(filterChains) > 0 && downstream != downstreamProxyProtocol {
+				err := fmt.Errorf("downstream PROXY protocol must be configured the same for all LoadBalancers sharing TLS passthrough port %d", port)
+				log.Error(err, "skipping TLS passthrough route", "namespace", lb.Namespace, "name", lb.Name)
+				skipped = append(skipped, SkippedObject{Object: lb, Reason: kubelbv1alpha1.ReasonInvalidTLSPassthrough, Err: err})
+				continue
+			}
+
+			accessLogs := makeAccessLogs(settings.getAccessLog(lb.Namespace), accessLogLabels{
+				tenant:          settings.getTenantName(lb.Namespace),
+				loadBalancer:    lb.Name,
+				originNamespace: lb.Labels[kubelb.LabelOriginNamespace],
+				originName:      lb.Labels[kubelb.LabelOriginName],
+			})
+
+			filterChain := &envoyListener.FilterChain{
+				FilterChainMatch: &envoyListener.FilterChainMatch{
+					ServerNames:       route.Hostnames,
+					TransportProtocol: tlsTransportProtocol,
+				},
+				Filters: []*envoyListener.Filter{makeTCPProxyFilter(clusters[0].name, clusters, getLoadBalancingPolicy(lb.Spec.LoadBalancingPolicy, lb.Spec.SessionAffinity), lb.Spec.TCP, accessLogs)},
+			}
+			if rateLimit := getConnectionRateLimit(lb, route.PortIndex); rateLimit != nil {
+				limitConnectionRate(filterChain, fmt.Sprintf(kubelb.EnvoyConnectionRateLimitPattern, lb.Namespace, lb.Name, route.PortIndex), rateLimit)
+			}
+			sourceRanges, err := makeSourceRanges(lb.Spec.SourceRanges, settings.getDeniedSourceRanges(lb.Namespace))
+			if err != nil {
+				log.Error(err, "failed to restrict source ranges, skipping TLS passthrough route", "namespace", lb.Namespace, "name", lb.Name)
+				skipped = append(skipped, SkippedObject{Object: lb, Reason: kubelbv1alpha1.ReasonInvalidSourceRanges, Err: err})
+				continue
+			}
+			restrictSourceRanges(filterChain, clusters[0].name, sourceRanges)
+			downstreamProxyProtocol = downstream
+			filterChains = append(filterChains, filterChain)
+		}
+		if len(filterChains) == 0 {
+			continue
+		}
+
+		tlsInspector, err := anypb.New(&envoyTLSInspector.TlsInspector{})
+		if err != nil {
+			panic(err)
+		}
+
+		listener := &envoyListener.Listener{
+			Name:         fmt.Sprintf(kubelb.EnvoyTLSPassthroughListenerPattern, port),
+			Address:      makeListenerAddress(envoyCore.SocketAddress_TCP, uint32(port), ipFamilies),
+			FilterChains: filterChains,
+		}
+		// The PROXY protocol header must be consumed before the TLS inspector reads the client hello.
+		if downstreamProxyProtocol {
+			acceptProxyProtocol(listener)
+		}
+		listener.ListenerFilters = append(listener.ListenerFilters, &envoyListener.ListenerFilter{
+			Name: wellknown.TLSInspector,
+			ConfigType: &envoyListener.ListenerFilter_TypedConfig{
+				TypedConfig: tlsInspector,
+			},
+		})
+		listeners = append(listeners, listener)
+	}
+	return listeners, skipped
+}
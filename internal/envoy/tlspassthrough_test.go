@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envoy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	envoyListener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+	"k8c.io/kubelb/internal/kubelb"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func newTLSPassthroughLoadBalancer(name string, endpointPorts ...kubelbv1alpha1.EndpointPort) *kubelbv1alpha1.LoadBalancer {
+	return &kubelbv1alpha1.LoadBalancer{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tenant-test"},
+		Spec: kubelbv1alpha1.LoadBalancerSpec{
+			Ports: []kubelbv1alpha1.LoadBalancerPort{{
+				Port:           443,
+				Protocol:       corev1.ProtocolTCP,
+				TLSPassthrough: &kubelbv1alpha1.TLSPassthrough{Hostnames: []string{name + ".example.com"}},
+			}},
+			Endpoints: []kubelbv1alpha1.LoadBalancerEndpoints{{
+				Addresses: []kubelbv1alpha1.EndpointAddress{{IP: "10.0.0.1"}},
+				Ports:     endpointPorts,
+			}},
+		},
+	}
+}
+
+func TestMakeTLSPassthroughListeners(t *testing.T) {
+	valid := newTLSPassthroughLoadBalancer("valid", kubelbv1alpha1.EndpointPort{Port: 30443, Protocol: corev1.ProtocolTCP})
+	withoutEndpoints := newTLSPassthroughLoadBalancer("without-endpoints")
+	withProxyProtocol := newTLSPassthroughLoadBalancer("with-proxy-protocol", kubelbv1alpha1.EndpointPort{Port: 30443, Protocol: corev1.ProtocolTCP})
+	withProxyProtocol.Spec.ProxyProtocol = &kubelbv1alpha1.ProxyProtocol{Downstream: ptr.To(true)}
+
+	testCases := []struct {
+		name             string
+		routes           []kubelb.TLSPassthroughRoute
+		tcpListenerPorts map[uint32]bool
+		filterChains     int
+		listenerFilters  []string
+		skipped          []string
+	}{
+		{
+			name:            "routes are served",
+			routes:          []kubelb.TLSPassthroughRoute{{LoadBalancer: valid, Hostnames: []string{"valid.example.com"}}},
+			filterChains:    1,
+			listenerFilters: []string{wellknown.TLSInspector},
+		},
+		{
+			name: "port without endpoints is skipped",
+			routes: []kubelb.TLSPassthroughRoute{
+				{LoadBalancer: valid, Hostnames: []string{"valid.example.com"}},
+				{LoadBalancer: withoutEndpoints, Hostnames: []string{"without-endpoints.example.com"}},
+			},
+			filterChains:    1,
+			listenerFilters: []string{wellknown.TLSInspector},
+			skipped:         []string{"without-endpoints"},
+		},
+		{
+			name:            "downstream PROXY protocol is accepted before the TLS inspector",
+			routes:          []kubelb.TLSPassthroughRoute{{LoadBalancer: withProxyProtocol, Hostnames: []string{"with-proxy-protocol.example.com"}}},
+			filterChains:    1,
+			listenerFilters: []string{wellknown.ProxyProtocol, wellknown.TLSInspector},
+		},
+		{
+			name: "port with mixed downstream PROXY protocol settings is skipped",
+			routes: []kubelb.TLSPassthroughRoute{
+				{LoadBalancer: withProxyProtocol, Hostnames: []string{"with-proxy-protocol.example.com"}},
+				{LoadBalancer: valid, Hostnames: []string{"valid.example.com"}},
+			},
+			filterChains:    1,
+			listenerFilters: []string{wellknown.ProxyProtocol, wellknown.TLSInspector},
+			skipped:         []string{"valid"},
+		},
+		{
+			name:             "port that is used by another listener is skipped",
+			routes:           []kubelb.TLSPassthroughRoute{{LoadBalancer: valid, Hostnames: []string{"valid.example.com"}}},
+			tcpListenerPorts: map[uint32]bool{443: true},
+			skipped:          []string{"valid"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := kubelb.TLSPassthroughClaims{Routes: map[int32][]kubelb.TLSPassthroughRoute{443: tc.routes}}
+			listeners, skipped := makeTLSPassthroughListeners(context.Background(), claims, tc.tcpListenerPorts, nil, SnapshotSettings{})
+
+			filterChains := 0
+			var listenerFilters []string
+			for _, listener := range listeners {
+				filterChains += len(listener.(*envoyListener.Listener).GetFilterChains())
+				for _, filter := range listener.(*envoyListener.Listener).GetListenerFilters() {
+					listenerFilters = append(listenerFilters, filter.GetName())
+				}
+			}
+			if filterChains != tc.filterChains {
+				t.Errorf("expected %d filter chains, got %d", tc.filterChains, filterChains)
+			}
+			if !reflect.DeepEqual(listenerFilters, tc.listenerFilters) {
+				t.Errorf("expected listener filters %v, got %v", tc.listenerFilters, listenerFilters)
+			}
+			if len(skipped) != len(tc.skipped) {
+				t.Fatalf("expected %d skipped LoadBalancers, got %v", len(tc.skipped), skipped)
+			}
+			for i, s := range skipped {
+				if s.Object.GetName() != tc.skipped[i] || s.Reason != kubelbv1alpha1.ReasonInvalidTLSPassthrough {
+					t.Errorf("expected LoadBalancer %q to be skipped for %s, got %q for %s", tc.skipped[i], kubelbv1alpha1.ReasonInvalidTLSPassthrough, s.Object.GetName(), s.Reason)
+				}
+			}
+		})
+	}
+}
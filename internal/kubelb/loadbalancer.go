@@ -17,7 +17,11 @@ limitations under the License.
 package kubelb
 
 import (
+	"fmt"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
 
 	kubelbiov1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
 
@@ -25,18 +29,58 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func MapLoadBalancer(userService *corev1.Service, clusterEndpoints []string, useAddressesReference bool, clusterName string) *kubelbiov1alpha1.LoadBalancer {
+var loadBalancingPolicyTypes = []kubelbiov1alpha1.LoadBalancingPolicyType{
+	kubelbiov1alpha1.LoadBalancingPolicyRoundRobin,
+	kubelbiov1alpha1.LoadBalancingPolicyLeastRequest,
+	kubelbiov1alpha1.LoadBalancingPolicyRandom,
+	kubelbiov1alpha1.LoadBalancingPolicyRingHash,
+	kubelbiov1alpha1.LoadBalancingPolicyMaglev,
+}
+
+var proxyProtocolVersions = []kubelbiov1alpha1.ProxyProtocolVersion{
+	kubelbiov1alpha1.ProxyProtocolVersionV1,
+	kubelbiov1alpha1.ProxyProtocolVersionV2,
+}
+
+// ValidateLoadBalancerAnnotations returns an error for each annotation of the Service that has an invalid value. These annotations are
+// ignored by MapLoadBalancer.
+func ValidateLoadBalancerAnnotations(userService *corev1.Service) []error {
+	var errs []error
+	if value, ok := userService.Annotations[kubelbiov1alpha1.LoadBalancingPolicyAnnotation]; ok && value != "" && !slices.Contains(loadBalancingPolicyTypes, kubelbiov1alpha1.LoadBalancingPolicyType(value)) {
+		errs = append(errs, fmt.Errorf("invalid value %q for annotation %s, valid values are %v", value, kubelbiov1alpha1.LoadBalancingPolicyAnnotation, loadBalancingPolicyTypes))
+	}
+	if value, ok := userService.Annotations[kubelbiov1alpha1.ProxyProtocolAnnotation]; ok && value != "" && !slices.Contains(proxyProtocolVersions, kubelbiov1alpha1.ProxyProtocolVersion(value)) {
+		errs = append(errs, fmt.Errorf("invalid value %q for annotation %s, valid values are %v", value, kubelbiov1alpha1.ProxyProtocolAnnotation, proxyProtocolVersions))
+	}
+	return errs
+}
+
+func MapLoadBalancer(userService *corev1.Service, clusterEndpoints []kubelbiov1alpha1.EndpointAddress, useAddressesReference bool, clusterName string) *kubelbiov1alpha1.LoadBalancer {
 	var lbServicePorts []kubelbiov1alpha1.LoadBalancerPort
 	var lbEndpointSubsets []kubelbiov1alpha1.LoadBalancerEndpoints
 	var lbEndpointPorts []kubelbiov1alpha1.EndpointPort
 
+	var tlsPassthrough *kubelbiov1alpha1.TLSPassthrough
+	if value, ok := userService.Annotations[kubelbiov1alpha1.TLSPassthroughHostnamesAnnotation]; ok && value != "" {
+		tlsPassthrough = &kubelbiov1alpha1.TLSPassthrough{}
+		for _, hostname := range strings.Split(value, ",") {
+			if hostname = strings.TrimSpace(hostname); hostname != "" {
+				tlsPassthrough.Hostnames = append(tlsPassthrough.Hostnames, hostname)
+			}
+		}
+	}
+
 	// mapping into load balancing service and endpoint subset ports
 	for _, port := range userService.Spec.Ports {
-		lbServicePorts = append(lbServicePorts, kubelbiov1alpha1.LoadBalancerPort{
+		lbServicePort := kubelbiov1alpha1.LoadBalancerPort{
 			Name:     port.Name,
 			Port:     port.Port,
 			Protocol: port.Protocol,
-		})
+		}
+		if tlsPassthrough != nil && len(tlsPassthrough.Hostnames) > 0 && port.Protocol == corev1.ProtocolTCP {
+			lbServicePort.TLSPassthrough = tlsPassthrough.DeepCopy()
+		}
+		lbServicePorts = append(lbServicePorts, lbServicePort)
 
 		lbEndpointPorts = append(lbEndpointPorts, kubelbiov1alpha1.EndpointPort{
 			Name:     port.Name,
@@ -54,17 +98,36 @@ func MapLoadBalancer(userService *corev1.Service, clusterEndpoints []string, use
 			Name: kubelbiov1alpha1.DefaultAddressName,
 		}
 	} else {
-		var endpointAddresses []kubelbiov1alpha1.EndpointAddress
-		for _, endpoint := range clusterEndpoints {
-			endpointAddresses = append(endpointAddresses, kubelbiov1alpha1.EndpointAddress{
-				IP: endpoint,
-			})
-		}
-		lbEndpoints.Addresses = endpointAddresses
+		lbEndpoints.Addresses = clusterEndpoints
 	}
 
 	lbEndpointSubsets = append(lbEndpointSubsets, lbEndpoints)
 
+	// The annotation is only used if the field is not specified, same as for the cloud providers.
+	sourceRanges := userService.Spec.LoadBalancerSourceRanges
+	if value, ok := userService.Annotations[corev1.AnnotationLoadBalancerSourceRangesKey]; ok && len(sourceRanges) == 0 {
+		for _, sourceRange := range strings.Split(value, ",") {
+			if sourceRange = strings.TrimSpace(sourceRange); sourceRange != "" {
+				sourceRanges = append(sourceRanges, sourceRange)
+			}
+		}
+	}
+
+	// Invalid values are ignored, they would be rejected by the API server. They are reported by ValidateLoadBalancerAnnotations.
+	var loadBalancingPolicy *kubelbiov1alpha1.LoadBalancingPolicy
+	if value, ok := userService.Annotations[kubelbiov1alpha1.LoadBalancingPolicyAnnotation]; ok && slices.Contains(loadBalancingPolicyTypes, kubelbiov1alpha1.LoadBalancingPolicyType(value)) {
+		loadBalancingPolicy = &kubelbiov1alpha1.LoadBalancingPolicy{
+			Type: kubelbiov1alpha1.LoadBalancingPolicyType(value),
+		}
+	}
+
+	var proxyProtocol *kubelbiov1alpha1.ProxyProtocol
+	if value, ok := userService.Annotations[kubelbiov1alpha1.ProxyProtocolAnnotation]; ok && slices.Contains(proxyProtocolVersions, kubelbiov1alpha1.ProxyProtocolVersion(value)) {
+		proxyProtocol = &kubelbiov1alpha1.ProxyProtocol{
+			Upstream: kubelbiov1alpha1.ProxyProtocolVersion(value),
+		}
+	}
+
 	return &kubelbiov1alpha1.LoadBalancer{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      string(userService.UID),
@@ -77,25 +140,109 @@ func MapLoadBalancer(userService *corev1.Service, clusterEndpoints []string, use
 			Annotations: userService.Annotations,
 		},
 		Spec: kubelbiov1alpha1.LoadBalancerSpec{
-			Ports:     lbServicePorts,
-			Endpoints: lbEndpointSubsets,
-			Type:      userService.Spec.Type,
+			Ports:               lbServicePorts,
+			Endpoints:           lbEndpointSubsets,
+			Type:                userService.Spec.Type,
+			LoadBalancingPolicy: loadBalancingPolicy,
+			HealthCheckNodePort: userService.Spec.HealthCheckNodePort,
+			SourceRanges:        sourceRanges,
+			SessionAffinity:     GetSessionAffinity(userService),
+			ProxyProtocol:       proxyProtocol,
+			IPFamilyPolicy:      userService.Spec.IPFamilyPolicy,
 		},
 	}
 }
 
+// PreserveLoadBalancerSettings copies the settings that are not generated by the CCM from the existing LoadBalancer into the desired one,
+// they are managed in the LB cluster. Ports are matched by their port and protocol, sets of endpoints by their index. Sets of endpoints
+// that were added in the LB cluster are kept as they are.
+func PreserveLoadBalancerSettings(existing, desired *kubelbiov1alpha1.LoadBalancer) {
+	desired.Spec.HealthCheck = existing.Spec.HealthCheck
+	desired.Spec.CircuitBreakers = existing.Spec.CircuitBreakers
+	desired.Spec.OutlierDetection = existing.Spec.OutlierDetection
+	desired.Spec.TCP = existing.Spec.TCP
+	desired.Spec.UpstreamTLS = existing.Spec.UpstreamTLS
+
+	// The type of the load balancing policy is taken from the annotation of the Service if it's set, the parameters of the policy are
+	// managed in the LB cluster.
+	if existing.Spec.LoadBalancingPolicy != nil {
+		policyType := existing.Spec.LoadBalancingPolicy.Type
+		if desired.Spec.LoadBalancingPolicy != nil {
+			policyType = desired.Spec.LoadBalancingPolicy.Type
+		}
+		desired.Spec.LoadBalancingPolicy = existing.Spec.LoadBalancingPolicy.DeepCopy()
+		desired.Spec.LoadBalancingPolicy.Type = policyType
+	}
+
+	if existing.Spec.ProxyProtocol != nil && existing.Spec.ProxyProtocol.Downstream != nil {
+		if desired.Spec.ProxyProtocol == nil {
+			desired.Spec.ProxyProtocol = &kubelbiov1alpha1.ProxyProtocol{}
+		}
+		desired.Spec.ProxyProtocol.Downstream = existing.Spec.ProxyProtocol.Downstream
+	}
+
+	for i := range desired.Spec.Ports {
+		for _, existingPort := range existing.Spec.Ports {
+			if existingPort.Port != desired.Spec.Ports[i].Port || existingPort.Protocol != desired.Spec.Ports[i].Protocol {
+				continue
+			}
+			desired.Spec.Ports[i].HealthCheck = existingPort.HealthCheck
+			desired.Spec.Ports[i].TLS = existingPort.TLS
+			desired.Spec.Ports[i].ConnectionRateLimit = existingPort.ConnectionRateLimit
+			desired.Spec.Ports[i].UDP = existingPort.UDP
+			break
+		}
+	}
+
+	for i := range existing.Spec.Endpoints {
+		if i >= len(desired.Spec.Endpoints) {
+			desired.Spec.Endpoints = append(desired.Spec.Endpoints, existing.Spec.Endpoints[i])
+			continue
+		}
+		desired.Spec.Endpoints[i].Weight = existing.Spec.Endpoints[i].Weight
+		desired.Spec.Endpoints[i].Priority = existing.Spec.Endpoints[i].Priority
+		desired.Spec.Endpoints[i].OverprovisioningFactor = existing.Spec.Endpoints[i].OverprovisioningFactor
+		desired.Spec.Endpoints[i].DNS = existing.Spec.Endpoints[i].DNS
+	}
+}
+
 func LoadBalancerIsDesiredState(actual, desired *kubelbiov1alpha1.LoadBalancer) bool {
 	if actual.Spec.Type != desired.Spec.Type {
 		return false
 	}
 
+	if !reflect.DeepEqual(actual.Spec.LoadBalancingPolicy, desired.Spec.LoadBalancingPolicy) {
+		return false
+	}
+
+	if actual.Spec.HealthCheckNodePort != desired.Spec.HealthCheckNodePort {
+		return false
+	}
+
+	if !reflect.DeepEqual(actual.Spec.SourceRanges, desired.Spec.SourceRanges) {
+		return false
+	}
+
+	if !reflect.DeepEqual(actual.Spec.SessionAffinity, desired.Spec.SessionAffinity) {
+		return false
+	}
+
+	if !reflect.DeepEqual(actual.Spec.ProxyProtocol, desired.Spec.ProxyProtocol) {
+		return false
+	}
+
+	if !reflect.DeepEqual(actual.Spec.IPFamilyPolicy, desired.Spec.IPFamilyPolicy) {
+		return false
+	}
+
 	if len(actual.Spec.Ports) != len(desired.Spec.Ports) {
 		return false
 	}
 
 	loadBalancerPortIsDesiredState := func(actual, desired kubelbiov1alpha1.LoadBalancerPort) bool {
 		return actual.Protocol == desired.Protocol &&
-			actual.Port == desired.Port
+			actual.Port == desired.Port &&
+			reflect.DeepEqual(actual.TLSPassthrough, desired.TLSPassthrough)
 	}
 
 	for i := 0; i < len(desired.Spec.Ports); i++ {
@@ -115,7 +262,9 @@ func LoadBalancerIsDesiredState(actual, desired *kubelbiov1alpha1.LoadBalancer)
 
 	endpointAddressIsDesiredState := func(actual, desired kubelbiov1alpha1.EndpointAddress) bool {
 		return actual.Hostname == desired.Hostname &&
-			actual.IP == desired.IP
+			actual.IP == desired.IP &&
+			actual.Zone == desired.Zone &&
+			actual.Region == desired.Region
 	}
 
 	for i := 0; i < len(desired.Spec.Endpoints); i++ {
@@ -141,3 +290,94 @@ func LoadBalancerIsDesiredState(actual, desired *kubelbiov1alpha1.LoadBalancer)
 
 	return reflect.DeepEqual(actual.Annotations, desired.Annotations)
 }
+
+// GetSessionAffinity returns the session affinity of a Service. Nil is returned if the Service doesn't use session affinity.
+func GetSessionAffinity(service *corev1.Service) *kubelbiov1alpha1.SessionAffinity {
+	if service.Spec.SessionAffinity != corev1.ServiceAffinityClientIP {
+		return nil
+	}
+
+	sessionAffinity := &kubelbiov1alpha1.SessionAffinity{
+		Type: corev1.ServiceAffinityClientIP,
+	}
+	if config := service.Spec.SessionAffinityConfig; config != nil && config.ClientIP != nil && config.ClientIP.TimeoutSeconds != nil {
+		timeoutSeconds := *config.ClientIP.TimeoutSeconds
+		sessionAffinity.TimeoutSeconds = &timeoutSeconds
+	}
+	return sessionAffinity
+}
+
+// GetEndpointsWeight returns the weight of a set of endpoints of the LoadBalancer.
+func GetEndpointsWeight(endpoints *kubelbiov1alpha1.LoadBalancerEndpoints) uint32 {
+	if endpoints.Weight == nil {
+		return 1
+	}
+	return *endpoints.Weight
+}
+
+// GetPriorityLevels returns the indexes of the sets of endpoints that serve a port of the LoadBalancer, grouped by their priority. The
+// groups are sorted from the highest to the lowest priority, i.e. the first group receives the traffic while its endpoints are healthy.
+func GetPriorityLevels(lb *kubelbiov1alpha1.LoadBalancer, portIndex int) [][]int {
+	var priorities []uint32
+	indexes := make(map[uint32][]int)
+	for i, endpoints := range lb.Spec.Endpoints {
+		if portIndex >= len(endpoints.Ports) {
+			continue
+		}
+		if _, ok := indexes[endpoints.Priority]; !ok {
+			priorities = append(priorities, endpoints.Priority)
+		}
+		indexes[endpoints.Priority] = append(indexes[endpoints.Priority], i)
+	}
+	sort.Slice(priorities, func(i, j int) bool { return priorities[i] < priorities[j] })
+
+	levels := make([][]int, 0, len(priorities))
+	for _, priority := range priorities {
+		levels = append(levels, indexes[priority])
+	}
+	return levels
+}
+
+// GetHealthCheck returns the health check configuration for a port of the LoadBalancer. Ports of the LoadBalancer and the endpoints are
+// mapped by their index. Configuration specified for the port has higher precedence than the one specified for the LoadBalancer.
+func GetHealthCheck(lb *kubelbiov1alpha1.LoadBalancer, portIndex int) *kubelbiov1alpha1.HealthCheck {
+	if portIndex < len(lb.Spec.Ports) && lb.Spec.Ports[portIndex].HealthCheck != nil {
+		return lb.Spec.Ports[portIndex].HealthCheck
+	}
+	return lb.Spec.HealthCheck
+}
+
+// GetHealthCheckMode returns the active health checking mode for a port along with the port that should be health checked instead of the
+// endpoint port. A port of 0 means that the endpoint port itself is health checked.
+func GetHealthCheckMode(healthCheck *kubelbiov1alpha1.HealthCheck, protocol corev1.Protocol, healthCheckNodePort int32) (kubelbiov1alpha1.HealthCheckMode, int32) {
+	if healthCheck != nil && healthCheck.Disable {
+		return kubelbiov1alpha1.HealthCheckModeDisabled, 0
+	}
+
+	if protocol == corev1.ProtocolUDP {
+		// UDP endpoints can't be probed directly, prefer the healthCheckNodePort if nothing else was configured.
+		mode := kubelbiov1alpha1.UDPHealthCheckModeNone
+		if healthCheck != nil && healthCheck.UDP != nil {
+			mode = healthCheck.UDP.Mode
+		} else if healthCheckNodePort != 0 {
+			mode = kubelbiov1alpha1.UDPHealthCheckModeHealthCheckNodePort
+		}
+
+		switch mode {
+		case kubelbiov1alpha1.UDPHealthCheckModeCompanionTCPPort:
+			if healthCheck.UDP.Port != nil {
+				return kubelbiov1alpha1.HealthCheckModeCompanionTCPPort, *healthCheck.UDP.Port
+			}
+		case kubelbiov1alpha1.UDPHealthCheckModeHealthCheckNodePort:
+			if healthCheckNodePort != 0 {
+				return kubelbiov1alpha1.HealthCheckModeHealthCheckNodePort, healthCheckNodePort
+			}
+		}
+		return kubelbiov1alpha1.HealthCheckModeDisabled, 0
+	}
+
+	if healthCheck != nil && healthCheck.HTTP != nil {
+		return kubelbiov1alpha1.HealthCheckModeHTTP, 0
+	}
+	return kubelbiov1alpha1.HealthCheckModeTCP, 0
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	kubelbiov1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestMapLoadBalancerAnnotations(t *testing.T) {
+	testCases := []struct {
+		name                string
+		annotations         map[string]string
+		loadBalancingPolicy *kubelbiov1alpha1.LoadBalancingPolicy
+		proxyProtocol       *kubelbiov1alpha1.ProxyProtocol
+		invalidAnnotations  int
+	}{
+		{
+			name: "no annotations",
+		},
+		{
+			name:                "valid load balancing policy",
+			annotations:         map[string]string{kubelbiov1alpha1.LoadBalancingPolicyAnnotation: "Maglev"},
+			loadBalancingPolicy: &kubelbiov1alpha1.LoadBalancingPolicy{Type: kubelbiov1alpha1.LoadBalancingPolicyMaglev},
+		},
+		{
+			name:        "empty load balancing policy is ignored",
+			annotations: map[string]string{kubelbiov1alpha1.LoadBalancingPolicyAnnotation: ""},
+		},
+		{
+			name:               "invalid load balancing policy is ignored and reported",
+			annotations:        map[string]string{kubelbiov1alpha1.LoadBalancingPolicyAnnotation: "maglev"},
+			invalidAnnotations: 1,
+		},
+		{
+			name:          "valid PROXY protocol version",
+			annotations:   map[string]string{kubelbiov1alpha1.ProxyProtocolAnnotation: "v2"},
+			proxyProtocol: &kubelbiov1alpha1.ProxyProtocol{Upstream: kubelbiov1alpha1.ProxyProtocolVersionV2},
+		},
+		{
+			name: "invalid annotations are ignored and reported",
+			annotations: map[string]string{
+				kubelbiov1alpha1.LoadBalancingPolicyAnnotation: "Maglev",
+				kubelbiov1alpha1.ProxyProtocolAnnotation:       "v3",
+			},
+			loadBalancingPolicy: &kubelbiov1alpha1.LoadBalancingPolicy{Type: kubelbiov1alpha1.LoadBalancingPolicyMaglev},
+			invalidAnnotations:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: tc.annotations},
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Port: 80, NodePort: 30080, Protocol: corev1.ProtocolTCP}},
+				},
+			}
+
+			lb := MapLoadBalancer(service, []kubelbiov1alpha1.EndpointAddress{{IP: "10.0.0.1"}}, false, "tenant-test")
+			if !reflect.DeepEqual(lb.Spec.LoadBalancingPolicy, tc.loadBalancingPolicy) {
+				t.Errorf("expected load balancing policy %v, got %v", tc.loadBalancingPolicy, lb.Spec.LoadBalancingPolicy)
+			}
+			if !reflect.DeepEqual(lb.Spec.ProxyProtocol, tc.proxyProtocol) {
+				t.Errorf("expected PROXY protocol %v, got %v", tc.proxyProtocol, lb.Spec.ProxyProtocol)
+			}
+			if errs := ValidateLoadBalancerAnnotations(service); len(errs) != tc.invalidAnnotations {
+				t.Errorf("expected %d invalid annotations, got %v", tc.invalidAnnotations, errs)
+			}
+		})
+	}
+}
+
+func TestPreserveLoadBalancerSettings(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{Name: "https", Port: 443, NodePort: 30443, Protocol: corev1.ProtocolTCP},
+				{Name: "dns", Port: 53, NodePort: 30053, Protocol: corev1.ProtocolUDP},
+			},
+		},
+	}
+	addresses := []kubelbiov1alpha1.EndpointAddress{{IP: "10.0.0.1"}}
+
+	// The settings that are managed in the LB cluster.
+	existing := MapLoadBalancer(service, addresses, false, "tenant-test")
+	existing.Spec.HealthCheck = &kubelbiov1alpha1.HealthCheck{HealthyThreshold: ptr.To[uint32](2)}
+	existing.Spec.CircuitBreakers = &kubelbiov1alpha1.CircuitBreakers{MaxConnections: ptr.To[uint32](100)}
+	existing.Spec.OutlierDetection = &kubelbiov1alpha1.OutlierDetection{ConsecutiveConnectionFailures: ptr.To[uint32](3)}
+	existing.Spec.TCP = &kubelbiov1alpha1.TCPSettings{IdleTimeout: &metav1.Duration{Duration: time.Minute}}
+	existing.Spec.UpstreamTLS = &kubelbiov1alpha1.UpstreamTLS{SNI: "backend.example.com"}
+	existing.Spec.ProxyProtocol = &kubelbiov1alpha1.ProxyProtocol{Downstream: ptr.To(true)}
+	existing.Spec.LoadBalancingPolicy = &kubelbiov1alpha1.LoadBalancingPolicy{
+		Type:   kubelbiov1alpha1.LoadBalancingPolicyMaglev,
+		Maglev: &kubelbiov1alpha1.MaglevConfig{TableSize: ptr.To[uint64](65537)},
+	}
+	existing.Spec.Ports[0].HealthCheck = &kubelbiov1alpha1.HealthCheck{Disable: true}
+	existing.Spec.Ports[0].TLS = &kubelbiov1alpha1.ListenerTLS{CertificateRef: kubelbiov1alpha1.SecretReference{Name: "cert"}}
+	existing.Spec.Ports[0].ConnectionRateLimit = &kubelbiov1alpha1.ConnectionRateLimit{MaxTokens: 10}
+	existing.Spec.Ports[1].UDP = &kubelbiov1alpha1.UDPSettings{IdleTimeout: &metav1.Duration{Duration: time.Second}}
+	existing.Spec.Endpoints[0].Weight = ptr.To[uint32](80)
+	existing.Spec.Endpoints[0].Priority = 1
+	existing.Spec.Endpoints[0].OverprovisioningFactor = ptr.To[uint32](200)
+	existing.Spec.Endpoints[0].DNS = &kubelbiov1alpha1.DNSSettings{Resolution: kubelbiov1alpha1.DNSResolutionLogical}
+	existing.Spec.Endpoints = append(existing.Spec.Endpoints, kubelbiov1alpha1.LoadBalancerEndpoints{
+		Addresses: []kubelbiov1alpha1.EndpointAddress{{IP: "10.0.1.1"}},
+		Ports:     existing.Spec.Endpoints[0].Ports,
+		Weight:    ptr.To[uint32](20),
+	})
+
+	// The Service is edited in the tenant cluster: a port is added, the endpoints and the annotations change.
+	service.Annotations = map[string]string{kubelbiov1alpha1.LoadBalancingPolicyAnnotation: "Random"}
+	service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{Name: "http", Port: 80, NodePort: 30080, Protocol: corev1.ProtocolTCP})
+	desired := MapLoadBalancer(service, []kubelbiov1alpha1.EndpointAddress{{IP: "10.0.0.2"}}, false, "tenant-test")
+
+	if LoadBalancerIsDesiredState(existing, desired) {
+		t.Fatal("expected the LoadBalancer to be updated")
+	}
+	PreserveLoadBalancerSettings(existing, desired)
+
+	if !reflect.DeepEqual(desired.Spec.HealthCheck, existing.Spec.HealthCheck) ||
+		!reflect.DeepEqual(desired.Spec.CircuitBreakers, existing.Spec.CircuitBreakers) ||
+		!reflect.DeepEqual(desired.Spec.OutlierDetection, existing.Spec.OutlierDetection) ||
+		!reflect.DeepEqual(desired.Spec.TCP, existing.Spec.TCP) ||
+		!reflect.DeepEqual(desired.Spec.UpstreamTLS, existing.Spec.UpstreamTLS) ||
+		!reflect.DeepEqual(desired.Spec.ProxyProtocol, existing.Spec.ProxyProtocol) {
+		t.Errorf("expected the LoadBalancer settings to be preserved, got %+v", desired.Spec)
+	}
+	if !reflect.DeepEqual(desired.Spec.Ports[:2], existing.Spec.Ports) {
+		t.Errorf("expected the port settings to be preserved, got %+v", desired.Spec.Ports)
+	}
+	if desired.Spec.Ports[2].HealthCheck != nil || desired.Spec.Ports[2].TLS != nil {
+		t.Errorf("expected the new port to have no settings, got %+v", desired.Spec.Ports[2])
+	}
+	if len(desired.Spec.Endpoints) != 2 || !reflect.DeepEqual(desired.Spec.Endpoints[1], existing.Spec.Endpoints[1]) {
+		t.Fatalf("expected the additional set of endpoints to be preserved, got %+v", desired.Spec.Endpoints)
+	}
+	endpoints := desired.Spec.Endpoints[0]
+	if !reflect.DeepEqual(endpoints.Addresses, []kubelbiov1alpha1.EndpointAddress{{IP: "10.0.0.2"}}) || len(endpoints.Ports) != 3 {
+		t.Errorf("expected the endpoints to be updated, got %+v", endpoints)
+	}
+	if *endpoints.Weight != 80 || endpoints.Priority != 1 || *endpoints.OverprovisioningFactor != 200 || endpoints.DNS == nil {
+		t.Errorf("expected the endpoint settings to be preserved, got %+v", endpoints)
+	}
+	if desired.Spec.LoadBalancingPolicy == nil || desired.Spec.LoadBalancingPolicy.Type != kubelbiov1alpha1.LoadBalancingPolicyRandom ||
+		!reflect.DeepEqual(desired.Spec.LoadBalancingPolicy.Maglev, existing.Spec.LoadBalancingPolicy.Maglev) {
+		t.Errorf("expected the type of the load balancing policy to be updated and its parameters to be preserved, got %+v", desired.Spec.LoadBalancingPolicy)
+	}
+
+	// Without the annotation, the policy of the LB cluster is kept.
+	service.Annotations = nil
+	desired = MapLoadBalancer(service, addresses, false, "tenant-test")
+	PreserveLoadBalancerSettings(existing, desired)
+	if !reflect.DeepEqual(desired.Spec.LoadBalancingPolicy, existing.Spec.LoadBalancingPolicy) {
+		t.Errorf("expected the load balancing policy to be preserved, got %+v", desired.Spec.LoadBalancingPolicy)
+	}
+}
+
+func TestGetPriorityLevels(t *testing.T) {
+	port := []kubelbiov1alpha1.EndpointPort{{Port: 30080, Protocol: corev1.ProtocolTCP}}
+
+	testCases := []struct {
+		name      string
+		endpoints []kubelbiov1alpha1.LoadBalancerEndpoints
+		portIndex int
+		expected  [][]int
+	}{
+		{
+			name:     "no endpoints",
+			expected: [][]int{},
+		},
+		{
+			name:      "single priority",
+			endpoints: []kubelbiov1alpha1.LoadBalancerEndpoints{{Ports: port}, {Ports: port}},
+			expected:  [][]int{{0, 1}},
+		},
+		{
+			name: "priorities are sorted from the highest to the lowest",
+			endpoints: []kubelbiov1alpha1.LoadBalancerEndpoints{
+				{Ports: port, Priority: 2},
+				{Ports: port},
+				{Ports: port, Priority: 2},
+			},
+			expected: [][]int{{1}, {0, 2}},
+		},
+		{
+			name: "sets of endpoints without the port are left out",
+			endpoints: []kubelbiov1alpha1.LoadBalancerEndpoints{
+				{Ports: append(port, kubelbiov1alpha1.EndpointPort{Port: 30443, Protocol: corev1.ProtocolTCP})},
+				{Ports: port},
+			},
+			portIndex: 1,
+			expected:  [][]int{{0}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lb := &kubelbiov1alpha1.LoadBalancer{Spec: kubelbiov1alpha1.LoadBalancerSpec{Endpoints: tc.endpoints}}
+			if levels := GetPriorityLevels(lb, tc.portIndex); !reflect.DeepEqual(levels, tc.expected) {
+				t.Errorf("expected priority levels %v, got %v", tc.expected, levels)
+			}
+		})
+	}
+}
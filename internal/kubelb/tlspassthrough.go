@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// TLSPassthroughRoute routes the TLS connections for a set of hostnames on a shared listener port to a port of a LoadBalancer.
+type TLSPassthroughRoute struct {
+	LoadBalancer *kubelbv1alpha1.LoadBalancer
+	// PortIndex is the index of the port of the LoadBalancer, the endpoint ports are mapped by the same index.
+	PortIndex int
+	Hostnames []string
+}
+
+// TLSPassthroughRejection is the reason why a hostname claim of a LoadBalancer was rejected.
+type TLSPassthroughRejection struct {
+	Reason  string
+	Message string
+}
+
+// TLSPassthroughClaims contains the resolved hostname claims of the LoadBalancers that use TLS passthrough.
+type TLSPassthroughClaims struct {
+	// Routes are the accepted claims grouped by the shared listener port.
+	Routes map[int32][]TLSPassthroughRoute
+	// Rejections are the rejected claims keyed by the namespace and name of the LoadBalancer.
+	Rejections map[types.NamespacedName][]TLSPassthroughRejection
+}
+
+// UsesTLSPassthrough returns true if a port of the LoadBalancer is routed through a shared listener port.
+func UsesTLSPassthrough(lb *kubelbv1alpha1.LoadBalancer, portIndex int) bool {
+	return portIndex < len(lb.Spec.Ports) && lb.Spec.Ports[portIndex].TLSPassthrough != nil
+}
+
+// ResolveTLSPassthroughClaims resolves the hostnames that the LoadBalancers claim for TLS passthrough. A hostname can only be claimed by a
+// single LoadBalancer port per listener port, the oldest LoadBalancer wins. Hostnames that are not allowed for the tenant of a LoadBalancer
+// are rejected, allowedHostnames returns the allowed hostnames for the namespace of a tenant or nil if all the hostnames are allowed.
+func ResolveTLSPassthroughClaims(lbs []kubelbv1alpha1.LoadBalancer, allowedHostnames func(namespace string) []string) TLSPassthroughClaims {
+	claims := TLSPassthroughClaims{
+		Routes:     make(map[int32][]TLSPassthroughRoute),
+		Rejections: make(map[types.NamespacedName][]TLSPassthroughRejection),
+	}
+
+	// The order must be stable, so that the same LoadBalancer wins a conflict across reconciliations.
+	sorted := make([]*kubelbv1alpha1.LoadBalancer, 0, len(lbs))
+	for i := range lbs {
+		sorted = append(sorted, &lbs[i])
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreationTimestamp.Equal(&sorted[j].CreationTimestamp) {
+			return sorted[i].CreationTimestamp.Before(&sorted[j].CreationTimestamp)
+		}
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	type owner struct {
+		lb        types.NamespacedName
+		portIndex int
+	}
+	owners := make(map[int32]map[string]owner)
+
+	for _, lb := range sorted {
+		key := types.NamespacedName{Namespace: lb.Namespace, Name: lb.Name}
+		allowed := allowedHostnames(lb.Namespace)
+		for p, port := range lb.Spec.Ports {
+			if port.TLSPassthrough == nil {
+				continue
+			}
+			if port.Protocol == corev1.ProtocolUDP {
+				claims.Rejections[key] = append(claims.Rejections[key], TLSPassthroughRejection{
+					Reason:  kubelbv1alpha1.ReasonInvalidTLSPassthrough,
+					Message: fmt.Sprintf("port %d: TLS passthrough is only supported for TCP", port.Port),
+				})
+				continue
+			}
+			if _, ok := owners[port.Port]; !ok {
+				owners[port.Port] = make(map[string]owner)
+			}
+
+			var hostnames []string
+			for _, hostname := range port.TLSPassthrough.Hostnames {
+				hostname = strings.ToLower(hostname)
+				if !HostnameIsAllowed(hostname, allowed) {
+					claims.Rejections[key] = append(claims.Rejections[key], TLSPassthroughRejection{
+						Reason:  kubelbv1alpha1.ReasonHostnameNotAllowed,
+						Message: fmt.Sprintf("port %d: hostname %q is not allowed for the tenant", port.Port, hostname),
+					})
+					continue
+				}
+				if current, ok := owners[port.Port][hostname]; ok {
+					if current.lb != key || current.portIndex != p {
+						claims.Rejections[key] = append(claims.Rejections[key], TLSPassthroughRejection{
+							Reason:  kubelbv1alpha1.ReasonHostnameConflict,
+							Message: fmt.Sprintf("port %d: hostname %q is already claimed by LoadBalancer %s", port.Port, hostname, current.lb),
+						})
+					}
+					continue
+				}
+				owners[port.Port][hostname] = owner{lb: key, portIndex: p}
+				hostnames = append(hostnames, hostname)
+			}
+
+			if len(hostnames) > 0 {
+				claims.Routes[port.Port] = append(claims.Routes[port.Port], TLSPassthroughRoute{
+					LoadBalancer: lb,
+					PortIndex:    p,
+					Hostnames:    hostnames,
+				})
+			}
+		}
+	}
+	return claims
+}
+
+// HostnameIsAllowed returns true if the hostname matches one of the allowed hostnames. A wildcard prefix such as *.example.com matches
+// all the subdomains, including wildcard hostnames for the subdomains. All the hostnames are allowed if no hostnames are specified.
+func HostnameIsAllowed(hostname string, allowedHostnames []string) bool {
+	if len(allowedHostnames) == 0 {
+		return true
+	}
+	for _, allowed := range allowedHostnames {
+		allowed = strings.ToLower(allowed)
+		if allowed == hostname {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") {
+			if name := strings.TrimPrefix(hostname, "*"); strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
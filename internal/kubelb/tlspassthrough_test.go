@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The KubeLB Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	kubelbv1alpha1 "k8c.io/kubelb/api/kubelb.k8c.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestHostnameIsAllowed(t *testing.T) {
+	testCases := []struct {
+		name     string
+		hostname string
+		allowed  []string
+		expected bool
+	}{
+		{
+			name:     "all hostnames are allowed by default",
+			hostname: "app.example.com",
+			expected: true,
+		},
+		{
+			name:     "exact match",
+			hostname: "app.example.com",
+			allowed:  []string{"App.Example.com"},
+			expected: true,
+		},
+		{
+			name:     "subdomain of a wildcard",
+			hostname: "app.example.com",
+			allowed:  []string{"*.example.com"},
+			expected: true,
+		},
+		{
+			name:     "wildcard for a subdomain of a wildcard",
+			hostname: "*.apps.example.com",
+			allowed:  []string{"*.example.com"},
+			expected: true,
+		},
+		{
+			name:     "wildcard doesn't match the domain itself",
+			hostname: "example.com",
+			allowed:  []string{"*.example.com"},
+		},
+		{
+			name:     "wildcard doesn't match a domain with the same suffix",
+			hostname: "badexample.com",
+			allowed:  []string{"*.example.com"},
+		},
+		{
+			name:     "other hostname",
+			hostname: "app.example.org",
+			allowed:  []string{"app.example.com"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if allowed := HostnameIsAllowed(tc.hostname, tc.allowed); allowed != tc.expected {
+				t.Errorf("expected %q to be allowed by %v: %t, got %t", tc.hostname, tc.allowed, tc.expected, allowed)
+			}
+		})
+	}
+}
+
+func newTLSPassthroughLoadBalancer(namespace, name string, created time.Time, protocol corev1.Protocol, hostnames ...string) kubelbv1alpha1.LoadBalancer {
+	return kubelbv1alpha1.LoadBalancer{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(created)},
+		Spec: kubelbv1alpha1.LoadBalancerSpec{
+			Ports: []kubelbv1alpha1.LoadBalancerPort{{
+				Port:           443,
+				Protocol:       protocol,
+				TLSPassthrough: &kubelbv1alpha1.TLSPassthrough{Hostnames: hostnames},
+			}},
+		},
+	}
+}
+
+func TestResolveTLSPassthroughClaims(t *testing.T) {
+	now := time.Now()
+	allowedHostnames := func(namespace string) []string {
+		if namespace == "tenant-restricted" {
+			return []string{"*.restricted.example.com"}
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name       string
+		lbs        []kubelbv1alpha1.LoadBalancer
+		routes     map[string][]string
+		rejections map[string][]string
+	}{
+		{
+			name: "hostnames are claimed",
+			lbs: []kubelbv1alpha1.LoadBalancer{
+				newTLSPassthroughLoadBalancer("tenant-a", "a", now, corev1.ProtocolTCP, "A.example.com"),
+				newTLSPassthroughLoadBalancer("tenant-b", "b", now, corev1.ProtocolTCP, "b.example.com"),
+			},
+			routes: map[string][]string{"tenant-a/a": {"a.example.com"}, "tenant-b/b": {"b.example.com"}},
+		},
+		{
+			name: "oldest LoadBalancer wins a conflict",
+			lbs: []kubelbv1alpha1.LoadBalancer{
+				newTLSPassthroughLoadBalancer("tenant-a", "new", now, corev1.ProtocolTCP, "app.example.com", "new.example.com"),
+				newTLSPassthroughLoadBalancer("tenant-b", "old", now.Add(-time.Hour), corev1.ProtocolTCP, "app.example.com"),
+			},
+			routes:     map[string][]string{"tenant-a/new": {"new.example.com"}, "tenant-b/old": {"app.example.com"}},
+			rejections: map[string][]string{"tenant-a/new": {kubelbv1alpha1.ReasonHostnameConflict}},
+		},
+		{
+			name: "hostnames that are not allowed are rejected",
+			lbs: []kubelbv1alpha1.LoadBalancer{
+				newTLSPassthroughLoadBalancer("tenant-restricted", "r", now, corev1.ProtocolTCP, "app.restricted.example.com", "app.example.com"),
+			},
+			routes:     map[string][]string{"tenant-restricted/r": {"app.restricted.example.com"}},
+			rejections: map[string][]string{"tenant-restricted/r": {kubelbv1alpha1.ReasonHostnameNotAllowed}},
+		},
+		{
+			name: "UDP ports are rejected",
+			lbs: []kubelbv1alpha1.LoadBalancer{
+				newTLSPassthroughLoadBalancer("tenant-a", "udp", now, corev1.ProtocolUDP, "app.example.com"),
+			},
+			rejections: map[string][]string{"tenant-a/udp": {kubelbv1alpha1.ReasonInvalidTLSPassthrough}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := ResolveTLSPassthroughClaims(tc.lbs, allowedHostnames)
+
+			routes := make(map[string][]string)
+			for _, route := range claims.Routes[443] {
+				key := types.NamespacedName{Namespace: route.LoadBalancer.Namespace, Name: route.LoadBalancer.Name}.String()
+				routes[key] = append(routes[key], route.Hostnames...)
+			}
+			if len(routes) != 0 || len(tc.routes) != 0 {
+				if !reflect.DeepEqual(routes, tc.routes) {
+					t.Errorf("expected routes %v, got %v", tc.routes, routes)
+				}
+			}
+
+			rejections := make(map[string][]string)
+			for key, keyRejections := range claims.Rejections {
+				for _, rejection := range keyRejections {
+					rejections[key.String()] = append(rejections[key.String()], rejection.Reason)
+				}
+			}
+			if len(rejections) != 0 || len(tc.rejections) != 0 {
+				if !reflect.DeepEqual(rejections, tc.rejections) {
+					t.Errorf("expected rejections %v, got %v", tc.rejections, rejections)
+				}
+			}
+		})
+	}
+}
@@ -48,6 +48,8 @@ const EnvoyEndpointPattern = "%s-%s-ep-%d"
 const EnvoyEndpointRoutePattern = "tenant-%s-route-%s-%s"
 const EnvoyRoutePortIdentifierPattern = "tenant-%s-route-%s-%s-svc-%s-port-%d-%s"
 const EnvoyListenerPattern = "%v-%s"
+const EnvoyTLSPassthroughListenerPattern = "tls-passthrough-%d"
+const EnvoyConnectionRateLimitPattern = "%s-%s-port-%d"
 const RouteServiceMapKey = "%s/%s"
 const DefaultRouteStatus = "{}"
 
@@ -53,9 +53,21 @@ func NewPortAllocator() *PortAllocator {
 	return pa
 }
 
+// GetPortLookupTable returns a copy of the lookup table, it's safe to use while ports are allocated.
 func (pa *PortAllocator) GetPortLookupTable() LookupTable {
-	return pa.portLookup
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	lookupTable := make(LookupTable, len(pa.portLookup))
+	for endpointKey, ports := range pa.portLookup {
+		lookupTable[endpointKey] = make(map[string]int, len(ports))
+		for portKey, port := range ports {
+			lookupTable[endpointKey][portKey] = port
+		}
+	}
+	return lookupTable
 }
+
 func (pa *PortAllocator) Lookup(endpointKey, portKey string) (int, bool) {
 	if endpointLookup, exists := pa.portLookup[endpointKey]; exists {
 		if port, exists := endpointLookup[portKey]; exists {
@@ -163,7 +175,10 @@ func (pa *PortAllocator) LoadState(ctx context.Context, apiReader client.Reader)
 				lookupTable[endpointKey] = make(map[string]int)
 			}
 
-			for _, lbEndpointPort := range lbEndpoint.Ports {
+			for p, lbEndpointPort := range lbEndpoint.Ports {
+				if kubelb.UsesTLSPassthrough(&lb, p) {
+					continue
+				}
 				portKey := fmt.Sprintf(kubelb.EnvoyListenerPattern, lbEndpointPort.Port, lbEndpointPort.Protocol)
 				for _, port := range lb.Status.Service.Ports {
 					// Name is not guaranteed to be set, so we need to check for port and protocol as well.
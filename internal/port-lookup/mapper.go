@@ -30,7 +30,11 @@ func (pa *PortAllocator) AllocatePortsForLoadBalancers(loadBalancers kubelbv1alp
 			endpointKey := fmt.Sprintf(kubelb.EnvoyEndpointPattern, lb.Namespace, lb.Name, i)
 
 			var keys []string
-			for _, lbEndpointPort := range lbEndpoint.Ports {
+			for p, lbEndpointPort := range lbEndpoint.Ports {
+				// Ports with TLS passthrough use a shared listener port instead.
+				if kubelb.UsesTLSPassthrough(&lb, p) {
+					continue
+				}
 				keys = append(keys, fmt.Sprintf(kubelb.EnvoyListenerPattern, lbEndpointPort.Port, lbEndpointPort.Protocol))
 			}
 			// If a port is already allocated, it will be skipped.
@@ -92,6 +92,9 @@ func CreateUpdateRoute(ctx context.Context, client ctrlclient.Client, route kube
 		return nil
 	}
 
+	// ServiceSettings are not generated by the CCM and are managed in the LB cluster.
+	route.Spec.ServiceSettings = existingRoute.Spec.ServiceSettings
+
 	if !reflect.DeepEqual(existingRoute.Spec, route.Spec) || !reflect.DeepEqual(existingRoute.Labels, route.Labels) || !reflect.DeepEqual(existingRoute.Annotations, route.Annotations) {
 		existingRoute.Spec = route.Spec
 		existingRoute.Labels = route.Labels
@@ -32,6 +32,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -181,6 +182,8 @@ func GenerateBridgeService(service corev1.Service, appName, controllerNamespace
 		kubelb.LabelAppKubernetesName: appName,
 	}
 	bridgeService.Spec.Type = corev1.ServiceTypeClusterIP
+	// Allocate cluster IPs for all the IP families supported by the cluster, the EndpointSlices for the tenant namespace are generated per IP family.
+	bridgeService.Spec.IPFamilyPolicy = ptr.To(corev1.IPFamilyPolicyPreferDualStack)
 	return bridgeService
 }
 
@@ -262,9 +265,12 @@ func cleanseService(svc corev1.Service, removeUID, removeClusterSpecificFields b
 	}
 
 	if removeClusterSpecificFields {
+		// IP families depend on the configuration of the cluster, the IP family policy is retained so that dual-stack Services remain
+		// dual-stack if the cluster supports it. RequireDualStack is relaxed since the Service can't be created on a single-stack cluster.
 		obj.Spec.IPFamilies = nil
-		obj.Spec.IPFamilyPolicy = nil
-		obj.Spec.SessionAffinity = ""
+		if obj.Spec.IPFamilyPolicy != nil && *obj.Spec.IPFamilyPolicy == corev1.IPFamilyPolicyRequireDualStack {
+			obj.Spec.IPFamilyPolicy = ptr.To(corev1.IPFamilyPolicyPreferDualStack)
+		}
 		obj.Spec.ExternalTrafficPolicy = ""
 		obj.Spec.InternalTrafficPolicy = nil
 	}